@@ -10,6 +10,7 @@ import (
 
 	"gpt-load/internal/config"
 	db "gpt-load/internal/db/migrations"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
 	"gpt-load/internal/proxy"
@@ -30,6 +31,7 @@ type App struct {
 	configManager     types.ConfigManager
 	settingsManager   *config.SystemSettingsManager
 	groupManager      *services.GroupManager
+	errorRuleManager  *keypool.ErrorRuleManager
 	logCleanupService *services.LogCleanupService
 	requestLogService *services.RequestLogService
 	cronChecker       *keypool.CronChecker
@@ -37,6 +39,7 @@ type App struct {
 	proxyServer       *proxy.ProxyServer
 	storage           store.Store
 	db                *gorm.DB
+	encryption        encryption.Service
 	httpServer        *http.Server
 }
 
@@ -47,6 +50,7 @@ type AppParams struct {
 	ConfigManager     types.ConfigManager
 	SettingsManager   *config.SystemSettingsManager
 	GroupManager      *services.GroupManager
+	ErrorRuleManager  *keypool.ErrorRuleManager
 	LogCleanupService *services.LogCleanupService
 	RequestLogService *services.RequestLogService
 	CronChecker       *keypool.CronChecker
@@ -54,6 +58,7 @@ type AppParams struct {
 	ProxyServer       *proxy.ProxyServer
 	Storage           store.Store
 	DB                *gorm.DB
+	Encryption        encryption.Service
 }
 
 // NewApp is the constructor for App, with dependencies injected by dig.
@@ -63,6 +68,7 @@ func NewApp(params AppParams) *App {
 		configManager:     params.ConfigManager,
 		settingsManager:   params.SettingsManager,
 		groupManager:      params.GroupManager,
+		errorRuleManager:  params.ErrorRuleManager,
 		logCleanupService: params.LogCleanupService,
 		requestLogService: params.RequestLogService,
 		cronChecker:       params.CronChecker,
@@ -70,6 +76,7 @@ func NewApp(params AppParams) *App {
 		proxyServer:       params.ProxyServer,
 		storage:           params.Storage,
 		db:                params.DB,
+		encryption:        params.Encryption,
 	}
 }
 
@@ -80,6 +87,9 @@ func (a *App) Start() error {
 		logrus.Info("Starting as Master Node.")
 
 		// 数据库迁移
+		if err := db.MigrateSchema(a.db); err != nil {
+			return fmt.Errorf("database schema migration failed: %w", err)
+		}
 		if err := a.db.AutoMigrate(
 			&models.SystemSetting{},
 			&models.Group{},
@@ -89,8 +99,13 @@ func (a *App) Start() error {
 		); err != nil {
 			return fmt.Errorf("database auto-migration failed: %w", err)
 		}
-		// 数据修复
-		db.MigrateDatabase(a.db)
+		// 数据修复需要解密旧日志中的密钥，先确认主密钥可用
+		if err := encryption.CheckMasterKey(a.db, a.encryption); err != nil {
+			return err
+		}
+		if err := db.MigrateDatabase(a.db, a.encryption); err != nil {
+			return fmt.Errorf("database migration failed: %w", err)
+		}
 		logrus.Info("Database auto-migration completed.")
 
 		// 初始化系统设置
@@ -113,6 +128,9 @@ func (a *App) Start() error {
 		a.cronChecker.Start()
 	} else {
 		logrus.Info("Starting as Slave Node.")
+		if err := encryption.CheckMasterKey(a.db, a.encryption); err != nil {
+			return err
+		}
 		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
 	}
 
@@ -121,6 +139,10 @@ func (a *App) Start() error {
 
 	a.groupManager.Initialize()
 
+	if err := a.errorRuleManager.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize error rules: %w", err)
+	}
+
 	// Create HTTP server
 	serverConfig := a.configManager.GetEffectiveServerConfig()
 	a.httpServer = &http.Server{
@@ -169,6 +191,7 @@ func (a *App) Stop(ctx context.Context) {
 	// 使用原始的总超时 context 继续关闭其他后台服务
 	stoppableServices := []func(context.Context){
 		a.groupManager.Stop,
+		a.errorRuleManager.Stop,
 		a.settingsManager.Stop,
 	}
 
@@ -0,0 +1,162 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrBalanceUnsupported 表示渠道没有默认的余额接口，且分组未配置余额接口地址
+var ErrBalanceUnsupported = errors.New("balance check is not supported for this channel")
+
+// checkBalance 请求余额接口并返回剩余额度。
+// 分组配置了余额接口时优先使用，可以是相对上游地址的路径或完整 URL；否则使用渠道默认路径。
+// 分组配置了余额 JSON 路径时按路径读取，否则按常见的余额响应格式解析。
+func (b *BaseChannel) checkBalance(
+	ctx context.Context,
+	defaultPath string,
+	apiKey *models.APIKey,
+	group *models.Group,
+	modify func(req *http.Request, apiKey *models.APIKey, group *models.Group),
+) (float64, error) {
+	endpoint := strings.TrimSpace(group.EffectiveConfig.KeyBalanceEndpoint)
+	if endpoint == "" {
+		endpoint = defaultPath
+	}
+	if endpoint == "" {
+		return 0, ErrBalanceUnsupported
+	}
+
+	reqURL := endpoint
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		upstreamURL := b.getUpstreamURL()
+		if upstreamURL == nil {
+			return 0, fmt.Errorf("no upstream URL configured for channel %s", b.Name)
+		}
+		path, rawQuery, _ := strings.Cut(endpoint, "?")
+		joined, err := url.JoinPath(upstreamURL.String(), path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to join upstream URL and balance path: %w", err)
+		}
+		reqURL = joined
+		if rawQuery != "" {
+			reqURL += "?" + rawQuery
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create balance request: %w", err)
+	}
+	modify(req, apiKey, group)
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send balance request: %w", app_errors.NewNetworkError(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read balance response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("[status %d] %w", resp.StatusCode, app_errors.NewUpstreamError(resp.StatusCode, body))
+	}
+
+	if jsonPath := strings.TrimSpace(group.EffectiveConfig.KeyBalanceJSONPath); jsonPath != "" {
+		value, ok := jsonPathValue(body, jsonPath)
+		if !ok {
+			return 0, fmt.Errorf("balance response is missing JSON path %q", jsonPath)
+		}
+		return parseBalanceNumber(value)
+	}
+	return parseBalance(body)
+}
+
+// parseBalance 解析常见的余额响应格式：
+//   - OpenAI credit_grants：{"total_available": 4.2}
+//   - DeepSeek：{"balance_infos": [{"total_balance": "110.00"}]}
+//   - OpenRouter credits：{"data": {"total_credits": 10, "total_usage": 2.5}}
+//   - OpenRouter key：{"data": {"limit_remaining": 7.5}}
+//   - 通用格式：{"balance": 3} 或 {"data": {"balance": 3}}
+func parseBalance(body []byte) (float64, error) {
+	var payload struct {
+		TotalAvailable *json.Number `json:"total_available"`
+		Balance        *json.Number `json:"balance"`
+		BalanceInfos   []struct {
+			TotalBalance json.Number `json:"total_balance"`
+		} `json:"balance_infos"`
+		Data *struct {
+			TotalCredits   *json.Number `json:"total_credits"`
+			TotalUsage     *json.Number `json:"total_usage"`
+			LimitRemaining *json.Number `json:"limit_remaining"`
+			Balance        *json.Number `json:"balance"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return 0, fmt.Errorf("failed to parse balance response: %w", err)
+	}
+
+	switch {
+	case payload.TotalAvailable != nil:
+		return payload.TotalAvailable.Float64()
+	case payload.Balance != nil:
+		return payload.Balance.Float64()
+	case len(payload.BalanceInfos) > 0:
+		var total float64
+		for _, info := range payload.BalanceInfos {
+			balance, err := info.TotalBalance.Float64()
+			if err != nil {
+				return 0, fmt.Errorf("invalid total_balance %q: %w", info.TotalBalance, err)
+			}
+			total += balance
+		}
+		return total, nil
+	case payload.Data != nil && payload.Data.LimitRemaining != nil:
+		return payload.Data.LimitRemaining.Float64()
+	case payload.Data != nil && payload.Data.TotalCredits != nil:
+		credits, err := payload.Data.TotalCredits.Float64()
+		if err != nil {
+			return 0, err
+		}
+		var usage float64
+		if payload.Data.TotalUsage != nil {
+			if usage, err = payload.Data.TotalUsage.Float64(); err != nil {
+				return 0, err
+			}
+		}
+		return credits - usage, nil
+	case payload.Data != nil && payload.Data.Balance != nil:
+		return payload.Data.Balance.Float64()
+	}
+	return 0, fmt.Errorf("unrecognized balance response, configure key_balance_json_path to locate the balance")
+}
+
+// parseBalanceNumber 将 JSON 路径读取到的数字或数字字符串转换为余额
+func parseBalanceNumber(value any) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("balance value %v is not a number", value)
+	}
+}
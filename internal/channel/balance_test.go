@@ -0,0 +1,125 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func TestParseBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "openai credit grants", body: `{"total_granted":10,"total_available":4.2}`, want: 4.2},
+		{name: "deepseek balance infos", body: `{"balance_infos":[{"currency":"CNY","total_balance":"110.00"},{"currency":"USD","total_balance":"2.5"}]}`, want: 112.5},
+		{name: "openrouter credits", body: `{"data":{"total_credits":10,"total_usage":2.5}}`, want: 7.5},
+		{name: "openrouter key limit", body: `{"data":{"limit_remaining":7.5,"usage":1}}`, want: 7.5},
+		{name: "top level balance", body: `{"balance":3}`, want: 3},
+		{name: "nested balance", body: `{"data":{"balance":3}}`, want: 3},
+		{name: "invalid deepseek balance", body: `{"balance_infos":[{"total_balance":"n/a"}]}`, wantErr: true},
+		{name: "unrecognized format", body: `{"remaining":3}`, wantErr: true},
+		{name: "not json", body: `<html></html>`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBalance([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBalance() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseBalance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBalance(t *testing.T) {
+	const apiKey = "sk-balance-test"
+
+	tests := []struct {
+		name     string
+		endpoint string // 相对上游地址的路径，fullURL 为真时拼接到测试服务器地址后作为完整 URL
+		fullURL  bool
+		jsonPath string
+		status   int
+		body     string
+		want     float64
+		wantErr  bool
+	}{
+		{name: "default openai endpoint", status: http.StatusOK, body: `{"total_available":4.2}`, want: 4.2},
+		{name: "relative endpoint with query", endpoint: "/user/balance?currency=usd", status: http.StatusOK, body: `{"balance":"8.5"}`, want: 8.5},
+		{name: "full url endpoint", endpoint: "/v1/credits", fullURL: true, status: http.StatusOK, body: `{"data":{"total_credits":10,"total_usage":4}}`, want: 6},
+		{name: "json path", endpoint: "/custom", jsonPath: "result.amount", status: http.StatusOK, body: `{"result":{"amount":"12.25"}}`, want: 12.25},
+		{name: "missing json path", endpoint: "/custom", jsonPath: "result.amount", status: http.StatusOK, body: `{"result":{}}`, wantErr: true},
+		{name: "upstream error", status: http.StatusUnauthorized, body: `{"error":{"code":"invalid_api_key"}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRequest *http.Request
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequest = r
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			upstream, err := url.Parse(srv.URL + "/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch := &OpenAIChannel{BaseChannel: &BaseChannel{
+				Name:       "test",
+				Upstreams:  []UpstreamInfo{{URL: upstream, Weight: 1}},
+				HTTPClient: srv.Client(),
+			}}
+
+			endpoint, wantPath, wantQuery := tt.endpoint, "/api/dashboard/billing/credit_grants", ""
+			switch {
+			case tt.fullURL:
+				wantPath = endpoint
+				endpoint = srv.URL + endpoint
+			case endpoint != "":
+				u, _ := url.Parse(endpoint)
+				wantPath, wantQuery = "/api"+u.Path, u.RawQuery
+			}
+			group := &models.Group{}
+			group.EffectiveConfig.KeyBalanceEndpoint = endpoint
+			group.EffectiveConfig.KeyBalanceJSONPath = tt.jsonPath
+
+			got, err := ch.CheckBalance(context.Background(), &models.APIKey{KeyValue: apiKey}, group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckBalance() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CheckBalance() = %v, want %v", got, tt.want)
+			}
+			if gotRequest == nil {
+				t.Fatal("balance endpoint was not called")
+			}
+			if gotRequest.URL.Path != wantPath || gotRequest.URL.RawQuery != wantQuery {
+				t.Errorf("request URL = %s, want path %q query %q", gotRequest.URL, wantPath, wantQuery)
+			}
+			if auth := gotRequest.Header.Get("Authorization"); auth != "Bearer "+apiKey {
+				t.Errorf("Authorization = %q, want the key as a bearer token", auth)
+			}
+		})
+	}
+}
+
+func TestCheckBalanceUnsupported(t *testing.T) {
+	ch := &AnthropicChannel{BaseChannel: &BaseChannel{Name: "test", HTTPClient: http.DefaultClient}}
+	_, err := ch.CheckBalance(context.Background(), &models.APIKey{KeyValue: "sk-ant"}, &models.Group{})
+	if !errors.Is(err, ErrBalanceUnsupported) {
+		t.Errorf("CheckBalance() error = %v, want ErrBalanceUnsupported", err)
+	}
+}
@@ -35,6 +35,12 @@ type ChannelProxy interface {
 	// ValidateKey checks if the given API key is valid.
 	ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error)
 
+	// ListModels returns the models the given API key can access upstream.
+	ListModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]string, error)
+
+	// CheckBalance returns the remaining balance of the given API key, or ErrBalanceUnsupported.
+	CheckBalance(ctx context.Context, apiKey *models.APIKey, group *models.Group) (float64, error)
+
 	// ForceHTTP11 indicates whether the channel should force HTTP/1.1 for requests.
 	ForceHTTP11() bool
 }
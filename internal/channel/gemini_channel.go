@@ -95,8 +95,22 @@ func (ch *GeminiChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
 	return ""
 }
 
+// ListModels lists the models available to the key via the models.list endpoint.
+func (ch *GeminiChannel) ListModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]string, error) {
+	return ch.fetchModels(ctx, "/v1beta/models", url.Values{"pageSize": {"1000"}}, apiKey, group, ch.ModifyRequest, parseGeminiModelList)
+}
+
+// CheckBalance queries the balance endpoint configured for the group; Gemini has no public balance API.
+func (ch *GeminiChannel) CheckBalance(ctx context.Context, apiKey *models.APIKey, group *models.Group) (float64, error) {
+	return ch.checkBalance(ctx, "", apiKey, group, ch.ModifyRequest)
+}
+
 // ValidateKey checks if the given API key is valid by making a generateContent request.
 func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	if tmpl := validationTemplateFor(group); tmpl != nil {
+		return ch.validateWithTemplate(ctx, tmpl, apiKey, group, ch.ModifyRequest)
+	}
+
 	upstreamURL := ch.getUpstreamURL()
 	if upstreamURL == nil {
 		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
@@ -135,7 +149,7 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 
 	resp, err := ch.HTTPClient.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("failed to send validation request: %w", err)
+		return false, fmt.Errorf("failed to send validation request: %w", app_errors.NewNetworkError(err))
 	}
 	defer resp.Body.Close()
 
@@ -150,8 +164,6 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
 	}
 
-	// Use the new parser to extract a clean error message.
-	parsedError := app_errors.ParseUpstreamError(errorBody)
-
-	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+	// Parse and classify the error so the key status can be updated by category.
+	return false, fmt.Errorf("[status %d] %w", resp.StatusCode, app_errors.NewUpstreamError(resp.StatusCode, errorBody))
 }
@@ -0,0 +1,107 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// fetchModels 请求上游的模型列表接口，parse 负责从响应体中提取模型名称
+func (b *BaseChannel) fetchModels(
+	ctx context.Context,
+	path string,
+	query url.Values,
+	apiKey *models.APIKey,
+	group *models.Group,
+	modify func(req *http.Request, apiKey *models.APIKey, group *models.Group),
+	parse func(body []byte) ([]string, error),
+) ([]string, error) {
+	upstreamURL := b.getUpstreamURL()
+	if upstreamURL == nil {
+		return nil, fmt.Errorf("no upstream URL configured for channel %s", b.Name)
+	}
+
+	reqURL, err := url.JoinPath(upstreamURL.String(), path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join upstream URL and models path: %w", err)
+	}
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create models request: %w", err)
+	}
+	modify(req, apiKey, group)
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send models request: %w", app_errors.NewNetworkError(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("[status %d] %w", resp.StatusCode, app_errors.NewUpstreamError(resp.StatusCode, body))
+	}
+
+	return parse(body)
+}
+
+// parseModelIDList 解析 OpenAI/Anthropic 格式的模型列表：{"data":[{"id":"..."}]}
+func parseModelIDList(body []byte) ([]string, error) {
+	var payload struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	modelNames := make([]string, 0, len(payload.Data))
+	for _, m := range payload.Data {
+		if m.ID != "" {
+			modelNames = append(modelNames, m.ID)
+		}
+	}
+	return modelNames, nil
+}
+
+// parseGeminiModelList 解析 Gemini 格式的模型列表：{"models":[{"name":"models/..."}]}
+func parseGeminiModelList(body []byte) ([]string, error) {
+	var payload struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	modelNames := make([]string, 0, len(payload.Models))
+	for _, m := range payload.Models {
+		if name := strings.TrimPrefix(m.Name, "models/"); name != "" {
+			modelNames = append(modelNames, name)
+		}
+	}
+	return modelNames, nil
+}
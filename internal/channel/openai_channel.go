@@ -72,8 +72,22 @@ func (ch *OpenAIChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
 	return ""
 }
 
+// ListModels lists the models available to the key via the /v1/models endpoint.
+func (ch *OpenAIChannel) ListModels(ctx context.Context, apiKey *models.APIKey, group *models.Group) ([]string, error) {
+	return ch.fetchModels(ctx, "/v1/models", nil, apiKey, group, ch.ModifyRequest, parseModelIDList)
+}
+
+// CheckBalance queries the remaining credit via the billing credit_grants endpoint.
+func (ch *OpenAIChannel) CheckBalance(ctx context.Context, apiKey *models.APIKey, group *models.Group) (float64, error) {
+	return ch.checkBalance(ctx, "/dashboard/billing/credit_grants", apiKey, group, ch.ModifyRequest)
+}
+
 // ValidateKey checks if the given API key is valid by making a chat completion request.
 func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	if tmpl := validationTemplateFor(group); tmpl != nil {
+		return ch.validateWithTemplate(ctx, tmpl, apiKey, group, ch.ModifyRequest)
+	}
+
 	upstreamURL := ch.getUpstreamURL()
 	if upstreamURL == nil {
 		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
@@ -115,7 +129,7 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 
 	resp, err := ch.HTTPClient.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("failed to send validation request: %w", err)
+		return false, fmt.Errorf("failed to send validation request: %w", app_errors.NewNetworkError(err))
 	}
 	defer resp.Body.Close()
 
@@ -130,8 +144,6 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
 	}
 
-	// Use the new parser to extract a clean error message.
-	parsedError := app_errors.ParseUpstreamError(errorBody)
-
-	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+	// Parse and classify the error so the key status can be updated by category.
+	return false, fmt.Errorf("[status %d] %w", resp.StatusCode, app_errors.NewUpstreamError(resp.StatusCode, errorBody))
 }
@@ -0,0 +1,226 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TestModelPlaceholder is replaced with the group's test model in validation templates.
+const TestModelPlaceholder = "${TEST_MODEL}"
+
+var validTemplateMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+	http.MethodHead:   true,
+}
+
+// CheckValidationTemplate normalizes the template and reports configuration errors.
+func CheckValidationTemplate(tmpl *models.ValidationTemplate) error {
+	tmpl.Method = strings.ToUpper(strings.TrimSpace(tmpl.Method))
+	if tmpl.Method == "" {
+		tmpl.Method = http.MethodPost
+	}
+	if !validTemplateMethods[tmpl.Method] {
+		return fmt.Errorf("unsupported validation method: %s", tmpl.Method)
+	}
+
+	tmpl.Path = strings.TrimSpace(tmpl.Path)
+	if !strings.HasPrefix(tmpl.Path, "/") {
+		return fmt.Errorf("validation path must start with /")
+	}
+
+	if strings.TrimSpace(tmpl.Body) != "" {
+		body := renderTemplateBody(tmpl.Body, "test-model")
+		if !json.Valid([]byte(body)) {
+			return fmt.Errorf("validation body must be valid JSON")
+		}
+	}
+
+	for key := range tmpl.Headers {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("validation header name cannot be empty")
+		}
+	}
+
+	for _, code := range tmpl.SuccessStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid success status code: %d", code)
+		}
+	}
+
+	tmpl.SuccessJSONPath = strings.TrimSpace(tmpl.SuccessJSONPath)
+	if tmpl.SuccessJSONPath != "" {
+		for _, segment := range splitJSONPath(tmpl.SuccessJSONPath) {
+			if segment == "" {
+				return fmt.Errorf("invalid success JSON path: %s", tmpl.SuccessJSONPath)
+			}
+		}
+	}
+	return nil
+}
+
+// validationTemplateFor 返回分组的自定义验证模板。
+// 定时任务直接从数据库加载分组，不包含缓存字段，此时从原始 JSON 解析。
+func validationTemplateFor(group *models.Group) *models.ValidationTemplate {
+	if group.ValidationTemplateConfig != nil {
+		return group.ValidationTemplateConfig
+	}
+	if len(group.ValidationTemplate) == 0 || string(group.ValidationTemplate) == "null" {
+		return nil
+	}
+	var tmpl models.ValidationTemplate
+	if err := json.Unmarshal(group.ValidationTemplate, &tmpl); err != nil {
+		return nil
+	}
+	return &tmpl
+}
+
+// validateWithTemplate 按分组自定义模板发送验证请求，modify 用于注入渠道自身的鉴权信息
+func (b *BaseChannel) validateWithTemplate(
+	ctx context.Context,
+	tmpl *models.ValidationTemplate,
+	apiKey *models.APIKey,
+	group *models.Group,
+	modify func(req *http.Request, apiKey *models.APIKey, group *models.Group),
+) (bool, error) {
+	upstreamURL := b.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", b.Name)
+	}
+
+	path, rawQuery, _ := strings.Cut(strings.ReplaceAll(tmpl.Path, TestModelPlaceholder, b.TestModel), "?")
+	reqURL, err := url.JoinPath(upstreamURL.String(), path)
+	if err != nil {
+		return false, fmt.Errorf("failed to join upstream URL and validation path: %w", err)
+	}
+	if rawQuery != "" {
+		reqURL += "?" + rawQuery
+	}
+
+	var body io.Reader
+	if strings.TrimSpace(tmpl.Body) != "" {
+		body = strings.NewReader(renderTemplateBody(tmpl.Body, b.TestModel))
+	}
+
+	method := tmpl.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	modify(req, apiKey, group)
+
+	// 模板请求头在渠道鉴权之后应用，便于兼容使用非标准鉴权头的中转服务
+	for key, value := range tmpl.Headers {
+		req.Header.Set(key, strings.ReplaceAll(value, TestModelPlaceholder, b.TestModel))
+	}
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", app_errors.NewNetworkError(err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read validation response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if !isTemplateSuccessStatus(tmpl, resp.StatusCode) {
+		return false, fmt.Errorf("[status %d] %w", resp.StatusCode, app_errors.NewUpstreamError(resp.StatusCode, respBody))
+	}
+
+	if tmpl.SuccessJSONPath != "" && !jsonPathExists(respBody, tmpl.SuccessJSONPath) {
+		return false, fmt.Errorf("[status %d] validation response is missing JSON path %q", resp.StatusCode, tmpl.SuccessJSONPath)
+	}
+
+	return true, nil
+}
+
+// renderTemplateBody 替换请求体中的测试模型占位符，模型名按 JSON 字符串转义
+func renderTemplateBody(body, testModel string) string {
+	escaped, _ := json.Marshal(testModel)
+	return strings.ReplaceAll(body, TestModelPlaceholder, string(escaped[1:len(escaped)-1]))
+}
+
+// isTemplateSuccessStatus 未配置成功状态码时，任意 2xx 均视为成功
+func isTemplateSuccessStatus(tmpl *models.ValidationTemplate, statusCode int) bool {
+	if len(tmpl.SuccessStatusCodes) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	for _, code := range tmpl.SuccessStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// splitJSONPath 将 "data.0.embedding" 或 "$.data[0].embedding" 形式的路径拆分为片段
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	return strings.Split(path, ".")
+}
+
+// jsonPathExists 判断响应体中是否存在指定路径的非 null 值
+func jsonPathExists(body []byte, path string) bool {
+	_, ok := jsonPathValue(body, path)
+	return ok
+}
+
+// jsonPathValue 读取响应体中指定路径的非 null 值，数字以 json.Number 返回
+func jsonPathValue(body []byte, path string) (any, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var current any
+	if err := decoder.Decode(&current); err != nil {
+		return nil, false
+	}
+
+	for _, segment := range splitJSONPath(path) {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
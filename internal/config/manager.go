@@ -49,6 +49,8 @@ type Config struct {
 	Log         types.LogConfig         `json:"log"`
 	Database    types.DatabaseConfig    `json:"database"`
 	RedisDSN    string                  `json:"redis_dsn"`
+	// EncryptionKey 是加密密钥值的主密钥，为空表示不加密
+	EncryptionKey string `json:"-"`
 }
 
 // NewManager creates a new configuration manager
@@ -68,6 +70,11 @@ func (m *Manager) ReloadConfig() error {
 		logrus.Info("Info: Create .env file to support environment variable configuration")
 	}
 
+	encryptionKey, err := LoadSecret("ENCRYPTION_KEY")
+	if err != nil {
+		return err
+	}
+
 	config := &Config{
 		Server: types.ServerConfig{
 			IsMaster:                !utils.ParseBoolean(os.Getenv("IS_SLAVE"), false),
@@ -90,6 +97,8 @@ func (m *Manager) ReloadConfig() error {
 		},
 		Performance: types.PerformanceConfig{
 			MaxConcurrentRequests: utils.ParseInteger(os.Getenv("MAX_CONCURRENT_REQUESTS"), 100),
+			MaxQueuedRequests:     utils.ParseInteger(os.Getenv("MAX_QUEUED_REQUESTS"), 100),
+			QueueTimeoutSeconds:   utils.ParseInteger(os.Getenv("REQUEST_QUEUE_TIMEOUT"), 30),
 		},
 		Log: types.LogConfig{
 			Level:      utils.GetEnvOrDefault("LOG_LEVEL", "info"),
@@ -100,7 +109,8 @@ func (m *Manager) ReloadConfig() error {
 		Database: types.DatabaseConfig{
 			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
 		},
-		RedisDSN: os.Getenv("REDIS_DSN"),
+		RedisDSN:      os.Getenv("REDIS_DSN"),
+		EncryptionKey: encryptionKey,
 	}
 	m.config = config
 
@@ -147,6 +157,27 @@ func (m *Manager) GetDatabaseConfig() types.DatabaseConfig {
 	return m.config.Database
 }
 
+// GetEncryptionKey returns the master key used to encrypt key values at rest.
+func (m *Manager) GetEncryptionKey() string {
+	return m.config.EncryptionKey
+}
+
+// LoadSecret reads a secret from the environment variable name, or from the file named by name_FILE.
+func LoadSecret(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 // GetEffectiveServerConfig returns server configuration merged with system settings
 func (m *Manager) GetEffectiveServerConfig() types.ServerConfig {
 	return m.config.Server
@@ -165,6 +196,14 @@ func (m *Manager) Validate() error {
 		validationErrors = append(validationErrors, "max concurrent requests cannot be less than 1")
 	}
 
+	if m.config.Performance.MaxQueuedRequests < 0 {
+		validationErrors = append(validationErrors, "max queued requests cannot be negative")
+	}
+
+	if m.config.Performance.QueueTimeoutSeconds < 0 {
+		validationErrors = append(validationErrors, "request queue timeout cannot be negative")
+	}
+
 	// Validate auth key
 	if m.config.Auth.Key == "" {
 		validationErrors = append(validationErrors, "AUTH_KEY is required and cannot be empty")
@@ -206,6 +245,8 @@ func (m *Manager) DisplayServerConfig() {
 
 	logrus.Info("  --- Performance ---")
 	logrus.Infof("    Max Concurrent Requests: %d", perfConfig.MaxConcurrentRequests)
+	logrus.Infof("    Max Queued Requests: %d", perfConfig.MaxQueuedRequests)
+	logrus.Infof("    Request Queue Timeout: %d seconds", perfConfig.QueueTimeoutSeconds)
 
 	logrus.Info("  --- Security ---")
 	logrus.Infof("    Authentication: enabled (key loaded)")
@@ -214,6 +255,11 @@ func (m *Manager) DisplayServerConfig() {
 		corsStatus = fmt.Sprintf("enabled (Origins: %s)", strings.Join(corsConfig.AllowedOrigins, ", "))
 	}
 	logrus.Infof("    CORS: %s", corsStatus)
+	encryptionStatus := "disabled"
+	if m.config.EncryptionKey != "" {
+		encryptionStatus = "enabled"
+	}
+	logrus.Infof("    Key Encryption: %s", encryptionStatus)
 
 	logrus.Info("  --- Logging ---")
 	logrus.Infof("    Log Level: %s", logConfig.Level)
@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"gpt-load/internal/db"
 	"gpt-load/internal/models"
+	"gpt-load/internal/redaction"
+	"gpt-load/internal/schedule"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
@@ -306,6 +310,32 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "oneof=") {
+					allowed := strings.Fields(strings.TrimPrefix(trimmedRule, "oneof="))
+					if !slices.Contains(allowed, strVal) {
+						return fmt.Errorf("invalid value for %s: must be one of %s", key, strings.Join(allowed, ", "))
+					}
+				}
+				if trimmedRule == "cron" && strVal != "" {
+					if _, err := schedule.Parse(strVal); err != nil {
+						return fmt.Errorf("invalid cron expression for %s: %v", key, err)
+					}
+				}
+				if trimmedRule == "timezone" && strVal != "" {
+					if _, err := time.LoadLocation(strVal); err != nil {
+						return fmt.Errorf("invalid time zone for %s: %v", key, err)
+					}
+				}
+				if trimmedRule == "redaction_detectors" {
+					if _, err := redaction.ParseDetectors(strVal); err != nil {
+						return fmt.Errorf("invalid value for %s: %v", key, err)
+					}
+				}
+				if trimmedRule == "regexlist" {
+					if _, err := redaction.CompilePatterns(strVal); err != nil {
+						return fmt.Errorf("invalid value for %s: %v", key, err)
+					}
+				}
 			}
 		default:
 			return fmt.Errorf("unsupported type for setting key validation: %s", key)
@@ -376,6 +406,32 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "oneof=") {
+					allowed := strings.Fields(strings.TrimPrefix(trimmedRule, "oneof="))
+					if !slices.Contains(allowed, strVal) {
+						return fmt.Errorf("invalid value for %s: must be one of %s", key, strings.Join(allowed, ", "))
+					}
+				}
+				if trimmedRule == "cron" && strVal != "" {
+					if _, err := schedule.Parse(strVal); err != nil {
+						return fmt.Errorf("invalid cron expression for %s: %v", key, err)
+					}
+				}
+				if trimmedRule == "timezone" && strVal != "" {
+					if _, err := time.LoadLocation(strVal); err != nil {
+						return fmt.Errorf("invalid time zone for %s: %v", key, err)
+					}
+				}
+				if trimmedRule == "redaction_detectors" {
+					if _, err := redaction.ParseDetectors(strVal); err != nil {
+						return fmt.Errorf("invalid value for %s: %v", key, err)
+					}
+				}
+				if trimmedRule == "regexlist" {
+					if _, err := redaction.CompilePatterns(strVal); err != nil {
+						return fmt.Errorf("invalid value for %s: %v", key, err)
+					}
+				}
 			}
 		case reflect.Bool:
 			_, ok := value.(bool)
@@ -411,9 +467,29 @@ func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettin
 
 	logrus.Info("  --- Key & Group Behavior ---")
 	logrus.Infof("    Max Retries: %d", settings.MaxRetries)
-	logrus.Infof("    Retry Interval: %d ms", settings.RetryIntervalMs)
-	logrus.Infof("    Blacklist Threshold: %d", settings.BlacklistThreshold)
-	logrus.Infof("    Key Validation Interval: %d minutes", settings.KeyValidationIntervalMinutes)
+	logrus.Infof("    Retry Interval: %d ms (max %d ms, jitter: %t)", settings.RetryIntervalMs, settings.RetryMaxIntervalMs, settings.RetryJitter)
+	logrus.Infof("    Retry Status Codes: %s", settings.RetryStatusCodes)
+	logrus.Infof("    Key Wait Queue: %d requests, %d seconds", settings.KeyWaitQueueSize, settings.KeyWaitTimeoutSeconds)
+	if settings.FailurePolicy == "window" {
+		logrus.Infof("    Failure Window: %d seconds, max %d failures, max %d%% failure rate", settings.FailureWindowSeconds, settings.FailureWindowMaxFailures, settings.FailureWindowMaxRate)
+	} else {
+		logrus.Infof("    Blacklist Threshold: %d", settings.BlacklistThreshold)
+	}
+	if settings.KeyValidationCron != "" {
+		logrus.Infof("    Key Validation Schedule: %s", settings.KeyValidationCron)
+	} else {
+		logrus.Infof("    Key Validation Interval: %d minutes (backoff up to %d minutes)", settings.KeyValidationIntervalMinutes, settings.KeyValidationMaxMinutes)
+	}
+	logrus.Infof("    Active Key Probes: %d per hour", settings.ActiveKeyProbesPerHour)
+	if settings.KeyBalanceCheckHours > 0 {
+		logrus.Infof("    Key Balance Check: every %d hours (low balance below %d)", settings.KeyBalanceCheckHours, settings.KeyLowBalanceThreshold)
+	}
+	logrus.Infof("    Quota Reset Time Zone: %s", settings.QuotaResetTimezone)
+	logrus.Infof("    Shared Key State: %t", settings.SharedKeyState)
+	logrus.Infof("    Key Expiry Warning: %d days", settings.KeyExpiryWarningDays)
+	if settings.KeyModelDiscoveryHours > 0 {
+		logrus.Infof("    Key Model Discovery: every %d hours", settings.KeyModelDiscoveryHours)
+	}
 	logrus.Info("====================================")
 	logrus.Info("")
 }
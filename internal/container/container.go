@@ -6,6 +6,7 @@ import (
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/db"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/handler"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/keypool"
@@ -34,6 +35,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(store.NewStore); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(encryption.NewServiceFromConfig); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(httpclient.NewHTTPClientManager); err != nil {
 		return nil, err
 	}
@@ -57,6 +61,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewKeyDeleteService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewKeyTransferService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogService); err != nil {
 		return nil, err
 	}
@@ -69,6 +76,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewGroupManager); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(keypool.NewErrorRuleManager); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(keypool.NewProvider); err != nil {
 		return nil, err
 	}
@@ -1,9 +1,22 @@
 package db
 
 import (
+	"gpt-load/internal/encryption"
+
 	"gorm.io/gorm"
 )
 
-func MigrateDatabase(db *gorm.DB) error {
-	return V1_0_22_DropRetriesColumn(db)
+// MigrateSchema 执行需要在 AutoMigrate 之前完成的结构迁移
+func MigrateSchema(db *gorm.DB) error {
+	return V1_2_0_AddKeyHash(db)
+}
+
+func MigrateDatabase(db *gorm.DB, encryptionService encryption.Service) error {
+	if err := V1_0_22_DropRetriesColumn(db); err != nil {
+		return err
+	}
+	if err := V1_1_0_SeedErrorRules(db); err != nil {
+		return err
+	}
+	return V1_2_1_MaskRequestLogKeys(db, encryptionService)
 }
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"gpt-load/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// V1_1_0_SeedErrorRules 创建错误规则表，并在首次创建时写入原先硬编码的不计数错误和可忽略错误
+func V1_1_0_SeedErrorRules(db *gorm.DB) error {
+	isNewTable := !db.Migrator().HasTable(&models.ErrorRule{})
+	if err := db.AutoMigrate(&models.ErrorRule{}); err != nil {
+		return err
+	}
+	if !isNewTable {
+		return nil
+	}
+
+	rules := []models.ErrorRule{
+		{Name: "Resource exhausted", MessagePattern: "resource has been exhausted", Action: models.ErrorRuleActionIgnore},
+		{Name: "Context length exceeded", MessagePattern: "please reduce the length of the messages", Action: models.ErrorRuleActionIgnore},
+		{Name: "Client disconnected", MessagePattern: "context canceled|connection reset by peer|broken pipe|use of closed network connection|request canceled", Action: models.ErrorRuleActionNoRetry},
+	}
+	for i := range rules {
+		rules[i].Sort = i + 1
+		rules[i].Enabled = true
+	}
+	return db.Create(&rules).Error
+}
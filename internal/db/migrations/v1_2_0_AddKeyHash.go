@@ -0,0 +1,49 @@
+package db
+
+import (
+	"gpt-load/internal/encryption"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyHash 用于迁移的临时结构体
+type APIKeyHash struct {
+	ID       uint
+	KeyValue string
+	KeyHash  string `gorm:"type:varchar(64);not null;default:''"`
+}
+
+func (APIKeyHash) TableName() string {
+	return "api_keys"
+}
+
+// V1_2_0_AddKeyHash 为 api_keys 表添加 key_hash 列并回填，唯一索引从 (group_id, key_value) 改为 (group_id, key_hash)。
+// 需要在 AutoMigrate 之前执行，否则新唯一索引会因为空的 key_hash 重复而创建失败。
+func V1_2_0_AddKeyHash(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&APIKeyHash{}) || migrator.HasColumn(&APIKeyHash{}, "key_hash") {
+		return nil
+	}
+
+	if migrator.HasIndex(&APIKeyHash{}, "idx_group_key") {
+		if err := migrator.DropIndex(&APIKeyHash{}, "idx_group_key"); err != nil {
+			return err
+		}
+	}
+	if err := migrator.AddColumn(&APIKeyHash{}, "KeyHash"); err != nil {
+		return err
+	}
+
+	// 加密功能之前的密钥值都是明文，直接计算哈希
+	var batch []APIKeyHash
+	return db.Select("id, key_value").FindInBatches(&batch, 1000, func(_ *gorm.DB, _ int) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			for _, key := range batch {
+				if err := tx.Model(&APIKeyHash{}).Where("id = ?", key.ID).Update("key_hash", encryption.HashKey(key.KeyValue)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}).Error
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const maskLogKeysBatchSize = 500
+
+// V1_2_1_MaskRequestLogKeys 把历史日志中的完整密钥改写为 key_id 加脱敏预览。
+// 旧日志的 key_value 可能是明文或用当前主密钥加密的密文，解密后按哈希关联到 api_keys，找不到的密钥 key_id 保持为 0。
+// 脱敏预览总是包含 ****（短密钥只保留长度），已脱敏的记录不再处理，可以重复执行。
+func V1_2_1_MaskRequestLogKeys(db *gorm.DB, svc encryption.Service) error {
+	if !db.Migrator().HasTable(&models.RequestLog{}) {
+		return nil
+	}
+
+	type keyRef struct {
+		GroupID uint
+		KeyHash string
+	}
+
+	var logs []models.RequestLog
+	return db.Model(&models.RequestLog{}).Select("id, group_id, key_value").
+		Where("key_id = 0 AND key_value <> '' AND key_value NOT LIKE ?", "%****%").
+		FindInBatches(&logs, maskLogKeysBatchSize, func(_ *gorm.DB, _ int) error {
+			hashes := make([]string, len(logs))
+			previews := make([]string, len(logs))
+			for i, log := range logs {
+				keyValue, err := svc.Decrypt(log.KeyValue)
+				if err != nil {
+					return fmt.Errorf("failed to decrypt key of request log %s: %w", log.ID, err)
+				}
+				hashes[i] = encryption.HashKey(keyValue)
+				previews[i] = utils.MaskAPIKey(keyValue)
+			}
+
+			var keys []models.APIKey
+			if err := db.Select("id, group_id, key_hash").Where("key_hash IN ?", hashes).Order("id ASC").Find(&keys).Error; err != nil {
+				return err
+			}
+			// 优先关联同分组的密钥，密钥已移出原分组时退回到任意分组中的同一密钥
+			keyIDs := make(map[keyRef]uint, len(keys))
+			anyGroupKeyIDs := make(map[string]uint, len(keys))
+			for _, key := range keys {
+				keyIDs[keyRef{GroupID: key.GroupID, KeyHash: key.KeyHash}] = key.ID
+				if _, ok := anyGroupKeyIDs[key.KeyHash]; !ok {
+					anyGroupKeyIDs[key.KeyHash] = key.ID
+				}
+			}
+
+			return db.Transaction(func(tx *gorm.DB) error {
+				for i, log := range logs {
+					keyID, ok := keyIDs[keyRef{GroupID: log.GroupID, KeyHash: hashes[i]}]
+					if !ok {
+						keyID = anyGroupKeyIDs[hashes[i]]
+					}
+					if err := tx.Model(&models.RequestLog{}).Where("id = ?", log.ID).Updates(map[string]any{
+						"key_id":    keyID,
+						"key_value": previews[i],
+						"key_hash":  hashes[i],
+					}).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}).Error
+}
@@ -0,0 +1,184 @@
+// Package encryption provides envelope encryption for API key values stored at rest.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gpt-load/internal/types"
+)
+
+// encryptedPrefix 标记加密后的值，格式为 enc:v1:<加密后的数据密钥>:<加密后的密钥值>
+const encryptedPrefix = "enc:v1:"
+
+// ErrNoMasterKey 表示遇到了加密值，但没有配置主密钥
+var ErrNoMasterKey = errors.New("value is encrypted but no encryption key is configured")
+
+// Service encrypts and decrypts key values.
+// 未配置主密钥时加密原样返回明文，解密只接受明文。
+type Service interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(value string) (string, error)
+	Enabled() bool
+}
+
+// NewServiceFromConfig creates the Service from the configured master key.
+func NewServiceFromConfig(configManager types.ConfigManager) (Service, error) {
+	return NewService(configManager.GetEncryptionKey())
+}
+
+// NewService creates a Service with the given master key, an empty key disables encryption.
+func NewService(masterKey string) (Service, error) {
+	if masterKey == "" {
+		return noopService{}, nil
+	}
+
+	// 主密钥可以是任意长度的字符串，使用其 SHA-256 作为密钥加密密钥
+	kek := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(kek[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key encryption cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key encryption cipher: %w", err)
+	}
+	return &envelopeService{kek: aead}, nil
+}
+
+// IsEncrypted reports whether the value was produced by Encrypt with a master key.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// HashKey returns the hex SHA-256 of a plaintext key value.
+// 哈希不依赖主密钥，用于在密钥值加密后按值查找和去重，轮换主密钥时无需重新计算。
+func HashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashKeys returns the hashes of the plaintext key values in the same order.
+func HashKeys(plaintexts []string) []string {
+	hashes := make([]string, len(plaintexts))
+	for i, plaintext := range plaintexts {
+		hashes[i] = HashKey(plaintext)
+	}
+	return hashes
+}
+
+type noopService struct{}
+
+func (noopService) Encrypt(plaintext string) (string, error) {
+	return plaintext, nil
+}
+
+func (noopService) Decrypt(value string) (string, error) {
+	if IsEncrypted(value) {
+		return "", ErrNoMasterKey
+	}
+	return value, nil
+}
+
+func (noopService) Enabled() bool {
+	return false
+}
+
+// envelopeService 为每个值生成随机的数据密钥，用数据密钥加密值，再用主密钥加密数据密钥
+type envelopeService struct {
+	kek cipher.AEAD
+}
+
+func (s *envelopeService) Encrypt(plaintext string) (string, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrappedKey, err := seal(s.kek, dek)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt 解密加密值，未加密的旧数据原样返回
+func (s *envelopeService) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	wrappedPart, ciphertextPart, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(wrappedPart)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted data key: %w", err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(ciphertextPart)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	dek, err := open(s.kek, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt data key, the encryption key may be wrong: %w", err)
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (s *envelopeService) Enabled() bool {
+	return true
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal 加密数据，返回 nonce 与密文拼接的结果
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
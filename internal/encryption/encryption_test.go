@@ -0,0 +1,192 @@
+package encryption
+
+import (
+	"errors"
+	"testing"
+)
+
+func mustService(t *testing.T, masterKey string) Service {
+	t.Helper()
+	svc, err := NewService(masterKey)
+	if err != nil {
+		t.Fatalf("NewService(%q) error = %v", masterKey, err)
+	}
+	return svc
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	svc := mustService(t, "master-key")
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "openai key", plaintext: "sk-proj-abcdefghijklmnopqrstuvwxyz0123456789"},
+		{name: "empty value", plaintext: ""},
+		{name: "value with separators", plaintext: "enc:v1:not:really:encrypted"},
+		{name: "unicode value", plaintext: "密钥-🔑"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := svc.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if !IsEncrypted(encrypted) {
+				t.Fatalf("Encrypt() = %q, want the %q prefix", encrypted, encryptedPrefix)
+			}
+			decrypted, err := svc.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Errorf("Decrypt() = %q, want %q", decrypted, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesFreshDataKeys(t *testing.T) {
+	svc := mustService(t, "master-key")
+
+	first, err := svc.Encrypt("sk-same")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	second, err := svc.Encrypt("sk-same")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if first == second {
+		t.Error("encrypting the same value twice produced identical ciphertexts")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	svc := mustService(t, "master-key")
+	encrypted, err := svc.Encrypt("sk-secret")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		svc     Service
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "legacy plaintext passes through", svc: svc, value: "sk-legacy", want: "sk-legacy"},
+		{name: "wrong master key", svc: mustService(t, "other-key"), value: encrypted, wantErr: true},
+		{name: "malformed value", svc: svc, value: encryptedPrefix + "missing-separator", wantErr: true},
+		{name: "invalid base64", svc: svc, value: encryptedPrefix + "!!!:!!!", wantErr: true},
+		{name: "truncated ciphertext", svc: svc, value: encrypted[:len(encrypted)-8], wantErr: true},
+		{name: "disabled service reads plaintext", svc: mustService(t, ""), value: "sk-plain", want: "sk-plain"},
+		{name: "disabled service rejects encrypted value", svc: mustService(t, ""), value: encrypted, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.svc.Decrypt(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoopService(t *testing.T) {
+	svc := mustService(t, "")
+	if svc.Enabled() {
+		t.Error("Enabled() = true for an empty master key")
+	}
+	encrypted, err := svc.Encrypt("sk-plain")
+	if err != nil || encrypted != "sk-plain" {
+		t.Errorf("Encrypt() = %q, %v, want the plaintext unchanged", encrypted, err)
+	}
+	if _, err := svc.Decrypt(encryptedPrefix + "a:b"); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("Decrypt() error = %v, want ErrNoMasterKey", err)
+	}
+}
+
+func TestReencryptValue(t *testing.T) {
+	const plaintext = "sk-rotate-me"
+	plain := mustService(t, "")
+	oldKey := mustService(t, "old-key")
+	newKey := mustService(t, "new-key")
+
+	encryptedOld, err := oldKey.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	encryptedNew, err := newKey.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		value         string
+		from, to      Service
+		wantKeyValue  bool
+		wantEncrypted bool
+		wantErr       bool
+	}{
+		{name: "enable encryption", value: plaintext, from: plain, to: newKey, wantKeyValue: true, wantEncrypted: true},
+		{name: "rotate master key", value: encryptedOld, from: oldKey, to: newKey, wantKeyValue: true, wantEncrypted: true},
+		{name: "already rotated value only backfills the hash", value: encryptedNew, from: oldKey, to: newKey},
+		{name: "disable encryption", value: encryptedOld, from: oldKey, to: plain, wantKeyValue: true},
+		{name: "unknown master key", value: encryptedOld, from: newKey, to: plain, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, err := reencryptValue(tt.value, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reencryptValue() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if updates["key_hash"] != HashKey(plaintext) {
+				t.Errorf("key_hash = %v, want %s", updates["key_hash"], HashKey(plaintext))
+			}
+
+			keyValue, ok := updates["key_value"].(string)
+			if ok != tt.wantKeyValue {
+				t.Fatalf("key_value present = %t, want %t", ok, tt.wantKeyValue)
+			}
+			if !ok {
+				return
+			}
+			if IsEncrypted(keyValue) != tt.wantEncrypted {
+				t.Errorf("IsEncrypted(key_value) = %t, want %t", IsEncrypted(keyValue), tt.wantEncrypted)
+			}
+			decrypted, err := tt.to.Decrypt(keyValue)
+			if err != nil || decrypted != plaintext {
+				t.Errorf("target Decrypt() = %q, %v, want %q", decrypted, err, plaintext)
+			}
+		})
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	if HashKey("sk-a") != HashKey("sk-a") {
+		t.Error("HashKey is not stable for the same input")
+	}
+	if HashKey("sk-a") == HashKey("sk-b") {
+		t.Error("HashKey collides for different inputs")
+	}
+	if got := HashKey(""); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("HashKey(\"\") = %s, want the SHA-256 of the empty string", got)
+	}
+
+	hashes := HashKeys([]string{"sk-a", "sk-b"})
+	if len(hashes) != 2 || hashes[0] != HashKey("sk-a") || hashes[1] != HashKey("sk-b") {
+		t.Errorf("HashKeys() = %v, want hashes in input order", hashes)
+	}
+}
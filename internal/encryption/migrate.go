@@ -0,0 +1,100 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const reencryptBatchSize = 500
+
+// ReencryptResult holds the number of rows rewritten by Reencrypt.
+type ReencryptResult struct {
+	Keys int64
+}
+
+// CheckMasterKey 启动时检查主密钥能否解密数据库中已加密的密钥值，避免用错误的主密钥运行
+func CheckMasterKey(db *gorm.DB, svc Service) error {
+	if !db.Migrator().HasTable(&models.APIKey{}) {
+		return nil
+	}
+
+	var sample models.APIKey
+	err := db.Select("id, key_value").Where("key_value LIKE ?", encryptedPrefix+"%").Order("id ASC").First(&sample).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if svc.Enabled() {
+			var plaintextCount int64
+			if err := db.Model(&models.APIKey{}).Where("key_value NOT LIKE ?", encryptedPrefix+"%").Count(&plaintextCount).Error; err != nil {
+				return err
+			}
+			if plaintextCount > 0 {
+				logrus.Warnf("%d API keys are still stored in plaintext, run `gpt-load migrate-keys` to encrypt them.", plaintextCount)
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check encrypted keys: %w", err)
+	}
+
+	if !svc.Enabled() {
+		return fmt.Errorf("API keys in the database are encrypted, set ENCRYPTION_KEY or ENCRYPTION_KEY_FILE to start")
+	}
+	if _, err := svc.Decrypt(sample.KeyValue); err != nil {
+		return fmt.Errorf("ENCRYPTION_KEY cannot decrypt key %d: %w", sample.ID, err)
+	}
+	return nil
+}
+
+// Reencrypt 用 from 解密、用 to 重新加密 api_keys 中的密钥值，同时回填 key_hash。
+// request_logs 只保存脱敏预览，不需要重新加密。
+// 用于首次启用加密、轮换主密钥或关闭加密，执行期间服务应当停止。
+func Reencrypt(db *gorm.DB, from, to Service) (ReencryptResult, error) {
+	var result ReencryptResult
+
+	var keys []models.APIKey
+	err := db.Model(&models.APIKey{}).Select("id, key_value").
+		FindInBatches(&keys, reencryptBatchSize, func(_ *gorm.DB, _ int) error {
+			return db.Transaction(func(tx *gorm.DB) error {
+				for _, key := range keys {
+					updates, err := reencryptValue(key.KeyValue, from, to)
+					if err != nil {
+						return fmt.Errorf("key %d: %w", key.ID, err)
+					}
+					if err := tx.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+						return err
+					}
+					result.Keys++
+				}
+				return nil
+			})
+		}).Error
+	if err != nil {
+		return result, fmt.Errorf("failed to re-encrypt api keys: %w", err)
+	}
+
+	return result, nil
+}
+
+// reencryptValue 返回重新加密后需要更新的字段，已经用新主密钥加密过的值保持不变，中断后可以重新执行
+func reencryptValue(value string, from, to Service) (map[string]any, error) {
+	plaintext, err := from.Decrypt(value)
+	if err != nil {
+		if plaintext, toErr := to.Decrypt(value); toErr == nil && IsEncrypted(value) {
+			return map[string]any{"key_hash": HashKey(plaintext)}, nil
+		}
+		return nil, err
+	}
+	encrypted, err := to.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"key_value": encrypted,
+		"key_hash":  HashKey(plaintext),
+	}, nil
+}
@@ -0,0 +1,196 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 上游错误分类
+const (
+	ErrorCategoryAuthInvalid      = "auth_invalid"
+	ErrorCategoryAccountSuspended = "account_suspended"
+	ErrorCategoryQuotaExhausted   = "quota_exhausted"
+	ErrorCategoryRateLimited      = "rate_limited"
+	ErrorCategoryContentFiltered  = "content_filtered"
+	ErrorCategoryContextTooLong   = "context_too_long"
+	ErrorCategoryBadRequest       = "bad_request"
+	ErrorCategoryUpstream5xx      = "upstream_5xx"
+	ErrorCategoryNetwork          = "network"
+	ErrorCategoryUnknown          = "unknown"
+)
+
+// UpstreamError describes a classified failure returned by (or while reaching) an upstream service.
+type UpstreamError struct {
+	StatusCode int    // 上游状态码，网络错误时为 0
+	Category   string // 错误分类
+	Message    string // 解析后的错误信息
+	Terminal   bool   // 错误明确表明密钥永远无法恢复
+	StatusOnly bool   // 响应体未匹配任何分类，分类仅由状态码推断
+}
+
+// Error implements the error interface.
+func (e *UpstreamError) Error() string {
+	return e.Message
+}
+
+// NewUpstreamError parses and classifies an upstream error response.
+func NewUpstreamError(statusCode int, body []byte) *UpstreamError {
+	category, terminal, matched := classifyUpstreamError(statusCode, body)
+	return &UpstreamError{
+		StatusCode: statusCode,
+		Category:   category,
+		Message:    ParseUpstreamError(body),
+		Terminal:   terminal,
+		StatusOnly: !matched,
+	}
+}
+
+// NewNetworkError wraps a transport-level error as an upstream error.
+func NewNetworkError(err error) *UpstreamError {
+	return &UpstreamError{
+		Category: ErrorCategoryNetwork,
+		Message:  err.Error(),
+	}
+}
+
+// AsUpstreamError extracts an UpstreamError from err, wrapping unknown errors.
+// It returns nil when err is nil.
+func AsUpstreamError(err error) *UpstreamError {
+	if err == nil {
+		return nil
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr
+	}
+	return &UpstreamError{Category: ErrorCategoryUnknown, Message: err.Error()}
+}
+
+// CategoryOf returns the error category carried by err, or an empty string if it is not classified.
+func CategoryOf(err error) string {
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Category
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == ErrContextTooLong.Code {
+		return ErrorCategoryContextTooLong
+	}
+	return ""
+}
+
+// providerErrorResponse matches the machine-readable fields used by OpenAI, Anthropic and Gemini.
+type providerErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type   string `json:"type"`
+		Code   any    `json:"code"`
+		Status string `json:"status"`
+	} `json:"error"`
+}
+
+// categoryMatcher 通过错误码或错误信息片段匹配分类
+type categoryMatcher struct {
+	category string
+	codes    []string
+	messages []string
+	terminal bool // 匹配时表示密钥永远无法恢复
+}
+
+// categoryMatchers 按优先级排列，先匹配的分类生效
+var categoryMatchers = []categoryMatcher{
+	{
+		category: ErrorCategoryAccountSuspended,
+		codes:    []string{"account_deactivated", "account_suspended", "organization_deactivated"},
+		messages: []string{"has been suspended", "has been deactivated", "account has been disabled", "organization has been disabled", "account is not active"},
+		terminal: true,
+	},
+	{
+		category: ErrorCategoryQuotaExhausted,
+		codes:    []string{"insufficient_quota", "billing_hard_limit_reached", "billing_not_active"},
+		messages: []string{"exceeded your current quota", "insufficient balance", "credit balance is too low", "insufficient_quota", "billing hard limit has been reached"},
+	},
+	{
+		category: ErrorCategoryContextTooLong,
+		codes:    []string{"context_length_exceeded", "string_above_max_length"},
+		messages: []string{"please reduce the length of the messages", "maximum context length", "prompt is too long", "input token count", "too many tokens", "context window"},
+	},
+	{
+		category: ErrorCategoryContentFiltered,
+		codes:    []string{"content_filter", "content_policy_violation"},
+		messages: []string{"content management policy", "content filter", "safety system", "content_policy"},
+	},
+	{
+		category: ErrorCategoryAuthInvalid,
+		codes:    []string{"invalid_api_key", "api_key_invalid"},
+		messages: []string{"api key not valid", "invalid api key", "incorrect api key", "invalid x-api-key", "api key expired"},
+		terminal: true,
+	},
+	{
+		category: ErrorCategoryAuthInvalid,
+		codes:    []string{"authentication_error", "unauthenticated", "permission_denied", "permission_error"},
+		messages: []string{"invalid authentication"},
+	},
+	{
+		category: ErrorCategoryRateLimited,
+		codes:    []string{"rate_limit_exceeded", "rate_limit_error", "resource_exhausted"},
+		messages: []string{"rate limit", "too many requests", "resource has been exhausted"},
+	},
+}
+
+// ClassifyUpstreamError maps an upstream status code and provider error body to an error category.
+func ClassifyUpstreamError(statusCode int, body []byte) string {
+	category, _, _ := classifyUpstreamError(statusCode, body)
+	return category
+}
+
+// classifyUpstreamError 返回错误分类、错误是否明确表明密钥永远无法恢复，以及分类是否由错误码或错误信息匹配得出。
+// 按状态码兜底的分类不视为不可恢复。
+func classifyUpstreamError(statusCode int, body []byte) (string, bool, bool) {
+	var codes []string
+	var providerErr providerErrorResponse
+	if err := json.Unmarshal(body, &providerErr); err == nil {
+		for _, code := range []string{providerErr.Type, providerErr.Error.Type, providerErr.Error.Status} {
+			if code != "" {
+				codes = append(codes, strings.ToLower(code))
+			}
+		}
+		if providerErr.Error.Code != nil {
+			codes = append(codes, strings.ToLower(fmt.Sprint(providerErr.Error.Code)))
+		}
+	}
+	message := strings.ToLower(ParseUpstreamError(body))
+
+	for _, matcher := range categoryMatchers {
+		for _, code := range matcher.codes {
+			for _, actual := range codes {
+				if actual == code {
+					return matcher.category, matcher.terminal, true
+				}
+			}
+		}
+		for _, fragment := range matcher.messages {
+			if strings.Contains(message, fragment) {
+				return matcher.category, matcher.terminal, true
+			}
+		}
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrorCategoryAuthInvalid, false, false
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorCategoryRateLimited, false, false
+	case statusCode == http.StatusPaymentRequired:
+		return ErrorCategoryQuotaExhausted, false, false
+	case statusCode >= 500:
+		return ErrorCategoryUpstream5xx, false, false
+	case statusCode >= 400:
+		return ErrorCategoryBadRequest, false, false
+	default:
+		return ErrorCategoryUnknown, false, false
+	}
+}
@@ -0,0 +1,165 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNewUpstreamError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		category   string
+		terminal   bool
+		statusOnly bool
+	}{
+		{
+			name:     "openai invalid api key",
+			status:   http.StatusUnauthorized,
+			body:     `{"error":{"message":"Incorrect API key provided: sk-abc.","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			category: ErrorCategoryAuthInvalid,
+			terminal: true,
+		},
+		{
+			name:     "anthropic authentication error",
+			status:   http.StatusUnauthorized,
+			body:     `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			category: ErrorCategoryAuthInvalid,
+			terminal: true,
+		},
+		{
+			name:     "gemini api key invalid",
+			status:   http.StatusBadRequest,
+			body:     `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`,
+			category: ErrorCategoryAuthInvalid,
+			terminal: true,
+		},
+		{
+			name:     "permission denied is recoverable",
+			status:   http.StatusForbidden,
+			body:     `{"error":{"code":403,"message":"Caller does not have permission","status":"PERMISSION_DENIED"}}`,
+			category: ErrorCategoryAuthInvalid,
+		},
+		{
+			name:     "account deactivated",
+			status:   http.StatusUnauthorized,
+			body:     `{"error":{"message":"This key is associated with a deactivated account.","code":"account_deactivated"}}`,
+			category: ErrorCategoryAccountSuspended,
+			terminal: true,
+		},
+		{
+			name:     "insufficient quota",
+			status:   http.StatusTooManyRequests,
+			body:     `{"error":{"message":"You exceeded your current quota, please check your plan and billing details.","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			category: ErrorCategoryQuotaExhausted,
+		},
+		{
+			name:       "billing mentioned without a hard limit is not quota",
+			status:     http.StatusBadRequest,
+			body:       `{"error":{"message":"Invalid billing address format"}}`,
+			category:   ErrorCategoryBadRequest,
+			statusOnly: true,
+		},
+		{
+			name:     "rate limited",
+			status:   http.StatusTooManyRequests,
+			body:     `{"error":{"message":"Rate limit reached for requests","type":"requests","code":"rate_limit_exceeded"}}`,
+			category: ErrorCategoryRateLimited,
+		},
+		{
+			name:     "gemini resource exhausted",
+			status:   http.StatusTooManyRequests,
+			body:     `{"error":{"code":429,"message":"Resource has been exhausted (e.g. check quota).","status":"RESOURCE_EXHAUSTED"}}`,
+			category: ErrorCategoryRateLimited,
+		},
+		{
+			name:     "context too long",
+			status:   http.StatusBadRequest,
+			body:     `{"error":{"message":"This model's maximum context length is 8192 tokens.","code":"context_length_exceeded"}}`,
+			category: ErrorCategoryContextTooLong,
+		},
+		{
+			name:     "content filtered",
+			status:   http.StatusBadRequest,
+			body:     `{"error":{"message":"The response was filtered due to the prompt triggering content management policy.","code":"content_filter"}}`,
+			category: ErrorCategoryContentFiltered,
+		},
+		{
+			name:       "status only unauthorized",
+			status:     http.StatusUnauthorized,
+			body:       `Unauthorized`,
+			category:   ErrorCategoryAuthInvalid,
+			statusOnly: true,
+		},
+		{
+			name:       "status only payment required",
+			status:     http.StatusPaymentRequired,
+			body:       `{}`,
+			category:   ErrorCategoryQuotaExhausted,
+			statusOnly: true,
+		},
+		{
+			name:       "status only server error",
+			status:     http.StatusBadGateway,
+			body:       `<html>Bad Gateway</html>`,
+			category:   ErrorCategoryUpstream5xx,
+			statusOnly: true,
+		},
+		{
+			name:       "status only bad request",
+			status:     http.StatusBadRequest,
+			body:       `{"error":{"message":"unknown parameter"}}`,
+			category:   ErrorCategoryBadRequest,
+			statusOnly: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewUpstreamError(tt.status, []byte(tt.body))
+			if got.Category != tt.category || got.Terminal != tt.terminal || got.StatusOnly != tt.statusOnly {
+				t.Errorf("NewUpstreamError(%d, %s) = {Category: %s, Terminal: %t, StatusOnly: %t}, want {Category: %s, Terminal: %t, StatusOnly: %t}",
+					tt.status, tt.body, got.Category, got.Terminal, got.StatusOnly, tt.category, tt.terminal, tt.statusOnly)
+			}
+			if got.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", got.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	upstreamErr := NewUpstreamError(http.StatusTooManyRequests, []byte(`{"error":{"code":"rate_limit_exceeded"}}`))
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "upstream error", err: upstreamErr, want: ErrorCategoryRateLimited},
+		{name: "wrapped upstream error", err: fmt.Errorf("[status 429] %w", upstreamErr), want: ErrorCategoryRateLimited},
+		{name: "context too long api error", err: ErrContextTooLong, want: ErrorCategoryContextTooLong},
+		{name: "network error", err: NewNetworkError(errors.New("connection reset")), want: ErrorCategoryNetwork},
+		{name: "plain error", err: errors.New("boom"), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CategoryOf(tt.err); got != tt.want {
+				t.Errorf("CategoryOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsUpstreamError(t *testing.T) {
+	if AsUpstreamError(nil) != nil {
+		t.Error("AsUpstreamError(nil) should return nil")
+	}
+	if got := AsUpstreamError(errors.New("boom")); got.Category != ErrorCategoryUnknown || got.Message != "boom" {
+		t.Errorf("AsUpstreamError(plain) = %+v, want unknown category with the original message", got)
+	}
+}
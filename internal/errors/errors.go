@@ -26,6 +26,7 @@ func (e *APIError) Error() string {
 var (
 	ErrBadRequest         = &APIError{HTTPStatus: http.StatusBadRequest, Code: "BAD_REQUEST", Message: "Invalid request parameters"}
 	ErrInvalidJSON        = &APIError{HTTPStatus: http.StatusBadRequest, Code: "INVALID_JSON", Message: "Invalid JSON format"}
+	ErrContextTooLong     = &APIError{HTTPStatus: http.StatusBadRequest, Code: "CONTEXT_TOO_LONG", Message: "The prompt exceeds the maximum context length of this group"}
 	ErrValidation         = &APIError{HTTPStatus: http.StatusBadRequest, Code: "VALIDATION_FAILED", Message: "Input validation failed"}
 	ErrDuplicateResource  = &APIError{HTTPStatus: http.StatusConflict, Code: "DUPLICATE_RESOURCE", Message: "Resource already exists"}
 	ErrResourceNotFound   = &APIError{HTTPStatus: http.StatusNotFound, Code: "NOT_FOUND", Message: "Resource not found"}
@@ -37,7 +38,10 @@ var (
 	ErrBadGateway         = &APIError{HTTPStatus: http.StatusBadGateway, Code: "BAD_GATEWAY", Message: "Upstream service error"}
 	ErrNoActiveKeys       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
 	ErrMaxRetriesExceeded = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
+	ErrNoMatchingKeys     = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_MATCHING_KEYS", Message: "No active API keys in this group match the requested model or key routing rules"}
 	ErrNoKeysAvailable    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrTooManyRequests    = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "TOO_MANY_REQUESTS", Message: "Too many concurrent requests for this group or proxy key"}
+	ErrServerBusy         = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "SERVER_BUSY", Message: "Too many concurrent requests, please try again later"}
 )
 
 // NewAPIError creates a new APIError with a custom message.
@@ -4,6 +4,7 @@ import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,12 @@ func (s *Server) Stats(c *gin.Context) {
 	s.DB.Model(&models.APIKey{}).Where("status = ?", models.KeyStatusInvalid).Count(&invalidKeys)
 
 	now := time.Now()
+	expiringKeys, err := s.getExpiringKeys(now)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, "failed to get expiring keys"))
+		return
+	}
+
 	rpmStats, err := s.getRPMStats(now)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, "failed to get rpm stats"))
@@ -100,11 +107,60 @@ func (s *Server) Stats(c *gin.Context) {
 			Trend:         errorRateTrend,
 			TrendIsGrowth: errorRateTrendIsGrowth,
 		},
+		ExpiringKeys: expiringKeys,
 	}
 
 	response.Success(c, stats)
 }
 
+// maxExpiringKeys 是仪表盘最多展示的即将过期密钥数量
+const maxExpiringKeys = 20
+
+// getExpiringKeys 返回在提醒天数内即将过期的有效密钥，按过期时间升序排列
+func (s *Server) getExpiringKeys(now time.Time) ([]models.ExpiringKey, error) {
+	expiringKeys := []models.ExpiringKey{}
+	warningDays := s.SettingsManager.GetSettings().KeyExpiryWarningDays
+	if warningDays <= 0 {
+		return expiringKeys, nil
+	}
+
+	var keys []models.APIKey
+	err := s.DB.Where("status = ? AND expires_at IS NOT NULL AND expires_at > ? AND expires_at <= ?", models.KeyStatusActive, now, now.AddDate(0, 0, warningDays)).
+		Order("expires_at ASC").
+		Limit(maxExpiringKeys).
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames := make(map[uint]string)
+	for _, key := range keys {
+		name, ok := groupNames[key.GroupID]
+		if !ok {
+			var group models.Group
+			if err := s.DB.Select("name", "display_name").First(&group, key.GroupID).Error; err == nil {
+				name = group.DisplayName
+				if name == "" {
+					name = group.Name
+				}
+			}
+			groupNames[key.GroupID] = name
+		}
+		keyValue, err := s.KeyService.Encryption.Decrypt(key.KeyValue)
+		if err != nil {
+			return nil, err
+		}
+		expiringKeys = append(expiringKeys, models.ExpiringKey{
+			ID:        key.ID,
+			GroupID:   key.GroupID,
+			GroupName: name,
+			KeyValue:  utils.MaskAPIKey(keyValue),
+			ExpiresAt: *key.ExpiresAt,
+		})
+	}
+	return expiringKeys, nil
+}
+
 // Chart Get dashboard chart data
 func (s *Server) Chart(c *gin.Context) {
 	groupID := c.Query("groupId")
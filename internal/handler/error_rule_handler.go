@@ -0,0 +1,201 @@
+// Package handler provides HTTP handlers for the application
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorRuleRequest 定义创建或更新错误规则的请求体
+type ErrorRuleRequest struct {
+	GroupID         *uint  `json:"group_id"`
+	Name            string `json:"name"`
+	StatusCode      int    `json:"status_code"`
+	MessagePattern  string `json:"message_pattern"`
+	ChannelType     string `json:"channel_type"`
+	Action          string `json:"action"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+	Sort            int    `json:"sort"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+var validErrorRuleActions = map[string]bool{
+	models.ErrorRuleActionCount:    true,
+	models.ErrorRuleActionIgnore:   true,
+	models.ErrorRuleActionDisable:  true,
+	models.ErrorRuleActionCooldown: true,
+	models.ErrorRuleActionNoRetry:  true,
+	models.ErrorRuleActionRetire:   true,
+}
+
+// validateErrorRuleRequest 校验请求并转换为错误规则
+func (s *Server) validateErrorRuleRequest(req *ErrorRuleRequest, rule *models.ErrorRule) error {
+	action := strings.TrimSpace(req.Action)
+	if !validErrorRuleActions[action] {
+		return fmt.Errorf("invalid action: %s", req.Action)
+	}
+	if action == models.ErrorRuleActionCooldown && req.CooldownSeconds <= 0 {
+		return fmt.Errorf("cooldown_seconds must be greater than 0 for cooldown action")
+	}
+	if req.StatusCode != 0 && (req.StatusCode < 100 || req.StatusCode > 599) {
+		return fmt.Errorf("invalid status code: %d", req.StatusCode)
+	}
+
+	pattern := strings.TrimSpace(req.MessagePattern)
+	if pattern != "" {
+		if _, err := keypool.CompileErrorRulePattern(pattern); err != nil {
+			return fmt.Errorf("invalid message pattern: %v", err)
+		}
+	}
+
+	channelType := strings.TrimSpace(req.ChannelType)
+	if channelType != "" && !isValidChannelType(channelType) {
+		return fmt.Errorf("invalid channel type: %s", channelType)
+	}
+
+	if req.GroupID != nil {
+		var count int64
+		if err := s.DB.Model(&models.Group{}).Where("id = ?", *req.GroupID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("group %d not found", *req.GroupID)
+		}
+	}
+
+	rule.GroupID = req.GroupID
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.StatusCode = req.StatusCode
+	rule.MessagePattern = pattern
+	rule.ChannelType = channelType
+	rule.Action = action
+	rule.CooldownSeconds = req.CooldownSeconds
+	rule.Sort = req.Sort
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// invalidateErrorRules 通知所有实例重新加载错误规则
+func (s *Server) invalidateErrorRules(c *gin.Context) {
+	if err := s.ErrorRuleManager.Invalidate(); err != nil {
+		logrus.WithContext(c.Request.Context()).WithError(err).Error("failed to invalidate error rule cache")
+	}
+}
+
+// ListErrorRules 获取错误规则列表，可按分组过滤
+func (s *Server) ListErrorRules(c *gin.Context) {
+	query := s.DB.Order("sort ASC, id ASC")
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		groupID, err := strconv.Atoi(groupIDStr)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid group ID format"))
+			return
+		}
+		query = query.Where("group_id = ?", groupID)
+	}
+
+	var rules []models.ErrorRule
+	if err := query.Find(&rules).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, rules)
+}
+
+// CreateErrorRule 创建错误规则
+func (s *Server) CreateErrorRule(c *gin.Context) {
+	var req ErrorRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	rule := models.ErrorRule{Enabled: true}
+	if err := s.validateErrorRuleRequest(&req, &rule); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	if err := s.DB.Create(&rule).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	// enabled 字段有数据库默认值，零值 false 需要单独更新
+	if !rule.Enabled {
+		if err := s.DB.Model(&rule).Update("enabled", false).Error; err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+	}
+
+	s.invalidateErrorRules(c)
+	response.Success(c, rule)
+}
+
+// UpdateErrorRule 更新错误规则
+func (s *Server) UpdateErrorRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid error rule ID format"))
+		return
+	}
+
+	var req ErrorRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	var rule models.ErrorRule
+	if err := s.DB.First(&rule, id).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	if err := s.validateErrorRuleRequest(&req, &rule); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	if err := s.DB.Save(&rule).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	s.invalidateErrorRules(c)
+	response.Success(c, rule)
+}
+
+// DeleteErrorRule 删除错误规则
+func (s *Server) DeleteErrorRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid error rule ID format"))
+		return
+	}
+
+	result := s.DB.Delete(&models.ErrorRule{}, id)
+	if result.Error != nil {
+		response.Error(c, app_errors.ParseDBError(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.Error(c, app_errors.ErrResourceNotFound)
+		return
+	}
+
+	s.invalidateErrorRules(c)
+	response.Success(c, gin.H{"message": "错误规则删除成功"})
+}
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
 	"sync"
 
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"gpt-load/internal/utils"
 	"reflect"
 	"regexp"
@@ -93,6 +95,59 @@ func validateAndCleanUpstreams(upstreams json.RawMessage) (datatypes.JSON, error
 	return cleanedUpstreams, nil
 }
 
+// validateAndCleanValidationTemplate validates the custom validation template.
+// It returns nil when the template is empty or null, meaning the channel default is used.
+func validateAndCleanValidationTemplate(raw json.RawMessage) (datatypes.JSON, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil, nil
+	}
+
+	var tmpl models.ValidationTemplate
+	if err := json.Unmarshal(raw, &tmpl); err != nil {
+		return nil, fmt.Errorf("invalid format for validation template: %w", err)
+	}
+	if err := channel.CheckValidationTemplate(&tmpl); err != nil {
+		return nil, err
+	}
+
+	cleaned, err := json.Marshal(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal validation template: %w", err)
+	}
+	return cleaned, nil
+}
+
+// validateAndCleanKeyRoutingRules validates the key routing rules and normalizes their tags.
+func validateAndCleanKeyRoutingRules(rules []models.KeyRoutingRule) (datatypes.JSON, error) {
+	cleaned := make([]models.KeyRoutingRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Model = strings.TrimSpace(rule.Model)
+		rule.ProxyKey = strings.TrimSpace(rule.ProxyKey)
+		if rule.Model != "" {
+			if _, err := path.Match(rule.Model, ""); err != nil {
+				return nil, fmt.Errorf("key routing rule %d: invalid model pattern %q", i+1, rule.Model)
+			}
+		}
+
+		tags, err := utils.NormalizeTags(rule.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("key routing rule %d: %w", i+1, err)
+		}
+		if len(tags) == 0 {
+			return nil, fmt.Errorf("key routing rule %d: at least one tag is required", i+1)
+		}
+		rule.Tags = tags
+		cleaned = append(cleaned, rule)
+	}
+
+	rulesJSON, err := json.Marshal(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key routing rules: %w", err)
+	}
+	return rulesJSON, nil
+}
+
 // isValidGroupName checks if the group name is valid.
 func isValidGroupName(name string) bool {
 	if name == "" {
@@ -171,20 +226,22 @@ func (s *Server) validateAndCleanConfig(configMap map[string]any) (map[string]an
 
 // GroupCreateRequest defines the payload for creating a group.
 type GroupCreateRequest struct {
-	Name               string              `json:"name"`
-	DisplayName        string              `json:"display_name"`
-	Description        string              `json:"description"`
-	CodeSnippet        string              `json:"code_snippet"`
-	Upstreams          json.RawMessage     `json:"upstreams"`
-	ChannelType        string              `json:"channel_type"`
-	Sort               int                 `json:"sort"`
-	TestModel          string              `json:"test_model"`
-	ValidationEndpoint string              `json:"validation_endpoint"`
-	ParamOverrides     map[string]any      `json:"param_overrides"`
-	Config             map[string]any      `json:"config"`
-	HeaderRules        []models.HeaderRule `json:"header_rules"`
-	ProxyKeys          string              `json:"proxy_keys"`
-	ForceHTTP11        *bool               `json:"force_http11,omitempty"`
+	Name               string                  `json:"name"`
+	DisplayName        string                  `json:"display_name"`
+	Description        string                  `json:"description"`
+	CodeSnippet        string                  `json:"code_snippet"`
+	Upstreams          json.RawMessage         `json:"upstreams"`
+	ChannelType        string                  `json:"channel_type"`
+	Sort               int                     `json:"sort"`
+	TestModel          string                  `json:"test_model"`
+	ValidationEndpoint string                  `json:"validation_endpoint"`
+	ParamOverrides     map[string]any          `json:"param_overrides"`
+	Config             map[string]any          `json:"config"`
+	HeaderRules        []models.HeaderRule     `json:"header_rules"`
+	ValidationTemplate json.RawMessage         `json:"validation_template"`
+	KeyRoutingRules    []models.KeyRoutingRule `json:"key_routing_rules"`
+	ProxyKeys          string                  `json:"proxy_keys"`
+	ForceHTTP11        *bool                   `json:"force_http11,omitempty"`
 }
 
 // CreateGroup handles the creation of a new group.
@@ -233,6 +290,18 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		return
 	}
 
+	validationTemplate, err := validateAndCleanValidationTemplate(req.ValidationTemplate)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	keyRoutingRules, err := validateAndCleanKeyRoutingRules(req.KeyRoutingRules)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
 	// Validate and normalize header rules if provided
 	var headerRulesJSON datatypes.JSON
 	if len(req.HeaderRules) > 0 {
@@ -288,6 +357,8 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		ParamOverrides:     req.ParamOverrides,
 		Config:             cleanedConfig,
 		HeaderRules:        headerRulesJSON,
+		ValidationTemplate: validationTemplate,
+		KeyRoutingRules:    keyRoutingRules,
 		ProxyKeys:          strings.TrimSpace(req.ProxyKeys),
 		ForceHTTP11:        req.ForceHTTP11,
 	}
@@ -322,21 +393,23 @@ func (s *Server) ListGroups(c *gin.Context) {
 // GroupUpdateRequest defines the payload for updating a group.
 // Using a dedicated struct avoids issues with zero values being ignored by GORM's Update.
 type GroupUpdateRequest struct {
-	Name               *string             `json:"name,omitempty"`
-	DisplayName        *string             `json:"display_name,omitempty"`
-	Description        *string             `json:"description,omitempty"`
-	CodeSnippet        *string             `json:"code_snippet,omitempty"`
-	Upstreams          json.RawMessage     `json:"upstreams"`
-	ChannelType        *string             `json:"channel_type,omitempty"`
-	Sort               *int                `json:"sort"`
-	TestModel          string              `json:"test_model"`
-	ValidationEndpoint *string             `json:"validation_endpoint,omitempty"`
-	ParamOverrides     map[string]any      `json:"param_overrides"`
-	Config             map[string]any      `json:"config"`
-	HeaderRules        []models.HeaderRule `json:"header_rules"`
-	ProxyKeys          *string             `json:"proxy_keys,omitempty"`
-	ForceHTTP11        *bool               `json:"force_http11,omitempty"`
-	CCRModels          []string            `json:"ccr_models,omitempty"`
+	Name               *string                 `json:"name,omitempty"`
+	DisplayName        *string                 `json:"display_name,omitempty"`
+	Description        *string                 `json:"description,omitempty"`
+	CodeSnippet        *string                 `json:"code_snippet,omitempty"`
+	Upstreams          json.RawMessage         `json:"upstreams"`
+	ChannelType        *string                 `json:"channel_type,omitempty"`
+	Sort               *int                    `json:"sort"`
+	TestModel          string                  `json:"test_model"`
+	ValidationEndpoint *string                 `json:"validation_endpoint,omitempty"`
+	ParamOverrides     map[string]any          `json:"param_overrides"`
+	Config             map[string]any          `json:"config"`
+	HeaderRules        []models.HeaderRule     `json:"header_rules"`
+	ValidationTemplate json.RawMessage         `json:"validation_template"`
+	KeyRoutingRules    []models.KeyRoutingRule `json:"key_routing_rules"`
+	ProxyKeys          *string                 `json:"proxy_keys,omitempty"`
+	ForceHTTP11        *bool                   `json:"force_http11,omitempty"`
+	CCRModels          []string                `json:"ccr_models,omitempty"`
 }
 
 // UpdateGroup handles updating an existing group.
@@ -438,6 +511,24 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		group.ValidationEndpoint = validationEndpoint
 	}
 
+	if req.ValidationTemplate != nil {
+		validationTemplate, err := validateAndCleanValidationTemplate(req.ValidationTemplate)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		group.ValidationTemplate = validationTemplate
+	}
+
+	if req.KeyRoutingRules != nil {
+		keyRoutingRules, err := validateAndCleanKeyRoutingRules(req.KeyRoutingRules)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		group.KeyRoutingRules = keyRoutingRules
+	}
+
 	if req.Config != nil {
 		cleanedConfig, err := s.validateAndCleanConfig(req.Config)
 		if err != nil {
@@ -578,29 +669,31 @@ func (s *Server) UpdateGroupsOrder(c *gin.Context) {
 
 // GroupResponse defines the structure for a group response, excluding sensitive or large fields.
 type GroupResponse struct {
-	ID                 uint                `json:"id"`
-	Name               string              `json:"name"`
-	Endpoint           string              `json:"endpoint"`
-	DisplayName        string              `json:"display_name"`
-	Description        string              `json:"description"`
-	CodeSnippet        string              `json:"code_snippet"`
-	CCRModels          []string            `json:"ccr_models"`
-	Upstreams          datatypes.JSON      `json:"upstreams"`
-	ChannelType        string              `json:"channel_type"`
-	Sort               int                 `json:"sort"`
-	TestModel          string              `json:"test_model"`
-	ValidationEndpoint string              `json:"validation_endpoint"`
-	ParamOverrides     datatypes.JSONMap   `json:"param_overrides"`
-	Config             datatypes.JSONMap   `json:"config"`
-	HeaderRules        []models.HeaderRule `json:"header_rules"`
-	ProxyKeys          string              `json:"proxy_keys"`
-	ForceHTTP11        *bool               `json:"force_http11,omitempty"`
-	LastValidatedAt    *time.Time          `json:"last_validated_at"`
-	Archived           bool                `json:"archived"`
-	ArchivedAt         *time.Time          `json:"archived_at"`
-	CategoryID         *uint               `json:"category_id"`
-	CreatedAt          time.Time           `json:"created_at"`
-	UpdatedAt          time.Time           `json:"updated_at"`
+	ID                 uint                    `json:"id"`
+	Name               string                  `json:"name"`
+	Endpoint           string                  `json:"endpoint"`
+	DisplayName        string                  `json:"display_name"`
+	Description        string                  `json:"description"`
+	CodeSnippet        string                  `json:"code_snippet"`
+	CCRModels          []string                `json:"ccr_models"`
+	Upstreams          datatypes.JSON          `json:"upstreams"`
+	ChannelType        string                  `json:"channel_type"`
+	Sort               int                     `json:"sort"`
+	TestModel          string                  `json:"test_model"`
+	ValidationEndpoint string                  `json:"validation_endpoint"`
+	ParamOverrides     datatypes.JSONMap       `json:"param_overrides"`
+	Config             datatypes.JSONMap       `json:"config"`
+	HeaderRules        []models.HeaderRule     `json:"header_rules"`
+	ValidationTemplate datatypes.JSON          `json:"validation_template"`
+	KeyRoutingRules    []models.KeyRoutingRule `json:"key_routing_rules"`
+	ProxyKeys          string                  `json:"proxy_keys"`
+	ForceHTTP11        *bool                   `json:"force_http11,omitempty"`
+	LastValidatedAt    *time.Time              `json:"last_validated_at"`
+	Archived           bool                    `json:"archived"`
+	ArchivedAt         *time.Time              `json:"archived_at"`
+	CategoryID         *uint                   `json:"category_id"`
+	CreatedAt          time.Time               `json:"created_at"`
+	UpdatedAt          time.Time               `json:"updated_at"`
 }
 
 // newGroupResponse creates a new GroupResponse from a models.Group.
@@ -636,6 +729,15 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		}
 	}
 
+	// Parse key routing rules from JSON
+	keyRoutingRules := make([]models.KeyRoutingRule, 0)
+	if len(group.KeyRoutingRules) > 0 {
+		if err := json.Unmarshal(group.KeyRoutingRules, &keyRoutingRules); err != nil {
+			logrus.WithError(err).Error("Failed to unmarshal key routing rules")
+			keyRoutingRules = make([]models.KeyRoutingRule, 0)
+		}
+	}
+
 	return &GroupResponse{
 		ID:                 group.ID,
 		Name:               group.Name,
@@ -652,6 +754,8 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		ParamOverrides:     group.ParamOverrides,
 		Config:             group.Config,
 		HeaderRules:        headerRules,
+		ValidationTemplate: group.ValidationTemplate,
+		KeyRoutingRules:    keyRoutingRules,
 		ProxyKeys:          group.ProxyKeys,
 		ForceHTTP11:        group.ForceHTTP11,
 		LastValidatedAt:    group.LastValidatedAt,
@@ -814,9 +918,11 @@ func (s *Server) GetGroupConfigOptions(c *gin.Context) {
 
 // KeyStats defines the statistics for API keys in a group.
 type KeyStats struct {
-	TotalKeys   int64 `json:"total_keys"`
-	ActiveKeys  int64 `json:"active_keys"`
-	InvalidKeys int64 `json:"invalid_keys"`
+	TotalKeys     int64 `json:"total_keys"`
+	ActiveKeys    int64 `json:"active_keys"`
+	InvalidKeys   int64 `json:"invalid_keys"`
+	RetiredKeys   int64 `json:"retired_keys"`
+	ExhaustedKeys int64 `json:"exhausted_keys"`
 }
 
 // RequestStats defines the statistics for requests over a period.
@@ -826,12 +932,20 @@ type RequestStats struct {
 	FailureRate    float64 `json:"failure_rate"`
 }
 
+// BalanceStats defines the remaining credit of the active keys in a group.
+type BalanceStats struct {
+	TotalBalance   float64 `json:"total_balance"`
+	CheckedKeys    int64   `json:"checked_keys"`
+	LowBalanceKeys int64   `json:"low_balance_keys"`
+}
+
 // GroupStatsResponse defines the complete statistics for a group.
 type GroupStatsResponse struct {
-	KeyStats    KeyStats     `json:"key_stats"`
-	HourlyStats RequestStats `json:"hourly_stats"` // 1 hour
-	DailyStats  RequestStats `json:"daily_stats"`  // 24 hours
-	WeeklyStats RequestStats `json:"weekly_stats"` // 7 days
+	KeyStats     KeyStats     `json:"key_stats"`
+	BalanceStats BalanceStats `json:"balance_stats"`
+	HourlyStats  RequestStats `json:"hourly_stats"` // 1 hour
+	DailyStats   RequestStats `json:"daily_stats"`  // 24 hours
+	WeeklyStats  RequestStats `json:"weekly_stats"` // 7 days
 }
 
 // calculateRequestStats is a helper to compute request statistics.
@@ -873,7 +987,7 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		var totalKeys, activeKeys int64
+		var totalKeys, activeKeys, retiredKeys, exhaustedKeys int64
 
 		if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Count(&totalKeys).Error; err != nil {
 			mu.Lock()
@@ -887,17 +1001,69 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 			mu.Unlock()
 			return
 		}
+		if err := s.DB.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusRetired).Count(&retiredKeys).Error; err != nil {
+			mu.Lock()
+			errors = append(errors, fmt.Errorf("failed to get retired keys: %w", err))
+			mu.Unlock()
+			return
+		}
+		if err := s.DB.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusExhausted).Count(&exhaustedKeys).Error; err != nil {
+			mu.Lock()
+			errors = append(errors, fmt.Errorf("failed to get exhausted keys: %w", err))
+			mu.Unlock()
+			return
+		}
 
 		mu.Lock()
 		resp.KeyStats = KeyStats{
-			TotalKeys:   totalKeys,
-			ActiveKeys:  activeKeys,
-			InvalidKeys: totalKeys - activeKeys,
+			TotalKeys:     totalKeys,
+			ActiveKeys:    activeKeys,
+			InvalidKeys:   totalKeys - activeKeys - retiredKeys - exhaustedKeys,
+			RetiredKeys:   retiredKeys,
+			ExhaustedKeys: exhaustedKeys,
+		}
+		mu.Unlock()
+	}()
+
+	// 3. 余额统计，仅统计已查询到余额的有效 Key
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var result struct {
+			TotalBalance float64
+			CheckedKeys  int64
+		}
+		err := s.DB.Model(&models.APIKey{}).
+			Select("COALESCE(SUM(balance), 0) as total_balance, COUNT(balance) as checked_keys").
+			Where("group_id = ? AND status = ? AND balance IS NOT NULL", groupID, models.KeyStatusActive).
+			Scan(&result).Error
+		if err != nil {
+			mu.Lock()
+			errors = append(errors, fmt.Errorf("failed to get balance stats: %w", err))
+			mu.Unlock()
+			return
+		}
+
+		var lowBalanceKeys int64
+		if threshold := s.SettingsManager.GetEffectiveConfig(group.Config).KeyLowBalanceThreshold; threshold > 0 {
+			if err := s.DB.Model(&models.APIKey{}).Where("group_id = ? AND status = ? AND balance < ?", groupID, models.KeyStatusActive, threshold).Count(&lowBalanceKeys).Error; err != nil {
+				mu.Lock()
+				errors = append(errors, fmt.Errorf("failed to get low balance keys: %w", err))
+				mu.Unlock()
+				return
+			}
+		}
+
+		mu.Lock()
+		resp.BalanceStats = BalanceStats{
+			TotalBalance:   result.TotalBalance,
+			CheckedKeys:    result.CheckedKeys,
+			LowBalanceKeys: lowBalanceKeys,
 		}
 		mu.Unlock()
 	}()
 
-	// 3. 1小时请求统计 (查询 request_logs 表)
+	// 4. 1小时请求统计 (查询 request_logs 表)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -923,7 +1089,7 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 		mu.Unlock()
 	}()
 
-	// 4. 24小时和7天统计 (查询 group_hourly_stats 表)
+	// 5. 24小时和7天统计 (查询 group_hourly_stats 表)
 	// 辅助函数，用于从 group_hourly_stats 查询
 	queryHourlyStats := func(duration time.Duration) (RequestStats, error) {
 		var result struct {
@@ -1134,6 +1300,10 @@ func (s *Server) CopyGroup(c *gin.Context) {
 			response.Error(c, app_errors.ParseDBError(err))
 			return
 		}
+		if err := s.KeyService.DecryptKeys(sourceKeys); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+			return
+		}
 
 		// Extract key values for async import task
 		for _, sourceKey := range sourceKeys {
@@ -1158,7 +1328,7 @@ func (s *Server) CopyGroup(c *gin.Context) {
 		keysText := strings.Join(sourceKeyValues, "\n")
 
 		// Directly reuse the AddMultipleKeysAsync logic from key_handler.go
-		if _, err := s.KeyImportService.StartImportTask(&newGroup, keysText); err != nil {
+		if _, err := s.KeyImportService.StartImportTask(&newGroup, keysText, services.KeyImportFormatText); err != nil {
 			logrus.WithFields(logrus.Fields{
 				"groupId":  newGroup.ID,
 				"keyCount": len(sourceKeyValues),
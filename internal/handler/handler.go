@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"gpt-load/internal/config"
+	"gpt-load/internal/keypool"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
 
@@ -26,7 +27,9 @@ type Server struct {
 	KeyService                 *services.KeyService
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
+	KeyTransferService         *services.KeyTransferService
 	LogService                 *services.LogService
+	ErrorRuleManager           *keypool.ErrorRuleManager
 	CommonHandler              *CommonHandler
 }
 
@@ -42,7 +45,9 @@ type NewServerParams struct {
 	KeyService                 *services.KeyService
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
+	KeyTransferService         *services.KeyTransferService
 	LogService                 *services.LogService
+	ErrorRuleManager           *keypool.ErrorRuleManager
 	CommonHandler              *CommonHandler
 }
 
@@ -58,7 +63,9 @@ func NewServer(params NewServerParams) *Server {
 		KeyService:                 params.KeyService,
 		KeyImportService:           params.KeyImportService,
 		KeyDeleteService:           params.KeyDeleteService,
+		KeyTransferService:         params.KeyTransferService,
 		LogService:                 params.LogService,
+		ErrorRuleManager:           params.ErrorRuleManager,
 		CommonHandler:              params.CommonHandler,
 	}
 }
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
 	"strconv"
 	"strings"
@@ -58,10 +60,18 @@ type KeyTextRequest struct {
 	KeysText string `json:"keys_text" binding:"required"`
 }
 
+// KeyImportRequest defines the payload for importing keys as plain text, CSV or JSONL.
+type KeyImportRequest struct {
+	GroupID  uint   `json:"group_id" binding:"required"`
+	KeysText string `json:"keys_text" binding:"required"`
+	Format   string `json:"format" binding:"omitempty,oneof=text csv jsonl"` // detected when empty
+}
+
 // KeyTextWithRemarksRequest defines a payload for adding keys with remarks.
 type KeyTextWithRemarksRequest struct {
 	GroupID          uint   `json:"group_id" binding:"required"`
 	KeysText         string `json:"keys_text" binding:"required"`
+	Format           string `json:"format" binding:"omitempty,oneof=text csv jsonl"` // detected when empty
 	Remarks          string `json:"remarks"`
 	UseRemarksForAll bool   `json:"use_remarks_for_all"`
 }
@@ -91,6 +101,55 @@ type UpdateKeyRemarksRequest struct {
 	Remarks  string `json:"remarks"`
 }
 
+// UpdateKeyTagsRequest defines the payload for updating the tags of multiple keys.
+type UpdateKeyTagsRequest struct {
+	GroupID  uint     `json:"group_id" binding:"required"`
+	KeysText string   `json:"keys_text" binding:"required"`
+	Tags     []string `json:"tags"`
+	Action   string   `json:"action"` // set, add or remove
+}
+
+// UpdateKeyQuotaRequest defines the payload for setting usage quotas on multiple keys.
+type UpdateKeyQuotaRequest struct {
+	GroupID      uint   `json:"group_id" binding:"required"`
+	KeysText     string `json:"keys_text" binding:"required"`
+	QuotaPeriod  string `json:"quota_period"` // day, month or empty for unlimited
+	RequestQuota int64  `json:"request_quota"`
+	TokenQuota   int64  `json:"token_quota"`
+}
+
+// UpdateKeyScheduleRequest defines the payload for setting activation windows on multiple keys.
+type UpdateKeyScheduleRequest struct {
+	GroupID       uint       `json:"group_id" binding:"required"`
+	KeysText      string     `json:"keys_text" binding:"required"`
+	ValidFrom     *time.Time `json:"valid_from"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ActiveWindows string     `json:"active_windows"` // e.g. 22:00-06:00,12:00-13:00
+}
+
+// TransferKeysRequest defines the payload for moving or copying keys to another group.
+type TransferKeysRequest struct {
+	GroupID       uint   `json:"group_id" binding:"required"`
+	TargetGroupID uint   `json:"target_group_id" binding:"required"`
+	Mode          string `json:"mode" binding:"required,oneof=move copy"`
+	KeysText      string `json:"keys_text"` // selected keys, takes precedence over the filters
+	Status        string `json:"status"`
+	Tag           string `json:"tag"`
+}
+
+// DedupeKeysRequest defines the payload for removing duplicate keys across groups.
+type DedupeKeysRequest struct {
+	Type        string `json:"type" binding:"required,oneof=exact"` // only exact duplicates are removed automatically
+	Keep        string `json:"keep" binding:"required,oneof=oldest most_used"`
+	KeepGroupID uint   `json:"keep_group_id"` // prefer keeping the key in this group
+}
+
+// AssignLibraryKeyRequest defines the payload for adding a library key to other groups.
+type AssignLibraryKeyRequest struct {
+	KeyValue string `json:"key_value" binding:"required"`
+	GroupIDs []uint `json:"group_ids" binding:"required,min=1"`
+}
+
 // AddMultipleKeys handles creating new keys from a text block within a specific group.
 func (s *Server) AddMultipleKeys(c *gin.Context) {
 	var req KeyTextRequest
@@ -125,7 +184,7 @@ func (s *Server) AddMultipleKeys(c *gin.Context) {
 
 // AddMultipleKeysAsync handles creating new keys from a text block within a specific group.
 func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
-	var req KeyTextRequest
+	var req KeyImportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
 		return
@@ -141,7 +200,7 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 		return
 	}
 
-	taskStatus, err := s.KeyImportService.StartImportTask(group, req.KeysText)
+	taskStatus, err := s.KeyImportService.StartImportTask(group, req.KeysText, req.Format)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
 		return
@@ -168,7 +227,7 @@ func (s *Server) AddMultipleKeysAsyncWithRemarks(c *gin.Context) {
 		return
 	}
 
-	taskStatus, err := s.KeyImportService.StartImportTaskWithRemarks(group, req.KeysText, req.Remarks, req.UseRemarksForAll)
+	taskStatus, err := s.KeyImportService.StartImportTaskWithRemarks(group, req.KeysText, req.Format, req.Remarks, req.UseRemarksForAll)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
 		return
@@ -190,14 +249,15 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 	}
 
 	statusFilter := c.Query("status")
-	if statusFilter != "" && statusFilter != models.KeyStatusActive && statusFilter != models.KeyStatusInvalid {
+	if statusFilter != "" && statusFilter != models.KeyStatusActive && statusFilter != models.KeyStatusInvalid && statusFilter != models.KeyStatusRetired && statusFilter != models.KeyStatusExhausted {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Invalid status filter"))
 		return
 	}
 
 	searchKeyword := c.Query("key_value")
+	tagFilter := c.Query("tag")
 
-	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchKeyword)
+	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchKeyword, tagFilter)
 
 	var keys []models.APIKey
 	paginatedResult, err := response.Paginate(c, query, &keys)
@@ -205,6 +265,10 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
 	}
+	if err := s.KeyService.DecryptKeys(keys); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
 
 	response.Success(c, paginatedResult)
 }
@@ -268,6 +332,52 @@ func (s *Server) DeleteMultipleKeysAsync(c *gin.Context) {
 	response.Success(c, taskStatus)
 }
 
+// TransferKeysAsync handles moving or copying keys to another group using async task.
+func (s *Server) TransferKeysAsync(c *gin.Context) {
+	var req TransferKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	sourceGroup, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
+		return
+	}
+	targetGroup, ok := s.findGroupByID(c, req.TargetGroupID)
+	if !ok {
+		return
+	}
+	if sourceGroup.ID == targetGroup.ID {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Source and target group must be different"))
+		return
+	}
+
+	if req.KeysText != "" {
+		if err := validateKeysText(req.KeysText); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+	}
+	if req.Status != "" && req.Status != models.KeyStatusActive && req.Status != models.KeyStatusInvalid && req.Status != models.KeyStatusRetired && req.Status != models.KeyStatusExhausted {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Invalid status filter"))
+		return
+	}
+
+	filter := services.KeyTransferFilter{
+		KeysText: req.KeysText,
+		Status:   req.Status,
+		Tag:      req.Tag,
+	}
+	taskStatus, err := s.KeyTransferService.StartTransferTask(sourceGroup, targetGroup, req.Mode, filter)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
+		return
+	}
+
+	response.Success(c, taskStatus)
+}
+
 // RestoreMultipleKeys handles restoring keys from a text block within a specific group.
 func (s *Server) RestoreMultipleKeys(c *gin.Context) {
 	var req KeyTextRequest
@@ -353,7 +463,7 @@ func (s *Server) ValidateGroupKeys(c *gin.Context) {
 	}
 
 	// Validate status if provided
-	if req.Status != "" && req.Status != models.KeyStatusActive && req.Status != models.KeyStatusInvalid {
+	if req.Status != "" && req.Status != models.KeyStatusActive && req.Status != models.KeyStatusInvalid && req.Status != models.KeyStatusRetired && req.Status != models.KeyStatusExhausted {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Invalid status value"))
 		return
 	}
@@ -476,7 +586,7 @@ func (s *Server) ExportKeys(c *gin.Context) {
 	}
 
 	switch statusFilter {
-	case "all", models.KeyStatusActive, models.KeyStatusInvalid:
+	case "all", models.KeyStatusActive, models.KeyStatusInvalid, models.KeyStatusRetired, models.KeyStatusExhausted:
 	default:
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Invalid status filter"))
 		return
@@ -491,7 +601,7 @@ func (s *Server) ExportKeys(c *gin.Context) {
 	c.Header("Content-Disposition", "attachment; filename="+filename)
 	c.Header("Content-Type", "text/plain; charset=utf-8")
 
-	err = s.KeyService.StreamKeysToWriter(groupID, statusFilter, c.Writer)
+	err = s.KeyService.StreamKeysToWriter(groupID, statusFilter, c.Query("tag"), c.Writer)
 	if err != nil {
 		log.Printf("Failed to stream keys: %v", err)
 	}
@@ -543,3 +653,145 @@ func (s *Server) UpdateKeyRemarks(c *gin.Context) {
 
 	response.Success(c, gin.H{"message": "备注更新成功"})
 }
+
+// UpdateKeyTags 批量更新密钥标签
+func (s *Server) UpdateKeyTags(c *gin.Context) {
+	var req UpdateKeyTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+		return
+	}
+
+	updatedCount, err := s.KeyService.UpdateKeyTags(req.GroupID, req.KeysText, req.Tags, req.Action)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	response.Success(c, gin.H{"updated_count": updatedCount, "message": fmt.Sprintf("%d 个密钥的标签已更新", updatedCount)})
+}
+
+// UpdateKeyQuota 批量设置密钥用量配额
+func (s *Server) UpdateKeyQuota(c *gin.Context) {
+	var req UpdateKeyQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+		return
+	}
+
+	updatedCount, err := s.KeyService.UpdateKeyQuota(req.GroupID, req.KeysText, req.QuotaPeriod, req.RequestQuota, req.TokenQuota)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	response.Success(c, gin.H{"updated_count": updatedCount, "message": fmt.Sprintf("%d 个密钥的配额已更新", updatedCount)})
+}
+
+// UpdateKeySchedule 批量设置密钥生效时间、过期时间和可用时段
+func (s *Server) UpdateKeySchedule(c *gin.Context) {
+	var req UpdateKeyScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	group, ok := s.findGroupByID(c, req.GroupID)
+	if !ok {
+		return
+	}
+	group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+
+	updatedCount, err := s.KeyService.UpdateKeySchedule(group, req.KeysText, req.ValidFrom, req.ExpiresAt, req.ActiveWindows)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	response.Success(c, gin.H{"updated_count": updatedCount, "message": fmt.Sprintf("%d 个密钥的可用时间已更新", updatedCount)})
+}
+
+// ListLibraryKeys 列出密钥库中去重后的密钥及引用它们的分组
+func (s *Server) ListLibraryKeys(c *gin.Context) {
+	query := s.KeyService.LibraryKeysQuery(c.Query("key_value"))
+
+	var libraryKeys []services.LibraryKey
+	paginatedResult, err := response.Paginate(c, query, &libraryKeys)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	if err := s.KeyService.FillLibraryKeyGroups(libraryKeys); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	paginatedResult.Items = libraryKeys
+
+	response.Success(c, paginatedResult)
+}
+
+// AssignLibraryKey 将密钥库中的密钥加入其他分组
+func (s *Server) AssignLibraryKey(c *gin.Context) {
+	var req AssignLibraryKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	assignedCount, err := s.KeyService.AssignLibraryKey(req.KeyValue, req.GroupIDs)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	response.Success(c, gin.H{"assigned_count": assignedCount, "message": fmt.Sprintf("密钥已加入 %d 个分组", assignedCount)})
+}
+
+// ListDuplicateKeys 列出跨分组的重复密钥和规范化后相同的密钥
+func (s *Server) ListDuplicateKeys(c *gin.Context) {
+	duplicateType := c.Query("type")
+	if duplicateType != "" && duplicateType != services.DuplicateTypeExact && duplicateType != services.DuplicateTypeNormalized {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "Invalid duplicate type"))
+		return
+	}
+
+	report, err := s.KeyService.FindDuplicateKeys(duplicateType)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, report)
+}
+
+// DedupeKeys 批量去重，每组重复密钥只保留一个
+func (s *Server) DedupeKeys(c *gin.Context) {
+	var req DedupeKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if req.KeepGroupID != 0 {
+		if _, ok := s.findGroupByID(c, req.KeepGroupID); !ok {
+			return
+		}
+	}
+
+	removedCount, err := s.KeyService.DedupeKeys(req.Type, req.Keep, req.KeepGroupID)
+	if errors.Is(err, services.ErrSharedKeysNotDeduped) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "已开启跨分组共享密钥状态，多个分组中的相同密钥为共享密钥，不会被去重"))
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, gin.H{"removed_count": removedCount, "message": fmt.Sprintf("已移除 %d 个重复密钥", removedCount)})
+}
@@ -0,0 +1,124 @@
+package keypool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// balanceCheckBatchSize 是每个分组每轮最多查询余额的 Key 数量
+const balanceCheckBatchSize = 20
+
+// isLowBalance 判断余额是否低于阈值，未知余额或阈值为 0 时不视为低余额
+func isLowBalance(balance *float64, threshold int) bool {
+	return balance != nil && threshold > 0 && *balance < float64(threshold)
+}
+
+// lowBalance 按全局阈值判断 Key 是否余额不足，分组覆盖的阈值由余额查询任务修正
+func (p *KeyProvider) lowBalance(key *models.APIKey) bool {
+	return isLowBalance(key.Balance, p.settingsManager.GetSettings().KeyLowBalanceThreshold)
+}
+
+// isLowBalanceKey 读取 store 中 Key 详情的低余额标记
+func isLowBalanceKey(keyDetails map[string]string) bool {
+	low, _ := strconv.ParseBool(keyDetails["low_balance"])
+	return low
+}
+
+// checkKeyBalances 查询分组内到期的有效 Key 的剩余余额，并同步到数据库和 store。
+// 余额接口失败不计入 Key 的失败次数，只记录查询时间，等待下一个周期重试。
+func (s *CronChecker) checkKeyBalances(group *models.Group) {
+	hours := group.EffectiveConfig.KeyBalanceCheckHours
+	if hours <= 0 {
+		return
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(hours) * time.Hour)
+
+	var keys []models.APIKey
+	err := s.DB.Where("group_id = ? AND status = ? AND is_disabled = ?", group.ID, models.KeyStatusActive, false).
+		Where("balance_checked_at IS NULL OR balance_checked_at <= ?", cutoff).
+		Order("balance_checked_at IS NULL DESC, balance_checked_at ASC").
+		Limit(balanceCheckBatchSize).
+		Find(&keys).Error
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to get keys for balance check in group %s: %v", group.Name, err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	ch, err := s.Validator.channelFactory.GetChannel(group)
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to get channel for group %s: %v", group.Name, err)
+		return
+	}
+
+	threshold := group.EffectiveConfig.KeyLowBalanceThreshold
+	var checkedCount int
+	for i := range keys {
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+
+		key, err := s.Validator.keypoolProvider.DecryptKey(&keys[i])
+		if err != nil {
+			logrus.Errorf("CronChecker: Skipping balance check: %v", err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds)*time.Second)
+		balance, err := ch.CheckBalance(ctx, key, group)
+		cancel()
+
+		if errors.Is(err, channel.ErrBalanceUnsupported) {
+			logrus.Debugf("CronChecker: Balance check is not available for group '%s'.", group.Name)
+			return
+		}
+
+		updates := map[string]any{"balance_checked_at": now}
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error":    err,
+				"key_id":   key.ID,
+				"group_id": group.ID,
+			}).Debug("Key balance check failed")
+		} else {
+			updates["balance"] = balance
+		}
+
+		checked := err == nil
+		err = s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to save balance: %w", err)
+			}
+			if !checked {
+				return nil
+			}
+			if err := s.Validator.keypoolProvider.updateCachedKeyFields(key.ID, map[string]any{"low_balance": isLowBalance(&balance, threshold)}); err != nil {
+				return fmt.Errorf("failed to cache balance: %w", err)
+			}
+			return s.Validator.keypoolProvider.SyncSharedKey(tx, key.ID)
+		})
+		if err != nil {
+			logrus.Errorf("CronChecker: Failed to update balance for key %d: %v", key.ID, err)
+			continue
+		}
+		if checked {
+			checkedCount++
+		}
+	}
+
+	logrus.Debugf("CronChecker: Checked balance for %d of %d keys in group '%s'.", checkedCount, len(keys), group.Name)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"gpt-load/internal/config"
 	"gpt-load/internal/models"
+	"gpt-load/internal/schedule"
+	"gpt-load/internal/types"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,6 +15,7 @@ import (
 )
 
 // NewCronChecker is responsible for periodically validating invalid keys.
+// Each invalid key has its own next validation time, which backs off exponentially on repeated failures.
 type CronChecker struct {
 	DB              *gorm.DB
 	SettingsManager *config.SystemSettingsManager
@@ -66,13 +69,15 @@ func (s *CronChecker) runLoop() {
 
 	s.submitValidationJobs()
 
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			logrus.Debug("CronChecker: Running as Master, submitting validation jobs.")
+			s.Validator.keypoolProvider.RestoreExpiredCooldowns()
+			s.Validator.keypoolProvider.RestoreExhaustedKeys()
 			s.submitValidationJobs()
 		case <-s.stopChan:
 			return
@@ -80,7 +85,8 @@ func (s *CronChecker) runLoop() {
 	}
 }
 
-// submitValidationJobs finds groups whose keys need validation and validates them concurrently.
+// submitValidationJobs applies key activation schedules, validates the due invalid keys of every group concurrently,
+// probes active keys within each group's budget, refreshes the models supported by active keys and checks their balances.
 func (s *CronChecker) submitValidationJobs() {
 	var groups []models.Group
 	if err := s.DB.Find(&groups).Error; err != nil {
@@ -88,43 +94,114 @@ func (s *CronChecker) submitValidationJobs() {
 		return
 	}
 
-	validationStartTime := time.Now()
 	var wg sync.WaitGroup
 
 	for i := range groups {
 		group := &groups[i]
 		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
-		interval := time.Duration(group.EffectiveConfig.KeyValidationIntervalMinutes) * time.Minute
-
-		if group.LastValidatedAt == nil || validationStartTime.Sub(*group.LastValidatedAt) > interval {
-			wg.Add(1)
-			g := group
-			go func() {
-				defer wg.Done()
-				s.validateGroupKeys(g)
-			}()
-		}
+
+		wg.Add(1)
+		g := group
+		go func() {
+			defer wg.Done()
+			s.Validator.keypoolProvider.SyncKeySchedules(g)
+			s.validateGroupKeys(g)
+			s.probeActiveKeys(g)
+			s.discoverKeyModels(g)
+			s.checkKeyBalances(g)
+		}()
 	}
 
 	wg.Wait()
 }
 
-// validateGroupKeys validates all invalid keys for a single group concurrently.
+// nextValidationAt 计算第 failures 次验证失败后的下次验证时间：从验证间隔开始逐次翻倍，不超过最大间隔
+func nextValidationAt(cfg types.SystemSettings, failures int) time.Time {
+	delay := time.Duration(cfg.KeyValidationIntervalMinutes) * time.Minute
+	maxDelay := time.Duration(cfg.KeyValidationMaxMinutes) * time.Minute
+	for range failures {
+		if delay >= maxDelay {
+			break
+		}
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	return time.Now().Add(delay)
+}
+
+// isScheduleDue 判断自上次验证以来是否已到达 Cron 计划的时间点。
+// 从未验证过的分组仅在当前分钟匹配计划时执行。
+func isScheduleDue(cronExpr string, lastValidatedAt *time.Time, now time.Time) (bool, error) {
+	sched, err := schedule.Parse(cronExpr)
+	if err != nil {
+		return false, err
+	}
+	since := now.Add(-time.Minute)
+	if lastValidatedAt != nil {
+		since = *lastValidatedAt
+	}
+	next := sched.Next(since)
+	return !next.IsZero() && !next.After(now), nil
+}
+
+// scheduleNextValidation 记录一次失败的验证，并按退避计划安排下次验证
+func (s *CronChecker) scheduleNextValidation(key *models.APIKey, group *models.Group) {
+	failures := key.ValidationFailures + 1
+	updates := map[string]any{
+		"validation_failures": failures,
+		"next_validation_at":  nextValidationAt(group.EffectiveConfig, failures),
+	}
+	// 其他分组中的相同密钥沿用同一验证计划，避免重复验证
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("id = ? AND status = ?", key.ID, models.KeyStatusInvalid).Updates(updates).Error; err != nil {
+			return err
+		}
+		return s.Validator.keypoolProvider.SyncSharedKey(tx, key.ID)
+	})
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to schedule next validation for key %d: %v", key.ID, err)
+	}
+}
+
+// validateGroupKeys validates the invalid keys of a single group that are due for validation concurrently.
+// Retired keys are never revalidated here.
 func (s *CronChecker) validateGroupKeys(group *models.Group) {
 	groupProcessStart := time.Now()
 
+	query := s.DB.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusInvalid)
+	if cronExpr := group.EffectiveConfig.KeyValidationCron; cronExpr != "" {
+		// 配置了验证计划时，仅在计划时间验证全部无效 Key
+		due, err := isScheduleDue(cronExpr, group.LastValidatedAt, groupProcessStart)
+		if err != nil {
+			logrus.Errorf("CronChecker: Invalid validation schedule for group %s: %v", group.Name, err)
+			return
+		}
+		if !due {
+			return
+		}
+		if err := s.DB.Model(group).Update("last_validated_at", groupProcessStart).Error; err != nil {
+			logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
+			return
+		}
+	} else {
+		query = query.Where("next_validation_at IS NULL OR next_validation_at <= ?", groupProcessStart)
+	}
+
 	var invalidKeys []models.APIKey
-	err := s.DB.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusInvalid).Find(&invalidKeys).Error
-	if err != nil {
+	if err := query.Find(&invalidKeys).Error; err != nil {
 		logrus.Errorf("CronChecker: Failed to get invalid keys for group %s: %v", group.Name, err)
 		return
 	}
 
 	if len(invalidKeys) == 0 {
-		if err := s.DB.Model(group).Update("last_validated_at", time.Now()).Error; err != nil {
-			logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
+		if group.EffectiveConfig.KeyValidationCron == "" {
+			if err := s.DB.Model(group).Update("last_validated_at", time.Now()).Error; err != nil {
+				logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
+			}
 		}
-		logrus.Infof("CronChecker: Group '%s' has no invalid keys to check.", group.Name)
+		logrus.Debugf("CronChecker: Group '%s' has no invalid keys due for validation.", group.Name)
 		return
 	}
 
@@ -146,6 +223,8 @@ func (s *CronChecker) validateGroupKeys(group *models.Group) {
 					isValid, _ := s.Validator.ValidateSingleKey(key, group)
 					if isValid {
 						atomic.AddInt32(&becameValidCount, 1)
+					} else {
+						s.scheduleNextValidation(key, group)
 					}
 				case <-s.stopChan:
 					return
@@ -0,0 +1,44 @@
+package keypool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsScheduleDue(t *testing.T) {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		cron    string
+		last    *time.Time
+		now     *time.Time
+		want    bool
+		wantErr bool
+	}{
+		{name: "scheduled time reached", cron: "0 * * * *", last: at(9, 0), now: at(10, 0), want: true},
+		{name: "scheduled time passed during a long gap", cron: "0 * * * *", last: at(7, 0), now: at(10, 30), want: true},
+		{name: "already validated this period", cron: "0 * * * *", last: at(10, 0), now: at(10, 30), want: false},
+		{name: "next run not reached", cron: "0 * * * *", last: at(9, 0), now: at(9, 59), want: false},
+		{name: "never validated and the minute matches", cron: "0 * * * *", now: at(10, 0), want: true},
+		{name: "never validated outside the scheduled minute", cron: "0 * * * *", now: at(10, 1), want: false},
+		{name: "daily schedule", cron: "30 3 * * *", last: at(0, 0), now: at(3, 30), want: true},
+		{name: "invalid expression", cron: "61 * * * *", now: at(10, 0), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isScheduleDue(tt.cron, tt.last, *tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("isScheduleDue() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("isScheduleDue() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
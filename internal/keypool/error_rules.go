@@ -0,0 +1,136 @@
+package keypool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/syncer"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const ErrorRuleUpdateChannel = "error_rules:updated"
+
+// compiledErrorRule 缓存编译后的错误信息正则
+type compiledErrorRule struct {
+	rule    models.ErrorRule
+	pattern *regexp.Regexp
+}
+
+// ErrorRuleManager manages the caching and matching of admin-defined error rules.
+type ErrorRuleManager struct {
+	syncer *syncer.CacheSyncer[[]compiledErrorRule]
+	db     *gorm.DB
+	store  store.Store
+}
+
+// NewErrorRuleManager creates a new, uninitialized ErrorRuleManager.
+func NewErrorRuleManager(db *gorm.DB, store store.Store) *ErrorRuleManager {
+	return &ErrorRuleManager{
+		db:    db,
+		store: store,
+	}
+}
+
+// Initialize sets up the CacheSyncer for error rules.
+func (m *ErrorRuleManager) Initialize() error {
+	loader := func() ([]compiledErrorRule, error) {
+		var rules []models.ErrorRule
+		if err := m.db.Where("enabled = ?", true).Order("sort asc, id asc").Find(&rules).Error; err != nil {
+			return nil, fmt.Errorf("failed to load error rules from db: %w", err)
+		}
+
+		compiled := make([]compiledErrorRule, 0, len(rules))
+		for _, rule := range rules {
+			item := compiledErrorRule{rule: rule}
+			if rule.MessagePattern != "" {
+				pattern, err := CompileErrorRulePattern(rule.MessagePattern)
+				if err != nil {
+					logrus.WithError(err).WithField("rule_id", rule.ID).Warn("Skipping error rule with invalid message pattern")
+					continue
+				}
+				item.pattern = pattern
+			}
+			compiled = append(compiled, item)
+		}
+		return compiled, nil
+	}
+
+	syncer, err := syncer.NewCacheSyncer(
+		loader,
+		m.store,
+		ErrorRuleUpdateChannel,
+		logrus.WithField("syncer", "error_rules"),
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create error rule syncer: %w", err)
+	}
+	m.syncer = syncer
+	return nil
+}
+
+// CompileErrorRulePattern compiles a rule message pattern as a case-insensitive regular expression.
+func CompileErrorRulePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// Match 返回第一条匹配该上游错误的规则，分组规则优先于全局规则，未匹配时返回 nil。
+func (m *ErrorRuleManager) Match(group *models.Group, upstreamErr *app_errors.UpstreamError) *models.ErrorRule {
+	if m == nil || m.syncer == nil || upstreamErr == nil {
+		return nil
+	}
+
+	rules := m.syncer.Get()
+	var globalMatch *models.ErrorRule
+	for i := range rules {
+		item := &rules[i]
+		if !item.matches(group, upstreamErr) {
+			continue
+		}
+		if item.rule.GroupID != nil {
+			return &item.rule
+		}
+		if globalMatch == nil {
+			globalMatch = &item.rule
+		}
+	}
+	return globalMatch
+}
+
+// matches reports whether the rule applies to the group and upstream error.
+func (r *compiledErrorRule) matches(group *models.Group, upstreamErr *app_errors.UpstreamError) bool {
+	if r.rule.GroupID != nil && *r.rule.GroupID != group.ID {
+		return false
+	}
+	if r.rule.ChannelType != "" && r.rule.ChannelType != group.ChannelType {
+		return false
+	}
+	if r.rule.StatusCode != 0 && r.rule.StatusCode != upstreamErr.StatusCode {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(upstreamErr.Message) {
+		return false
+	}
+	return true
+}
+
+// Invalidate triggers a cache reload across all instances.
+func (m *ErrorRuleManager) Invalidate() error {
+	if m.syncer == nil {
+		return fmt.Errorf("ErrorRuleManager is not initialized")
+	}
+	return m.syncer.Invalidate()
+}
+
+// Stop gracefully stops the ErrorRuleManager's background syncer.
+func (m *ErrorRuleManager) Stop(ctx context.Context) {
+	if m.syncer != nil {
+		m.syncer.Stop()
+	}
+}
@@ -0,0 +1,96 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FailurePolicyWindow 表示按滑动时间窗口内的失败次数或失败率判定是否拉黑
+const FailurePolicyWindow = "window"
+
+// failureWindowBuckets 是每个滑动窗口划分的桶数量，桶越多统计越精确
+const failureWindowBuckets = 10
+
+// failureWindowKey returns the store hash holding the bucketed counters of a key.
+func failureWindowKey(keyID uint) string {
+	return fmt.Sprintf("key:%d:failure_window", keyID)
+}
+
+// recordWindowEvent 在滑动窗口中记录一次请求结果，并返回窗口内的失败次数和总请求数。
+// 计数按时间分桶保存在 store 的 HASH 中，字段形如 "f:<桶起始时间>"、"s:<桶起始时间>"，过期的桶在记录时清理，HASH 本身在窗口结束后过期。
+func (p *KeyProvider) recordWindowEvent(keyID uint, cfg types.SystemSettings, isSuccess bool) (failures int64, total int64, err error) {
+	window := int64(cfg.FailureWindowSeconds)
+	bucketSize := max(window/failureWindowBuckets, 1)
+	now := time.Now().Unix()
+	bucket := now / bucketSize * bucketSize
+
+	prefix := "f:"
+	if isSuccess {
+		prefix = "s:"
+	}
+	hashKey := failureWindowKey(keyID)
+	if _, err := p.store.HIncrBy(hashKey, prefix+strconv.FormatInt(bucket, 10), 1); err != nil {
+		return 0, 0, fmt.Errorf("failed to record failure window event: %w", err)
+	}
+	// 窗口内没有新事件时整个 HASH 自动过期，避免不再使用或已删除的 Key 残留计数
+	if err := p.store.Expire(hashKey, time.Duration(window+bucketSize)*time.Second); err != nil {
+		return 0, 0, fmt.Errorf("failed to set failure window expiry: %w", err)
+	}
+
+	counters, err := p.store.HGetAll(hashKey)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read failure window: %w", err)
+	}
+
+	var stale []string
+	for field, value := range counters {
+		kind, startStr, ok := strings.Cut(field, ":")
+		start, parseErr := strconv.ParseInt(startStr, 10, 64)
+		if !ok || parseErr != nil || start+bucketSize <= now-window {
+			stale = append(stale, field)
+			continue
+		}
+		count, _ := strconv.ParseInt(value, 10, 64)
+		total += count
+		if kind == "f" {
+			failures += count
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := p.store.HDel(hashKey, stale...); err != nil {
+			return failures, total, fmt.Errorf("failed to prune failure window: %w", err)
+		}
+	}
+	return failures, total, nil
+}
+
+// windowExceeded 判断窗口内的失败次数或失败率是否超过上限
+func windowExceeded(cfg types.SystemSettings, failures, total int64) bool {
+	if cfg.FailureWindowMaxFailures > 0 && failures >= int64(cfg.FailureWindowMaxFailures) {
+		return true
+	}
+	if cfg.FailureWindowMaxRate > 0 && total >= int64(cfg.FailureWindowMinRequests) && failures*100 > int64(cfg.FailureWindowMaxRate)*total {
+		return true
+	}
+	return false
+}
+
+// RecordSuccess 在 window 策略下记录一次成功请求，用于计算失败率；其他策略下不做任何操作。
+func (p *KeyProvider) RecordSuccess(apiKey *models.APIKey, group *models.Group) {
+	if group.EffectiveConfig.FailurePolicy != FailurePolicyWindow {
+		return
+	}
+	go func() {
+		if _, _, err := p.recordWindowEvent(apiKey.ID, group.EffectiveConfig, true); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Warn("Failed to record key success")
+		}
+	}()
+}
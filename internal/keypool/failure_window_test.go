@@ -0,0 +1,90 @@
+package keypool
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+)
+
+func TestWindowExceeded(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxFailures     int
+		maxRate         int
+		minRequests     int
+		failures, total int64
+		want            bool
+	}{
+		{name: "below both limits", maxFailures: 10, maxRate: 50, minRequests: 10, failures: 3, total: 20, want: false},
+		{name: "failure count reached", maxFailures: 10, maxRate: 50, minRequests: 10, failures: 10, total: 100, want: true},
+		{name: "failure rate exceeded", maxFailures: 100, maxRate: 50, minRequests: 10, failures: 6, total: 10, want: true},
+		{name: "failure rate equal to limit", maxFailures: 100, maxRate: 50, minRequests: 10, failures: 5, total: 10, want: false},
+		{name: "rate ignored below min requests", maxFailures: 100, maxRate: 50, minRequests: 10, failures: 5, total: 5, want: false},
+		{name: "count limit disabled", maxFailures: 0, maxRate: 50, minRequests: 10, failures: 9, total: 100, want: false},
+		{name: "rate limit disabled", maxFailures: 10, maxRate: 0, minRequests: 1, failures: 9, total: 9, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := types.SystemSettings{
+				FailureWindowMaxFailures: tt.maxFailures,
+				FailureWindowMaxRate:     tt.maxRate,
+				FailureWindowMinRequests: tt.minRequests,
+			}
+			if got := windowExceeded(cfg, tt.failures, tt.total); got != tt.want {
+				t.Errorf("windowExceeded(%d/%d) = %t, want %t", tt.failures, tt.total, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordWindowEvent(t *testing.T) {
+	memoryStore := store.NewMemoryStore()
+	p := &KeyProvider{store: memoryStore}
+	cfg := types.SystemSettings{FailureWindowSeconds: 300}
+
+	// 预置一个早已过期的桶和一个无法解析的字段，记录时应被清理且不计入统计
+	staleField := "f:" + strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	if err := memoryStore.HSet(failureWindowKey(1), map[string]any{staleField: 50, "garbage": 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []struct {
+		success      bool
+		wantFailures int64
+		wantTotal    int64
+	}{
+		{success: false, wantFailures: 1, wantTotal: 1},
+		{success: true, wantFailures: 1, wantTotal: 2},
+		{success: false, wantFailures: 2, wantTotal: 3},
+	}
+	for i, event := range events {
+		failures, total, err := p.recordWindowEvent(1, cfg, event.success)
+		if err != nil {
+			t.Fatalf("event %d: recordWindowEvent() error = %v", i, err)
+		}
+		if failures != event.wantFailures || total != event.wantTotal {
+			t.Errorf("event %d: recordWindowEvent() = %d/%d, want %d/%d", i, failures, total, event.wantFailures, event.wantTotal)
+		}
+	}
+
+	counters, err := memoryStore.HGetAll(failureWindowKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := counters["garbage"]; ok {
+		t.Error("unparseable field was not pruned")
+	}
+	if _, ok := counters[staleField]; ok {
+		t.Error("stale bucket was not pruned")
+	}
+
+	// 其他 Key 的计数互不影响
+	failures, total, err := p.recordWindowEvent(2, cfg, true)
+	if err != nil || failures != 0 || total != 1 {
+		t.Errorf("recordWindowEvent() for another key = %d/%d, %v, want 0/1", failures, total, err)
+	}
+}
@@ -0,0 +1,102 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// probeBudgetKey returns the store hash counting active key probes per hour for a group.
+func probeBudgetKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:probe_budget", groupID)
+}
+
+// probeAllowance 返回本轮可发起的探测数量。
+// 预算按分钟均匀分布在一小时内，已用数量保存在 store 中以小时为单位的计数里。
+func (s *CronChecker) probeAllowance(group *models.Group, now time.Time) (int, error) {
+	budget := group.EffectiveConfig.ActiveKeyProbesPerHour
+	hour := strconv.FormatInt(now.Truncate(time.Hour).Unix(), 10)
+
+	counters, err := s.Validator.keypoolProvider.store.HGetAll(probeBudgetKey(group.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	var stale []string
+	for field := range counters {
+		if field != hour {
+			stale = append(stale, field)
+		}
+	}
+	if len(stale) > 0 {
+		if err := s.Validator.keypoolProvider.store.HDel(probeBudgetKey(group.ID), stale...); err != nil {
+			return 0, err
+		}
+	}
+
+	used, _ := strconv.Atoi(counters[hour])
+	// 截至当前分钟应已消耗的预算（向上取整）
+	elapsedMinutes := now.Minute() + 1
+	target := (budget*elapsedMinutes + 59) / 60
+	return max(target-used, 0), nil
+}
+
+// probeActiveKeys 对分组内最久未探测的有效 Key 发起健康探测，失效的 Key 会按验证结果进入黑名单或退役。
+func (s *CronChecker) probeActiveKeys(group *models.Group) {
+	if group.EffectiveConfig.ActiveKeyProbesPerHour <= 0 {
+		return
+	}
+
+	now := time.Now()
+	allowance, err := s.probeAllowance(group, now)
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to read probe budget for group %s: %v", group.Name, err)
+		return
+	}
+	if allowance == 0 {
+		return
+	}
+
+	var keys []models.APIKey
+	err = s.DB.Where("group_id = ? AND status = ? AND is_disabled = ?", group.ID, models.KeyStatusActive, false).
+		Order("last_probed_at IS NULL DESC, last_probed_at ASC").
+		Limit(allowance).
+		Find(&keys).Error
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to get active keys to probe for group %s: %v", group.Name, err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	if _, err := s.Validator.keypoolProvider.store.HIncrBy(probeBudgetKey(group.ID), strconv.FormatInt(now.Truncate(time.Hour).Unix(), 10), int64(len(keys))); err != nil {
+		logrus.Errorf("CronChecker: Failed to update probe budget for group %s: %v", group.Name, err)
+		return
+	}
+	if err := s.DB.Model(&models.APIKey{}).Where("id IN ?", pluckIDs(keys)).Update("last_probed_at", now).Error; err != nil {
+		logrus.Errorf("CronChecker: Failed to update last_probed_at for group %s: %v", group.Name, err)
+	}
+
+	var failedCount int
+	for i := range keys {
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+		if isValid, _ := s.Validator.ValidateSingleKey(&keys[i], group); !isValid {
+			failedCount++
+		}
+	}
+
+	if failedCount > 0 {
+		logrus.Warnf("CronChecker: Health probe found %d of %d active keys failing in group '%s'.", failedCount, len(keys), group.Name)
+	} else {
+		logrus.Debugf("CronChecker: Health probe checked %d active keys in group '%s'.", len(keys), group.Name)
+	}
+}
@@ -0,0 +1,32 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+)
+
+// sealKey 计算明文密钥值的哈希并加密密钥值，用于写入数据库前
+func (p *KeyProvider) sealKey(key *models.APIKey) error {
+	if encryption.IsEncrypted(key.KeyValue) {
+		return nil
+	}
+	key.KeyHash = encryption.HashKey(key.KeyValue)
+	encrypted, err := p.encryption.Encrypt(key.KeyValue)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key value: %w", err)
+	}
+	key.KeyValue = encrypted
+	return nil
+}
+
+// DecryptKey 返回密钥值解密后的副本，仅在向上游发起请求时使用，解密结果不写回数据库或 store
+func (p *KeyProvider) DecryptKey(key *models.APIKey) (*models.APIKey, error) {
+	keyValue, err := p.encryption.Decrypt(key.KeyValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key %d: %w", key.ID, err)
+	}
+	decrypted := *key
+	decrypted.KeyValue = keyValue
+	return &decrypted, nil
+}
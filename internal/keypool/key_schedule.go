@@ -0,0 +1,110 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hasSchedule 判断 Key 是否设置了生效时间、过期时间或可用时段
+func hasSchedule(key *models.APIKey) bool {
+	return key.ValidFrom != nil || key.ExpiresAt != nil || key.ActiveWindows != ""
+}
+
+// keyInSchedule 判断 now 时 Key 是否处于生效期内且落在每天的可用时段中，时段按 loc 时区计算
+func keyInSchedule(key *models.APIKey, now time.Time, loc *time.Location) bool {
+	if key.ValidFrom != nil && now.Before(*key.ValidFrom) {
+		return false
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return false
+	}
+	windows, err := utils.ParseTimeWindows(key.ActiveWindows)
+	if err != nil {
+		// 时段在写入时已校验，解析失败时不限制可用时间
+		return true
+	}
+	return utils.InTimeWindows(windows, now.In(loc))
+}
+
+// offSchedule 按全局时区判断 Key 当前是否不可用，分组覆盖的时区由定时同步任务修正
+func (p *KeyProvider) offSchedule(key *models.APIKey) bool {
+	if !hasSchedule(key) {
+		return false
+	}
+	return !keyInSchedule(key, time.Now(), quotaLocation(p.settingsManager.GetSettings()))
+}
+
+// isOffSchedule 读取 store 中 Key 详情的时段标记
+func isOffSchedule(keyDetails map[string]string) bool {
+	off, _ := strconv.ParseBool(keyDetails["off_schedule"])
+	return off
+}
+
+// SyncKeySchedules 根据生效时间、过期时间和可用时段，将分组内的 Key 移出或放回轮询列表
+func (p *KeyProvider) SyncKeySchedules(group *models.Group) {
+	var keys []models.APIKey
+	err := p.db.Where("group_id = ? AND (valid_from IS NOT NULL OR expires_at IS NOT NULL OR active_windows <> '')", group.ID).
+		Find(&keys).Error
+	if err != nil {
+		logrus.Errorf("Failed to get scheduled keys for group %s: %v", group.Name, err)
+		return
+	}
+
+	now := time.Now()
+	loc := quotaLocation(group.EffectiveConfig)
+	for i := range keys {
+		key := &keys[i]
+		if err := p.applyKeySchedule(key, !keyInSchedule(key, now, loc)); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": key.ID, "error": err}).Error("Failed to apply key schedule")
+		}
+	}
+}
+
+// UpdateKeySchedule 在 Key 的时间设置变更后立即同步其轮询状态
+func (p *KeyProvider) UpdateKeySchedule(key *models.APIKey, group *models.Group) error {
+	off := hasSchedule(key) && !keyInSchedule(key, time.Now(), quotaLocation(group.EffectiveConfig))
+	return p.applyKeySchedule(key, off)
+}
+
+// applyKeySchedule 仅在时段状态变化时更新 store 标记和轮询列表
+func (p *KeyProvider) applyKeySchedule(key *models.APIKey, off bool) error {
+	keyHashKey := fmt.Sprintf("key:%d", key.ID)
+	keyDetails, err := p.store.HGetAll(keyHashKey)
+	if err != nil {
+		return fmt.Errorf("failed to get key details from store: %w", err)
+	}
+	if len(keyDetails) == 0 || isOffSchedule(keyDetails) == off {
+		return nil
+	}
+
+	if err := p.store.HSet(keyHashKey, map[string]any{"off_schedule": off}); err != nil {
+		return fmt.Errorf("failed to update key schedule flag in store: %w", err)
+	}
+
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
+	if off {
+		if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
+			return fmt.Errorf("failed to LRem key from active list: %w", err)
+		}
+		logrus.WithField("keyID", key.ID).Info("Key is outside its schedule and was removed from rotation.")
+		return nil
+	}
+
+	if key.Status != models.KeyStatusActive || key.IsDisabled || key.CooldownUntil != nil {
+		return nil
+	}
+	if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
+		return fmt.Errorf("failed to LRem key before LPush: %w", err)
+	}
+	if err := p.store.LPush(activeKeysListKey, key.ID); err != nil {
+		return fmt.Errorf("failed to LPush key to active list: %w", err)
+	}
+	logrus.WithField("keyID", key.ID).Info("Key entered its schedule and was added to rotation.")
+	return nil
+}
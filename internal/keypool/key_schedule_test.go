@@ -0,0 +1,46 @@
+package keypool
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+)
+
+func TestKeyInSchedule(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+	shanghai := time.FixedZone("UTC+8", 8*60*60)
+
+	tests := []struct {
+		name string
+		key  models.APIKey
+		loc  *time.Location
+		want bool
+	}{
+		{name: "no schedule", key: models.APIKey{}, want: true},
+		{name: "not yet valid", key: models.APIKey{ValidFrom: &after}, want: false},
+		{name: "valid from the past", key: models.APIKey{ValidFrom: &before}, want: true},
+		{name: "expired", key: models.APIKey{ExpiresAt: &before}, want: false},
+		{name: "expires exactly now", key: models.APIKey{ExpiresAt: &now}, want: false},
+		{name: "inside validity period", key: models.APIKey{ValidFrom: &before, ExpiresAt: &after}, want: true},
+		{name: "inside daily window", key: models.APIKey{ActiveWindows: "09:00-17:00"}, want: true},
+		{name: "outside daily window", key: models.APIKey{ActiveWindows: "22:00-06:00"}, want: false},
+		{name: "window uses the given time zone", key: models.APIKey{ActiveWindows: "18:00-22:00"}, loc: shanghai, want: true},
+		{name: "window outside validity period", key: models.APIKey{ActiveWindows: "09:00-17:00", ExpiresAt: &before}, want: false},
+		{name: "invalid windows do not restrict", key: models.APIKey{ActiveWindows: "bogus"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			if got := keyInSchedule(&tt.key, now, loc); got != tt.want {
+				t.Errorf("keyInSchedule() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// MoveKeys 将源分组中的 Key 移动到目标分组，保留 ID、状态、计数、备注和使用历史。
+// 目标分组中已存在相同密钥值的 Key 会被跳过，返回移动数量和跳过数量。
+// 数据库提交后再更新 store，store 更新失败时从数据库重建两个分组的 Key 缓存。
+func (p *KeyProvider) MoveKeys(sourceGroupID, targetGroupID uint, keyIDs []uint) (int64, int64, error) {
+	if len(keyIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	var movedCount, skippedCount int64
+	var keys []models.APIKey
+
+	// 事务只负责数据库，重试时不会重复修改 store
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		movedCount, skippedCount = 0, 0
+
+		var skipped int64
+		var err error
+		keys, skipped, err = p.findTransferableKeys(tx, sourceGroupID, targetGroupID, keyIDs)
+		if err != nil {
+			return err
+		}
+		skippedCount = skipped
+		if len(keys) == 0 {
+			return nil
+		}
+
+		result := tx.Model(&models.APIKey{}).Where("id IN ?", pluckIDs(keys)).Update("group_id", targetGroupID)
+		if result.Error != nil {
+			return result.Error
+		}
+		movedCount = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range keys {
+		keys[i].GroupID = targetGroupID
+	}
+	if err := p.storeTransferredKeys(keys, sourceGroupID); err != nil {
+		logrus.WithFields(logrus.Fields{"keys": len(keys), "error": err}).Error("Failed to move keys in store, reloading active keys of both groups")
+		return movedCount, skippedCount, p.reloadGroupKeys(sourceGroupID, targetGroupID)
+	}
+
+	return movedCount, skippedCount, nil
+}
+
+// CopyKeys 将源分组中的 Key 复制到目标分组，新 Key 沿用原 Key 的状态、计数、备注、标签和限额设置。
+// 目标分组中已存在相同密钥值的 Key 会被跳过，返回复制数量和跳过数量。
+func (p *KeyProvider) CopyKeys(sourceGroupID, targetGroupID uint, keyIDs []uint) (int64, int64, error) {
+	if len(keyIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	var copiedCount, skippedCount int64
+	var keys []models.APIKey
+
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		copiedCount, skippedCount = 0, 0
+
+		var skipped int64
+		var err error
+		keys, skipped, err = p.findTransferableKeys(tx, sourceGroupID, targetGroupID, keyIDs)
+		if err != nil {
+			return err
+		}
+		skippedCount = skipped
+		if len(keys) == 0 {
+			return nil
+		}
+
+		for i := range keys {
+			keys[i].ID = 0
+			keys[i].GroupID = targetGroupID
+			keys[i].CreatedAt = time.Time{}
+			keys[i].UpdatedAt = time.Time{}
+		}
+		if err := tx.Create(&keys).Error; err != nil {
+			return err
+		}
+		copiedCount = int64(len(keys))
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := p.storeTransferredKeys(keys, 0); err != nil {
+		logrus.WithFields(logrus.Fields{"keys": len(keys), "error": err}).Error("Failed to add copied keys to store, reloading active keys of target group")
+		return copiedCount, skippedCount, p.reloadGroupKeys(targetGroupID)
+	}
+
+	return copiedCount, skippedCount, nil
+}
+
+// findTransferableKeys 查找源分组中待转移的 Key，并排除目标分组中已存在相同密钥值的 Key
+func (p *KeyProvider) findTransferableKeys(tx *gorm.DB, sourceGroupID, targetGroupID uint, keyIDs []uint) ([]models.APIKey, int64, error) {
+	var keys []models.APIKey
+	if err := tx.Where("group_id = ? AND id IN ?", sourceGroupID, keyIDs).Find(&keys).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(keys) == 0 {
+		return nil, int64(len(keyIDs)), nil
+	}
+
+	keyHashes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyHashes = append(keyHashes, key.KeyHash)
+	}
+
+	var existingHashes []string
+	if err := tx.Model(&models.APIKey{}).Where("group_id = ? AND key_hash IN ?", targetGroupID, keyHashes).Pluck("key_hash", &existingHashes).Error; err != nil {
+		return nil, 0, err
+	}
+	existing := make(map[string]bool, len(existingHashes))
+	for _, hash := range existingHashes {
+		existing[hash] = true
+	}
+
+	transferable := keys[:0]
+	for _, key := range keys {
+		if !existing[key.KeyHash] {
+			transferable = append(transferable, key)
+		}
+	}
+
+	return transferable, int64(len(keyIDs) - len(transferable)), nil
+}
+
+// storeTransferredKeys 更新转移后 Key 在存储中的详情，并将其从源分组的活跃列表移到目标分组。
+// 同一批 Key 的所有命令在一个事务管道中原子执行，不会出现 Key 同时在两个分组或都不在的中间状态。
+// 冷却中、已停用或不在可用时段的 Key 不会进入目标分组的活跃列表。
+func (p *KeyProvider) storeTransferredKeys(keys []models.APIKey, sourceGroupID uint) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := p.store.TxPipeline()
+	for i := range keys {
+		key := &keys[i]
+		if sourceGroupID != 0 {
+			pipe.LRem(fmt.Sprintf("group:%d:active_keys", sourceGroupID), 0, key.ID)
+		}
+		pipe.HSet(fmt.Sprintf("key:%d", key.ID), p.apiKeyToMap(key))
+		if p.inRotation(key) {
+			targetListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
+			pipe.LRem(targetListKey, 0, key.ID)
+			pipe.LPush(targetListKey, key.ID)
+		}
+	}
+	if err := pipe.Exec(); err != nil {
+		return fmt.Errorf("failed to update transferred keys in store: %w", err)
+	}
+	return nil
+}
+
+// reloadGroupKeys 从数据库重新加载分组中所有 Key 的详情并重建活跃列表，用于数据库已提交但 store 更新失败后的修复
+func (p *KeyProvider) reloadGroupKeys(groupIDs ...uint) error {
+	for _, groupID := range groupIDs {
+		var keys []models.APIKey
+		if err := p.db.Where("group_id = ?", groupID).Find(&keys).Error; err != nil {
+			return fmt.Errorf("failed to load keys of group %d: %w", groupID, err)
+		}
+
+		activeIDs := make([]any, 0, len(keys))
+		for i := range keys {
+			if err := p.store.HSet(fmt.Sprintf("key:%d", keys[i].ID), p.apiKeyToMap(&keys[i])); err != nil {
+				return fmt.Errorf("failed to HSet key details for key %d: %w", keys[i].ID, err)
+			}
+			if p.inRotation(&keys[i]) {
+				activeIDs = append(activeIDs, keys[i].ID)
+			}
+		}
+
+		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+		if err := p.store.Delete(activeKeysListKey); err != nil {
+			return fmt.Errorf("failed to reset active keys of group %d: %w", groupID, err)
+		}
+		if len(activeIDs) > 0 {
+			if err := p.store.LPush(activeKeysListKey, activeIDs...); err != nil {
+				return fmt.Errorf("failed to LPush active keys of group %d: %w", groupID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"gpt-load/internal/models"
+)
+
+func TestMoveKeys(t *testing.T) {
+	p, db, memoryStore := newTestProvider(t)
+	active := addTestKey(t, p, 1, "sk-move-active")
+	duplicate := addTestKey(t, p, 1, "sk-move-duplicate")
+	addTestKey(t, p, 2, "sk-move-duplicate")
+	disabled := []models.APIKey{{GroupID: 1, KeyValue: "sk-move-disabled", Status: models.KeyStatusActive, IsDisabled: true}}
+	if err := p.AddKeys(1, disabled); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, skipped, err := p.MoveKeys(1, 2, []uint{active.ID, duplicate.ID, disabled[0].ID, 999})
+	if err != nil {
+		t.Fatalf("MoveKeys() error = %v", err)
+	}
+	if moved != 2 || skipped != 2 {
+		t.Errorf("MoveKeys() = %d moved, %d skipped, want 2 and 2", moved, skipped)
+	}
+
+	tests := []struct {
+		name       string
+		keyID      uint
+		wantGroup  uint
+		wantActive bool
+	}{
+		{name: "active key", keyID: active.ID, wantGroup: 2, wantActive: true},
+		{name: "disabled key", keyID: disabled[0].ID, wantGroup: 2, wantActive: false},
+		{name: "duplicate in target", keyID: duplicate.ID, wantGroup: 1, wantActive: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := loadTestKey(t, db, tt.keyID)
+			if key.GroupID != tt.wantGroup {
+				t.Fatalf("group = %d, want %d", key.GroupID, tt.wantGroup)
+			}
+			details, err := memoryStore.HGetAll(fmt.Sprintf("key:%d", key.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if details["group_id"] != strconv.FormatUint(uint64(tt.wantGroup), 10) {
+				t.Errorf("cached group_id = %q, want %d", details["group_id"], tt.wantGroup)
+			}
+			if got := inActiveList(t, memoryStore, key); got != tt.wantActive {
+				t.Errorf("in target rotation = %t, want %t", got, tt.wantActive)
+			}
+			other := *key
+			other.GroupID = 3 - tt.wantGroup
+			if inActiveList(t, memoryStore, &other) {
+				t.Errorf("key is still in the rotation of group %d", other.GroupID)
+			}
+		})
+	}
+}
+
+func TestCopyKeys(t *testing.T) {
+	p, db, memoryStore := newTestProvider(t)
+	source := addTestKey(t, p, 1, "sk-copy-source")
+	if err := db.Model(source).Updates(map[string]any{"remarks": "team a", "request_count": 7}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	copied, skipped, err := p.CopyKeys(1, 2, []uint{source.ID})
+	if err != nil {
+		t.Fatalf("CopyKeys() error = %v", err)
+	}
+	if copied != 1 || skipped != 0 {
+		t.Fatalf("CopyKeys() = %d copied, %d skipped, want 1 and 0", copied, skipped)
+	}
+
+	var copies []models.APIKey
+	if err := db.Where("group_id = ?", 2).Find(&copies).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(copies) != 1 {
+		t.Fatalf("target group has %d keys, want 1", len(copies))
+	}
+	clone := &copies[0]
+	if clone.ID == source.ID || clone.KeyHash != source.KeyHash || clone.Remarks != "team a" || clone.RequestCount != 7 {
+		t.Errorf("copy = %+v, want a new key with the source's value, remarks and counts", clone)
+	}
+	if !inActiveList(t, memoryStore, clone) {
+		t.Error("copied key is not in the target rotation")
+	}
+	if !inActiveList(t, memoryStore, loadTestKey(t, db, source.ID)) {
+		t.Error("source key was removed from its rotation")
+	}
+
+	if copied, skipped, err := p.CopyKeys(1, 2, []uint{source.ID}); err != nil || copied != 0 || skipped != 1 {
+		t.Errorf("second CopyKeys() = %d, %d, %v, want the key skipped", copied, skipped, err)
+	}
+}
@@ -0,0 +1,102 @@
+package keypool
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// modelDiscoveryBatchSize 是每个分组每轮最多进行模型发现的 Key 数量
+const modelDiscoveryBatchSize = 20
+
+// discoverKeyModels 查询分组内到期的有效 Key 可用的模型列表，并同步到数据库和 store。
+// 模型列表接口失败不计入 Key 的失败次数，只记录发现时间，等待下一个周期重试。
+func (s *CronChecker) discoverKeyModels(group *models.Group) {
+	hours := group.EffectiveConfig.KeyModelDiscoveryHours
+	if hours <= 0 {
+		return
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(hours) * time.Hour)
+
+	var keys []models.APIKey
+	err := s.DB.Where("group_id = ? AND status = ? AND is_disabled = ?", group.ID, models.KeyStatusActive, false).
+		Where("models_discovered_at IS NULL OR models_discovered_at <= ?", cutoff).
+		Order("models_discovered_at IS NULL DESC, models_discovered_at ASC").
+		Limit(modelDiscoveryBatchSize).
+		Find(&keys).Error
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to get keys for model discovery in group %s: %v", group.Name, err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	ch, err := s.Validator.channelFactory.GetChannel(group)
+	if err != nil {
+		logrus.Errorf("CronChecker: Failed to get channel for group %s: %v", group.Name, err)
+		return
+	}
+
+	var discoveredCount int
+	for i := range keys {
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+
+		key, err := s.Validator.keypoolProvider.DecryptKey(&keys[i])
+		if err != nil {
+			logrus.Errorf("CronChecker: Skipping model discovery: %v", err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(group.EffectiveConfig.KeyValidationTimeoutSeconds)*time.Second)
+		modelNames, err := ch.ListModels(ctx, key, group)
+		cancel()
+
+		updates := map[string]any{"models_discovered_at": now}
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error":    err,
+				"key_id":   key.ID,
+				"group_id": group.ID,
+			}).Debug("Key model discovery failed")
+		} else {
+			updates["supported_models"] = joinModelNames(modelNames)
+		}
+
+		if err := s.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+			logrus.Errorf("CronChecker: Failed to save discovered models for key %d: %v", key.ID, err)
+			continue
+		}
+		if supported, ok := updates["supported_models"]; ok {
+			if err := s.Validator.keypoolProvider.updateCachedKeyFields(key.ID, map[string]any{"supported_models": supported}); err != nil {
+				logrus.Errorf("CronChecker: Failed to cache discovered models for key %d: %v", key.ID, err)
+				continue
+			}
+			discoveredCount++
+		}
+	}
+
+	logrus.Debugf("CronChecker: Discovered models for %d of %d keys in group '%s'.", discoveredCount, len(keys), group.Name)
+}
+
+// joinModelNames 去重排序后以逗号拼接模型名称
+func joinModelNames(modelNames []string) string {
+	cleaned := make([]string, 0, len(modelNames))
+	for _, name := range modelNames {
+		if name = strings.TrimSpace(name); name != "" {
+			cleaned = append(cleaned, name)
+		}
+	}
+	slices.Sort(cleaned)
+	return strings.Join(slices.Compact(cleaned), ",")
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -20,78 +22,289 @@ type KeyProvider struct {
 	db              *gorm.DB
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
+	errorRules      *ErrorRuleManager
+	encryption      encryption.Service
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
-func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager) *KeyProvider {
+func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, errorRules *ErrorRuleManager, encryptionService encryption.Service) *KeyProvider {
 	return &KeyProvider{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
+		errorRules:      errorRules,
+		encryption:      encryptionService,
 	}
 }
 
-// SelectKey 为指定的分组原子性地选择并轮换一个可用的 APIKey。
-func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
+// KeyCriteria 描述请求对 Key 的要求，零值表示不做限制
+type KeyCriteria struct {
+	Model string   // 只选择支持该模型的 Key，尚未进行模型发现的 Key 视为支持所有模型
+	Tags  []string // 只选择包含全部标签的 Key
+}
+
+// matches 判断 store 中的 Key 详情是否满足要求
+func (c KeyCriteria) matches(keyDetails map[string]string) bool {
+	return c.accepts(keyDetails["supported_models"], keyDetails["tags"])
+}
+
+// Accepts 判断已选出的 Key 是否满足要求，用于把 Key 转交给条件不同的其他请求
+func (c KeyCriteria) Accepts(apiKey *models.APIKey) bool {
+	return c.accepts(apiKey.SupportedModels, apiKey.Tags)
+}
+
+func (c KeyCriteria) accepts(supportedModels, tags string) bool {
+	return (c.Model == "" || supportsModel(supportedModels, c.Model)) && utils.HasAllTags(tags, c.Tags)
+}
+
+// keySelectionScanLimit 是单次选择最多检查的 Key 数量，避免大分组中按条件选择时逐个读取整个活跃列表
+const keySelectionScanLimit = 64
+
+// SelectKey 为指定的分组原子性地选择并轮换一个满足 criteria 的可用 APIKey。
+// 余额不足的 Key 仅在没有其他满足要求的 Key 时使用。每次最多检查 keySelectionScanLimit 个 Key，只解密最终选中的 Key。
+func (p *KeyProvider) SelectKey(groupID uint, criteria KeyCriteria) (*models.APIKey, error) {
 	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
 
-	// 1. Atomically rotate the key ID from the list
-	keyIDStr, err := p.store.Rotate(activeKeysListKey)
-	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return nil, app_errors.ErrNoActiveKeys
+	// 轮换直到找到满足要求的 Key、同一个 Key 再次出现或达到检查上限
+	seen := make(map[string]struct{})
+	var lowBalanceKeyID string
+	var lowBalanceDetails map[string]string
+	for len(seen) < keySelectionScanLimit {
+		// 1. Atomically rotate the key ID from the list
+		keyIDStr, err := p.store.Rotate(activeKeysListKey)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, app_errors.ErrNoActiveKeys
+			}
+			return nil, fmt.Errorf("failed to rotate key from store: %w", err)
+		}
+		if _, ok := seen[keyIDStr]; ok {
+			break
+		}
+		seen[keyIDStr] = struct{}{}
+
+		keyDetails, err := p.getKeyDetails(keyIDStr)
+		if err != nil {
+			return nil, err
+		}
+		if !isOffSchedule(keyDetails) && criteria.matches(keyDetails) {
+			if !isLowBalanceKey(keyDetails) {
+				return p.buildAPIKey(groupID, keyIDStr, keyDetails)
+			}
+			if lowBalanceDetails == nil {
+				lowBalanceKeyID, lowBalanceDetails = keyIDStr, keyDetails
+			}
 		}
-		return nil, fmt.Errorf("failed to rotate key from store: %w", err)
 	}
 
-	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+	// 并发轮换可能导致跳过部分 Key，在剩余的检查额度内从随机位置扫描活跃列表
+	keyIDs, err := p.store.LRange(activeKeysListKey, 0, -1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+		return nil, fmt.Errorf("failed to list active keys from store: %w", err)
+	}
+	if len(keyIDs) > 0 {
+		offset := rand.Intn(len(keyIDs))
+		for i := 0; i < len(keyIDs) && len(seen) < keySelectionScanLimit; i++ {
+			keyIDStr := keyIDs[(offset+i)%len(keyIDs)]
+			if _, ok := seen[keyIDStr]; ok {
+				continue
+			}
+			seen[keyIDStr] = struct{}{}
+			keyDetails, err := p.getKeyDetails(keyIDStr)
+			if err != nil {
+				return nil, err
+			}
+			if !isOffSchedule(keyDetails) && criteria.matches(keyDetails) {
+				if !isLowBalanceKey(keyDetails) {
+					return p.buildAPIKey(groupID, keyIDStr, keyDetails)
+				}
+				if lowBalanceDetails == nil {
+					lowBalanceKeyID, lowBalanceDetails = keyIDStr, keyDetails
+				}
+			}
+		}
+	}
+	if lowBalanceDetails != nil {
+		return p.buildAPIKey(groupID, lowBalanceKeyID, lowBalanceDetails)
+	}
+	if len(keyIDs) == 0 {
+		return nil, app_errors.ErrNoActiveKeys
 	}
+	return nil, app_errors.ErrNoMatchingKeys
+}
 
+// getKeyDetails 从 store 中读取 Key 的原始字段，用于匹配选择条件，不解密密钥
+func (p *KeyProvider) getKeyDetails(keyIDStr string) (map[string]string, error) {
 	// 2. Get key details from HASH
-	keyHashKey := fmt.Sprintf("key:%d", keyID)
-	keyDetails, err := p.store.HGetAll(keyHashKey)
+	keyDetails, err := p.store.HGetAll("key:" + keyIDStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		return nil, fmt.Errorf("failed to get key details for key ID %s: %w", keyIDStr, err)
+	}
+	return keyDetails, nil
+}
+
+// buildAPIKey 把选中 Key 的原始字段转换为 APIKey，并在内存中解密密钥
+func (p *KeyProvider) buildAPIKey(groupID uint, keyIDStr string, keyDetails map[string]string) (*models.APIKey, error) {
+	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
 	}
 
 	// 3. Manually unmarshal the map into an APIKey struct
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	createdAt, _ := strconv.ParseInt(keyDetails["created_at"], 10, 64)
+	requestQuota, _ := strconv.ParseInt(keyDetails["request_quota"], 10, 64)
+	tokenQuota, _ := strconv.ParseInt(keyDetails["token_quota"], 10, 64)
+
+	// store 中保存的是加密后的值，只在内存中解密
+	keyValue, err := p.encryption.Decrypt(keyDetails["key_string"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key ID %d: %w", keyID, err)
+	}
+
+	return &models.APIKey{
+		ID:              uint(keyID),
+		KeyValue:        keyValue,
+		KeyHash:         encryption.HashKey(keyValue),
+		Status:          keyDetails["status"],
+		FailureCount:    failureCount,
+		GroupID:         groupID,
+		Tags:            keyDetails["tags"],
+		SupportedModels: keyDetails["supported_models"],
+		QuotaPeriod:     keyDetails["quota_period"],
+		RequestQuota:    requestQuota,
+		TokenQuota:      tokenQuota,
+		CreatedAt:       time.Unix(createdAt, 0),
+	}, nil
+}
+
+// supportsModel 判断逗号分隔的模型列表是否包含指定模型，列表为空表示尚未发现，视为支持
+func supportsModel(supportedModels, model string) bool {
+	if supportedModels == "" {
+		return true
+	}
+	for _, m := range strings.Split(supportedModels, ",") {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// 密钥失败后的处理方式
+const (
+	failureActionCount     = iota // 计入失败次数，达到阈值后拉黑
+	failureActionSkip             // 不计入失败次数
+	failureActionBlacklist        // 立即拉黑
+	failureActionDisable          // 永久停用
+	failureActionCooldown         // 冷却一段时间
+	failureActionRetire           // 永久退役
+	failureActionExhaust          // 配额用尽，到达重置边界后恢复
+)
 
-	apiKey := &models.APIKey{
-		ID:           uint(keyID),
-		KeyValue:     keyDetails["key_string"],
-		Status:       keyDetails["status"],
-		FailureCount: failureCount,
-		GroupID:      groupID,
-		CreatedAt:    time.Unix(createdAt, 0),
+// failureActionFor 根据上游错误分类决定密钥的处理方式。
+// 上游明确返回配额耗尽时等待下一个重置边界，未设置配额周期的 Key 按天重置。
+// 仅由 401/402/403 状态码推断的分类可能是上游的临时错误，按普通失败计数而不是立即拉黑。
+func failureActionFor(upstreamErr *app_errors.UpstreamError) int {
+	if upstreamErr.Terminal {
+		return failureActionRetire
+	}
+	switch upstreamErr.Category {
+	case app_errors.ErrorCategoryRateLimited,
+		app_errors.ErrorCategoryContextTooLong,
+		app_errors.ErrorCategoryContentFiltered,
+		app_errors.ErrorCategoryBadRequest:
+		return failureActionSkip
+	case app_errors.ErrorCategoryAuthInvalid,
+		app_errors.ErrorCategoryAccountSuspended:
+		if upstreamErr.StatusOnly {
+			return failureActionCount
+		}
+		return failureActionBlacklist
+	case app_errors.ErrorCategoryQuotaExhausted:
+		if upstreamErr.StatusOnly {
+			return failureActionCount
+		}
+		return failureActionExhaust
+	default:
+		return failureActionCount
 	}
+}
 
-	return apiKey, nil
+// failureActionForRule 将错误规则的动作映射为密钥的处理方式
+func failureActionForRule(rule *models.ErrorRule) int {
+	switch rule.Action {
+	case models.ErrorRuleActionIgnore, models.ErrorRuleActionNoRetry:
+		return failureActionSkip
+	case models.ErrorRuleActionDisable:
+		return failureActionDisable
+	case models.ErrorRuleActionCooldown:
+		return failureActionCooldown
+	case models.ErrorRuleActionRetire:
+		return failureActionRetire
+	default:
+		return failureActionCount
+	}
 }
 
 // UpdateStatus 异步地提交一个 Key 状态更新任务。
-func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, errorMessage string) {
+// 失败时优先按匹配的错误规则处理，未匹配规则时根据错误分类决定是否计数或直接拉黑。
+func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, failure error) {
 	go func() {
 		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
 		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
 
 		if isSuccess {
-			if err := p.handleSuccess(apiKey.ID, keyHashKey, activeKeysListKey); err != nil {
+			if err := p.handleSuccess(apiKey, keyHashKey, activeKeysListKey); err != nil {
 				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key success")
 			}
-		} else {
-			if app_errors.IsUnCounted(errorMessage) {
-				logrus.WithFields(logrus.Fields{
-					"keyID": apiKey.ID,
-					"error": errorMessage,
-				}).Debug("Uncounted error, skipping failure handling")
-			} else {
-				if err := p.handleFailure(apiKey, group, keyHashKey, activeKeysListKey); err != nil {
-					logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key failure")
-				}
+			return
+		}
+
+		upstreamErr := app_errors.AsUpstreamError(failure)
+		if upstreamErr == nil {
+			upstreamErr = &app_errors.UpstreamError{Category: app_errors.ErrorCategoryUnknown}
+		}
+
+		action := failureActionFor(upstreamErr)
+		rule := p.errorRules.Match(group, upstreamErr)
+		if rule != nil {
+			action = failureActionForRule(rule)
+		}
+
+		if action == failureActionSkip {
+			logrus.WithFields(logrus.Fields{
+				"keyID":    apiKey.ID,
+				"category": upstreamErr.Category,
+				"error":    upstreamErr.Message,
+			}).Debug("Uncounted error, skipping failure handling")
+			return
+		}
+		// 状态变化在同一事务中同步到其他分组中的相同密钥，普通计数已在 handleFailure 中按 key_hash 更新
+		switch action {
+		case failureActionDisable:
+			if err := p.disableKey(apiKey, group); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to disable key by error rule")
+				return
+			}
+		case failureActionCooldown:
+			if err := p.cooldownKey(apiKey, group, time.Duration(rule.CooldownSeconds)*time.Second); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to cool down key by error rule")
+				return
+			}
+		case failureActionExhaust:
+			if err := p.exhaustKey(apiKey, group); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to mark key as exhausted")
+				return
+			}
+		case failureActionRetire:
+			if err := p.retireKey(apiKey, group, keyHashKey, activeKeysListKey, upstreamErr); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to retire key")
+				return
+			}
+		default:
+			if err := p.handleFailure(apiKey, group, keyHashKey, activeKeysListKey, action == failureActionBlacklist); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key failure")
 			}
 		}
 	}()
@@ -124,7 +337,10 @@ func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) er
 	return err
 }
 
-func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey string) error {
+// handleSuccess 清零失败次数并恢复失效的 Key。
+// 开启共享状态时按 key_hash 清零所有分组中相同密钥的失败次数，Key 恢复时同步恢复其他分组中的相同密钥。
+func (p *KeyProvider) handleSuccess(apiKey *models.APIKey, keyHashKey, activeKeysListKey string) error {
+	keyID := apiKey.ID
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
@@ -133,6 +349,15 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	isActive := keyDetails["status"] == models.KeyStatusActive
 
+	sharedHash := p.sharedKeyHash(apiKey)
+	if sharedHash != "" {
+		shared, err := p.store.HGetAll(sharedKeyStateKey(sharedHash))
+		if err != nil {
+			return fmt.Errorf("failed to get shared key state from store: %w", err)
+		}
+		failureCount, _ = strconv.ParseInt(shared["failure_count"], 10, 64)
+	}
+
 	if failureCount == 0 && isActive {
 		return nil
 	}
@@ -151,12 +376,27 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 		if err := tx.Model(&key).Updates(updates).Error; err != nil {
 			return fmt.Errorf("failed to update key in DB: %w", err)
 		}
+		if sharedHash != "" {
+			if err := tx.Model(&models.APIKey{}).Where("key_hash = ?", sharedHash).Update("failure_count", 0).Error; err != nil {
+				return fmt.Errorf("failed to reset shared failure count in DB: %w", err)
+			}
+			if err := p.store.HSet(sharedKeyStateKey(sharedHash), map[string]any{"failure_count": 0}); err != nil {
+				return fmt.Errorf("failed to reset shared failure count in store: %w", err)
+			}
+		}
+
+		// 恢复后重置重新验证的退避计划
+		if !isActive {
+			if err := tx.Model(&key).Updates(map[string]any{"next_validation_at": nil, "validation_failures": 0}).Error; err != nil {
+				return fmt.Errorf("failed to reset validation schedule in DB: %w", err)
+			}
+		}
 
 		if err := p.store.HSet(keyHashKey, updates); err != nil {
 			return fmt.Errorf("failed to update key details in store: %w", err)
 		}
 
-		if !isActive {
+		if !isActive && !isOffSchedule(keyDetails) {
 			logrus.WithField("keyID", keyID).Debug("Key has recovered and is being restored to active pool.")
 			if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
 				return fmt.Errorf("failed to LRem key before LPush on recovery: %w", err)
@@ -166,24 +406,49 @@ func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey st
 			}
 		}
 
+		if !isActive {
+			return p.SyncSharedKey(tx, keyID)
+		}
 		return nil
 	})
 }
 
-func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string) error {
+// handleFailure 累加失败次数，达到阈值或 immediate 时拉黑 Key。
+// 开启共享状态时失败次数按 key_hash 在 store 中原子累加并写入所有分组中的相同密钥，拉黑时同步拉黑相同密钥。
+func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string, immediate bool) error {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
 	}
 
-	if keyDetails["status"] == models.KeyStatusInvalid {
+	switch keyDetails["status"] {
+	case models.KeyStatusInvalid, models.KeyStatusRetired, models.KeyStatusExhausted:
 		return nil
 	}
 
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
+	newFailureCount := failureCount + 1
+	sharedHash := p.sharedKeyHash(apiKey)
+	if sharedHash != "" {
+		newFailureCount, err = p.store.HIncrBy(sharedKeyStateKey(sharedHash), "failure_count", 1)
+		if err != nil {
+			return fmt.Errorf("failed to increment shared failure count in store: %w", err)
+		}
+	}
 
 	// 获取该分组的有效配置
-	blacklistThreshold := group.EffectiveConfig.BlacklistThreshold
+	cfg := group.EffectiveConfig
+	blacklistThreshold := cfg.BlacklistThreshold
+
+	// window 策略下按滑动窗口内的失败次数或失败率判定
+	windowTriggered := false
+	if cfg.FailurePolicy == FailurePolicyWindow {
+		failures, total, err := p.recordWindowEvent(apiKey.ID, cfg, false)
+		if err != nil {
+			return err
+		}
+		windowTriggered = windowExceeded(cfg, failures, total)
+	}
 
 	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
 		var key models.APIKey
@@ -191,40 +456,177 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 			return fmt.Errorf("failed to lock key %d for update: %w", apiKey.ID, err)
 		}
 
-		newFailureCount := failureCount + 1
-
 		updates := map[string]any{"failure_count": newFailureCount}
-		shouldBlacklist := blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold)
+		var shouldBlacklist bool
+		if cfg.FailurePolicy == FailurePolicyWindow {
+			shouldBlacklist = immediate || windowTriggered
+		} else {
+			shouldBlacklist = immediate || (blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold))
+		}
 		if shouldBlacklist {
 			updates["status"] = models.KeyStatusInvalid
+			updates["validation_failures"] = 0
+			updates["next_validation_at"] = nextValidationAt(cfg, 0)
 		}
 
 		if err := tx.Model(&key).Updates(updates).Error; err != nil {
 			return fmt.Errorf("failed to update key stats in DB: %w", err)
 		}
 
-		if _, err := p.store.HIncrBy(keyHashKey, "failure_count", 1); err != nil {
+		if sharedHash != "" {
+			if err := tx.Model(&models.APIKey{}).Where("key_hash = ?", sharedHash).Update("failure_count", newFailureCount).Error; err != nil {
+				return fmt.Errorf("failed to update shared failure count in DB: %w", err)
+			}
+			if err := p.store.HSet(keyHashKey, map[string]any{"failure_count": newFailureCount}); err != nil {
+				return fmt.Errorf("failed to update failure count in store: %w", err)
+			}
+		} else if _, err := p.store.HIncrBy(keyHashKey, "failure_count", 1); err != nil {
 			return fmt.Errorf("failed to increment failure count in store: %w", err)
 		}
 
 		if shouldBlacklist {
-			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}).Warn("Key has reached blacklist threshold, disabling.")
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "policy": cfg.FailurePolicy, "threshold": blacklistThreshold, "immediate": immediate}).Warn("Key has reached blacklist threshold, disabling.")
 			if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
 				return fmt.Errorf("failed to LRem key from active list: %w", err)
 			}
 			if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
 				return fmt.Errorf("failed to update key status to invalid in store: %w", err)
 			}
+			if err := p.store.Delete(failureWindowKey(apiKey.ID)); err != nil {
+				return fmt.Errorf("failed to reset failure window in store: %w", err)
+			}
+			return p.SyncSharedKey(tx, apiKey.ID)
+		}
+
+		return nil
+	})
+}
+
+// retireKey 将遇到不可恢复错误的密钥标记为退役，退役密钥不再参与轮询和定时验证
+func (p *KeyProvider) retireKey(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string, upstreamErr *app_errors.UpstreamError) error {
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("status", models.KeyStatusRetired).Error; err != nil {
+			return fmt.Errorf("failed to retire key in DB: %w", err)
+		}
+		if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
+			return fmt.Errorf("failed to LRem key from active list: %w", err)
+		}
+		if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusRetired}); err != nil {
+			return fmt.Errorf("failed to update key status to retired in store: %w", err)
+		}
+		return p.SyncSharedKey(tx, apiKey.ID)
+	})
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"keyID":    apiKey.ID,
+		"group":    group.Name,
+		"category": upstreamErr.Category,
+		"error":    upstreamErr.Message,
+	}).Warn("Key has been retired due to a terminal error.")
+	return nil
+}
+
+// disableKey 按错误规则永久停用密钥，需手动启用后才会重新参与轮询
+func (p *KeyProvider) disableKey(apiKey *models.APIKey, group *models.Group) error {
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("is_disabled", true).Error; err != nil {
+			return fmt.Errorf("failed to disable key in DB: %w", err)
+		}
+		if err := p.RemoveKeysFromActiveList(group.ID, []uint{apiKey.ID}); err != nil {
+			return err
+		}
+		return p.SyncSharedKey(tx, apiKey.ID)
+	})
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "group": group.Name}).Warn("Key has been disabled by error rule.")
+	return nil
+}
+
+// cooldownKey 将密钥移出轮询列表，冷却结束后自动恢复
+func (p *KeyProvider) cooldownKey(apiKey *models.APIKey, group *models.Group, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	until := time.Now().Add(duration)
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("cooldown_until", until).Error; err != nil {
+			return fmt.Errorf("failed to set key cooldown in DB: %w", err)
 		}
 
+		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
+		if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
+			return fmt.Errorf("failed to LRem key from active list: %w", err)
+		}
+		return p.SyncSharedKey(tx, apiKey.ID)
+	})
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "group": group.Name, "until": until}).Info("Key is cooling down by error rule.")
+
+	time.AfterFunc(duration, func() {
+		if err := p.restoreCooledKey(apiKey.ID); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to restore key after cooldown")
+		}
+	})
+	return nil
+}
+
+// restoreCooledKey 清除已到期的冷却标记，并在密钥仍可用时放回轮询列表
+func (p *KeyProvider) restoreCooledKey(keyID uint) error {
+	var key models.APIKey
+	if err := p.db.First(&key, keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to load key %d: %w", keyID, err)
+	}
+	if key.CooldownUntil == nil || key.CooldownUntil.After(time.Now()) {
 		return nil
+	}
+
+	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&key).Update("cooldown_until", nil).Error; err != nil {
+			return fmt.Errorf("failed to clear key cooldown in DB: %w", err)
+		}
+		if key.Status == models.KeyStatusActive && !key.IsDisabled {
+			if err := p.AddKeyToActiveList(&key); err != nil {
+				return err
+			}
+		}
+		return p.SyncSharedKey(tx, key.ID)
 	})
 }
 
+// RestoreExpiredCooldowns 恢复所有冷却已到期的密钥，作为进程重启等情况下定时恢复的兜底
+func (p *KeyProvider) RestoreExpiredCooldowns() {
+	var keyIDs []uint
+	if err := p.db.Model(&models.APIKey{}).Where("cooldown_until IS NOT NULL AND cooldown_until <= ?", time.Now()).Pluck("id", &keyIDs).Error; err != nil {
+		logrus.WithError(err).Error("Failed to query keys with expired cooldown")
+		return
+	}
+	for _, keyID := range keyIDs {
+		if err := p.restoreCooledKey(keyID); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to restore key after cooldown")
+		}
+	}
+}
+
+// initFlagKey 标记密钥已从数据库加载到 Store
+const initFlagKey = "initialization:db_keys_loaded"
+
+// InvalidateLoadedKeys 清除已加载标记，下次 Master 启动时重新从数据库加载密钥
+func (p *KeyProvider) InvalidateLoadedKeys() error {
+	return p.store.Delete(initFlagKey)
+}
+
 // LoadKeysFromDB 从数据库加载所有分组和密钥，并填充到 Store 中。
 func (p *KeyProvider) LoadKeysFromDB() error {
-	initFlagKey := "initialization:db_keys_loaded"
-
 	exists, err := p.store.Exists(initFlagKey)
 	if err != nil {
 		return fmt.Errorf("failed to check initialization flag: %w", err)
@@ -262,7 +664,7 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 				}
 			}
 
-			if key.Status == models.KeyStatusActive && !key.IsDisabled {
+			if key.Status == models.KeyStatusActive && !key.IsDisabled && key.CooldownUntil == nil && !p.offSchedule(key) {
 				allActiveKeyIDs[key.GroupID] = append(allActiveKeyIDs[key.GroupID], key.ID)
 			}
 		}
@@ -298,12 +700,18 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 	return nil
 }
 
-// AddKeys 批量添加新的 Key 到池和数据库中。
+// AddKeys 批量添加新的 Key 到池和数据库中，keys 中的密钥值为明文，写入前加密。
 func (p *KeyProvider) AddKeys(groupID uint, keys []models.APIKey) error {
 	if len(keys) == 0 {
 		return nil
 	}
 
+	for i := range keys {
+		if err := p.sealKey(&keys[i]); err != nil {
+			return err
+		}
+	}
+
 	err := p.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&keys).Error; err != nil {
 			return err
@@ -315,9 +723,15 @@ func (p *KeyProvider) AddKeys(groupID uint, keys []models.APIKey) error {
 				return err
 			}
 		}
+
+		// 其他分组中已存在的相同密钥将其共享状态同步给新 Key
+		for i := range keys {
+			if err := p.adoptSharedKeyState(tx, &keys[i]); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
-
 	return err
 }
 
@@ -331,7 +745,7 @@ func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error
 	var deletedCount int64
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("group_id = ? AND key_value IN ?", groupID, keyValues).Find(&keysToDelete).Error; err != nil {
+		if err := tx.Where("group_id = ? AND key_hash IN ?", groupID, encryption.HashKeys(keyValues)).Find(&keysToDelete).Error; err != nil {
 			return err
 		}
 
@@ -392,7 +806,7 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 				return err
 			}
 		}
-		return nil
+		return p.syncSharedKeys(tx, invalidKeys)
 	})
 
 	return restoredCount, err
@@ -428,7 +842,7 @@ func (p *KeyProvider) RestoreDisabledKeys(groupID uint) (int64, error) {
 				return err
 			}
 		}
-		return nil
+		return p.syncSharedKeys(tx, disabledKeys)
 	})
 
 	return restoredCount, err
@@ -445,7 +859,7 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
 		// 1. 查找要恢复的密钥
-		if err := tx.Where("group_id = ? AND key_value IN ? AND status = ?", groupID, keyValues, models.KeyStatusInvalid).Find(&keysToRestore).Error; err != nil {
+		if err := tx.Where("group_id = ? AND key_hash IN ? AND status IN ?", groupID, encryption.HashKeys(keyValues), []string{models.KeyStatusInvalid, models.KeyStatusRetired, models.KeyStatusExhausted}).Find(&keysToRestore).Error; err != nil {
 			return err
 		}
 
@@ -457,8 +871,9 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 
 		// 2. 更新数据库中的状态
 		updates := map[string]any{
-			"status":        models.KeyStatusActive,
-			"failure_count": 0,
+			"status":          models.KeyStatusActive,
+			"failure_count":   0,
+			"exhausted_until": nil,
 		}
 		result := tx.Model(&models.APIKey{}).Where("id IN ?", keyIDsToRestore).Updates(updates)
 		if result.Error != nil {
@@ -477,7 +892,7 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 			}
 		}
 
-		return nil
+		return p.syncSharedKeys(tx, keysToRestore)
 	})
 
 	return restoredCount, err
@@ -556,7 +971,7 @@ func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
 	// 第二步：批量删除所有相关的key hash
 	for _, keyID := range keyIDs {
 		keyHashKey := fmt.Sprintf("key:%d", keyID)
-		if err := p.store.Delete(keyHashKey); err != nil {
+		if err := p.store.Del(keyHashKey, failureWindowKey(keyID)); err != nil {
 			logrus.WithFields(logrus.Fields{
 				"keyID": keyID,
 				"error": err,
@@ -623,6 +1038,11 @@ func (p *KeyProvider) AddKeyToActiveList(key *models.APIKey) error {
 		return fmt.Errorf("failed to update key details for key %d: %w", key.ID, err)
 	}
 
+	// 不在可用时间内的 Key 由定时同步任务在进入时段后放回
+	if p.offSchedule(key) {
+		return nil
+	}
+
 	// 添加到active列表
 	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
 	// 先移除可能存在的重复项
@@ -651,8 +1071,8 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 		return fmt.Errorf("failed to HSet key details for key %d: %w", key.ID, err)
 	}
 
-	// 2. If active and not manually disabled, add to the active LIST
-	if key.Status == models.KeyStatusActive && !key.IsDisabled {
+	// 2. If active, not manually disabled and within its schedule, add to the active LIST
+	if key.Status == models.KeyStatusActive && !key.IsDisabled && !p.offSchedule(key) {
 		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
 		if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
 			return fmt.Errorf("failed to LRem key %d before LPush for group %d: %w", key.ID, key.GroupID, err)
@@ -672,22 +1092,53 @@ func (p *KeyProvider) removeKeyFromStore(keyID, groupID uint) error {
 	}
 
 	keyHashKey := fmt.Sprintf("key:%d", keyID)
-	if err := p.store.Delete(keyHashKey); err != nil {
+	if err := p.store.Del(keyHashKey, failureWindowKey(keyID)); err != nil {
 		return fmt.Errorf("failed to delete key HASH for key %d: %w", keyID, err)
 	}
 	return nil
 }
 
+// UpdateKeyTags 同步 Key 的标签到 store，供按标签路由使用
+func (p *KeyProvider) UpdateKeyTags(keyID uint, tags string) error {
+	return p.updateCachedKeyFields(keyID, map[string]any{"tags": tags})
+}
+
+// UpdateKeyQuota 同步 Key 的配额设置到 store，当前周期的已用量保持不变
+func (p *KeyProvider) UpdateKeyQuota(keyID uint, period string, requestQuota, tokenQuota int64) error {
+	return p.updateCachedKeyFields(keyID, map[string]any{
+		"quota_period":  period,
+		"request_quota": requestQuota,
+		"token_quota":   tokenQuota,
+	})
+}
+
+// updateCachedKeyFields 更新 store 中 Key 的部分字段，Key 已不在 store 中时跳过
+func (p *KeyProvider) updateCachedKeyFields(keyID uint, fields map[string]any) error {
+	keyHashKey := fmt.Sprintf("key:%d", keyID)
+	exists, err := p.store.Exists(keyHashKey)
+	if err != nil || !exists {
+		return err
+	}
+	return p.store.HSet(keyHashKey, fields)
+}
+
 // apiKeyToMap converts an APIKey model to a map for HSET.
 func (p *KeyProvider) apiKeyToMap(key *models.APIKey) map[string]any {
 	return map[string]any{
-		"id":            fmt.Sprint(key.ID),
-		"key_string":    key.KeyValue,
-		"status":        key.Status,
-		"is_disabled":   key.IsDisabled,
-		"failure_count": key.FailureCount,
-		"group_id":      key.GroupID,
-		"created_at":    key.CreatedAt.Unix(),
+		"id":               fmt.Sprint(key.ID),
+		"key_string":       key.KeyValue,
+		"status":           key.Status,
+		"is_disabled":      key.IsDisabled,
+		"failure_count":    key.FailureCount,
+		"group_id":         key.GroupID,
+		"created_at":       key.CreatedAt.Unix(),
+		"supported_models": key.SupportedModels,
+		"tags":             key.Tags,
+		"quota_period":     key.QuotaPeriod,
+		"request_quota":    key.RequestQuota,
+		"token_quota":      key.TokenQuota,
+		"off_schedule":     p.offSchedule(key),
+		"low_balance":      p.lowBalance(key),
 	}
 }
 
@@ -713,7 +1164,7 @@ func (p *KeyProvider) SelectKeyByID(groupID uint, keyID uint) (*models.APIKey, e
 		return nil, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("Key ID %d is disabled", keyID))
 	}
 
-	return &apiKey, nil
+	return p.DecryptKey(&apiKey)
 }
 
 // pluckIDs extracts IDs from a slice of APIKey.
@@ -0,0 +1,182 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	// 运行镜像不包含系统时区数据库，嵌入 tzdata 以支持配额重置时区
+	_ "time/tzdata"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// quotaUsageKey returns the store hash holding a key's usage in the quota period starting at periodStart.
+// With shared key state enabled, identical keys in different groups count against one quota keyed by key_hash.
+func (p *KeyProvider) quotaUsageKey(apiKey *models.APIKey, periodStart time.Time) string {
+	if sharedHash := p.sharedKeyHash(apiKey); sharedHash != "" {
+		return fmt.Sprintf("%s:quota:%d", sharedKeyStateKey(sharedHash), periodStart.Unix())
+	}
+	return fmt.Sprintf("key:%d:quota:%d", apiKey.ID, periodStart.Unix())
+}
+
+// quotaUsageGrace 是用量计数在重置边界之后的保留时间，避免边界附近的时钟偏差提前清除计数
+const quotaUsageGrace = time.Hour
+
+// quotaLocation 返回配额重置边界使用的时区，配置无效时回退到 UTC
+func quotaLocation(cfg types.SystemSettings) *time.Location {
+	loc, err := time.LoadLocation(cfg.QuotaResetTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// quotaPeriodBounds 返回 now 所在配额周期的起点和下一个重置边界
+func quotaPeriodBounds(now time.Time, period string) (time.Time, time.Time) {
+	if period == models.QuotaPeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// nextQuotaReset 返回 Key 配额的下一个重置边界
+func nextQuotaReset(apiKey *models.APIKey, group *models.Group) time.Time {
+	_, next := quotaPeriodBounds(time.Now().In(quotaLocation(group.EffectiveConfig)), apiKey.QuotaPeriod)
+	return next
+}
+
+// RecordUsage 累计 Key 在当前配额周期内的请求数和 Token 用量，达到配额后将 Key 置为 exhausted 直到下一个重置边界。
+// 配额用尽不计入失败次数。
+func (p *KeyProvider) RecordUsage(apiKey *models.APIKey, group *models.Group, tokens int64) {
+	if apiKey.QuotaPeriod == "" || (apiKey.RequestQuota <= 0 && apiKey.TokenQuota <= 0) {
+		return
+	}
+
+	go func() {
+		exceeded, err := p.incrementUsage(apiKey, group, tokens)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to record key usage")
+			return
+		}
+		if !exceeded {
+			return
+		}
+		if err := p.exhaustKey(apiKey, group); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to mark key as exhausted")
+		}
+	}()
+}
+
+// incrementUsage 累加用量计数。每个周期使用独立的计数键并在重置边界后过期，并发请求只做原子累加。
+func (p *KeyProvider) incrementUsage(apiKey *models.APIKey, group *models.Group, tokens int64) (bool, error) {
+	now := time.Now().In(quotaLocation(group.EffectiveConfig))
+	start, next := quotaPeriodBounds(now, apiKey.QuotaPeriod)
+	usageKey := p.quotaUsageKey(apiKey, start)
+
+	requests, err := p.store.HIncrBy(usageKey, "requests", 1)
+	if err != nil {
+		return false, err
+	}
+	usedTokens, err := p.store.HIncrBy(usageKey, "tokens", tokens)
+	if err != nil {
+		return false, err
+	}
+	if err := p.store.Expire(usageKey, next.Sub(now)+quotaUsageGrace); err != nil {
+		return false, err
+	}
+
+	return (apiKey.RequestQuota > 0 && requests >= apiKey.RequestQuota) ||
+		(apiKey.TokenQuota > 0 && usedTokens >= apiKey.TokenQuota), nil
+}
+
+// exhaustKey 将 Key 移出轮询列表并标记为 exhausted，到达下一个重置边界后自动恢复
+func (p *KeyProvider) exhaustKey(apiKey *models.APIKey, group *models.Group) error {
+	until := nextQuotaReset(apiKey, group)
+
+	var exhausted bool
+	err := p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		result := tx.Model(&models.APIKey{}).
+			Where("id = ? AND status = ?", apiKey.ID, models.KeyStatusActive).
+			Updates(map[string]any{"status": models.KeyStatusExhausted, "exhausted_until": until})
+		if result.Error != nil {
+			return fmt.Errorf("failed to mark key exhausted in DB: %w", result.Error)
+		}
+		exhausted = result.RowsAffected > 0
+		if !exhausted {
+			return nil
+		}
+
+		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
+		if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
+			return fmt.Errorf("failed to LRem key from active list: %w", err)
+		}
+		if err := p.store.HSet(fmt.Sprintf("key:%d", apiKey.ID), map[string]any{"status": models.KeyStatusExhausted}); err != nil {
+			return fmt.Errorf("failed to update key status in store: %w", err)
+		}
+		return p.SyncSharedKey(tx, apiKey.ID)
+	})
+	if err != nil || !exhausted {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "group": group.Name, "until": until}).Info("Key quota exhausted.")
+
+	time.AfterFunc(time.Until(until), func() {
+		if err := p.restoreExhaustedKey(apiKey.ID); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to restore key after quota reset")
+		}
+	})
+	return nil
+}
+
+// restoreExhaustedKey 在配额重置后将 Key 恢复为 active 并放回轮询列表
+func (p *KeyProvider) restoreExhaustedKey(keyID uint) error {
+	var key models.APIKey
+	if err := p.db.First(&key, keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to load key %d: %w", keyID, err)
+	}
+	if key.Status != models.KeyStatusExhausted || (key.ExhaustedUntil != nil && key.ExhaustedUntil.After(time.Now())) {
+		return nil
+	}
+
+	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
+		if err := tx.Model(&key).Updates(map[string]any{"status": models.KeyStatusActive, "exhausted_until": nil}).Error; err != nil {
+			return fmt.Errorf("failed to restore exhausted key in DB: %w", err)
+		}
+		key.Status = models.KeyStatusActive
+		key.ExhaustedUntil = nil
+		if key.IsDisabled {
+			if err := p.store.HSet(fmt.Sprintf("key:%d", key.ID), map[string]any{"status": models.KeyStatusActive}); err != nil {
+				return fmt.Errorf("failed to update key status in store: %w", err)
+			}
+		} else if err := p.AddKeyToActiveList(&key); err != nil {
+			return err
+		}
+		return p.SyncSharedKey(tx, key.ID)
+	})
+}
+
+// RestoreExhaustedKeys 恢复所有已到达重置边界的 exhausted Key，作为进程重启等情况下定时恢复的兜底
+func (p *KeyProvider) RestoreExhaustedKeys() {
+	var keyIDs []uint
+	err := p.db.Model(&models.APIKey{}).
+		Where("status = ? AND (exhausted_until IS NULL OR exhausted_until <= ?)", models.KeyStatusExhausted, time.Now()).
+		Pluck("id", &keyIDs).Error
+	if err != nil {
+		logrus.WithError(err).Error("Failed to query exhausted keys")
+		return
+	}
+	for _, keyID := range keyIDs {
+		if err := p.restoreExhaustedKey(keyID); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to restore key after quota reset")
+		}
+	}
+}
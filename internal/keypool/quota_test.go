@@ -0,0 +1,140 @@
+package keypool
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+)
+
+func TestQuotaPeriodBounds(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		period    string
+		wantStart time.Time
+		wantNext  time.Time
+	}{
+		{
+			name:      "day",
+			now:       time.Date(2024, 6, 1, 15, 30, 0, 0, time.UTC),
+			period:    models.QuotaPeriodDay,
+			wantStart: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "day at the boundary",
+			now:       time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+			period:    models.QuotaPeriodDay,
+			wantStart: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "month",
+			now:       time.Date(2024, 2, 29, 23, 59, 0, 0, time.UTC),
+			period:    models.QuotaPeriodMonth,
+			wantStart: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "month across the year",
+			now:       time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC),
+			period:    models.QuotaPeriodMonth,
+			wantStart: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "day in the reset time zone",
+			now:       time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC).In(shanghai),
+			period:    models.QuotaPeriodDay,
+			wantStart: time.Date(2024, 6, 2, 0, 0, 0, 0, shanghai),
+			wantNext:  time.Date(2024, 6, 3, 0, 0, 0, 0, shanghai),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, next := quotaPeriodBounds(tt.now, tt.period)
+			if !start.Equal(tt.wantStart) || !next.Equal(tt.wantNext) {
+				t.Errorf("quotaPeriodBounds() = %v, %v, want %v, %v", start, next, tt.wantStart, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestQuotaLocation(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     string
+	}{
+		{timezone: "Asia/Shanghai", want: "Asia/Shanghai"},
+		{timezone: "UTC", want: "UTC"},
+		{timezone: "Mars/Olympus", want: "UTC"},
+	}
+
+	for _, tt := range tests {
+		loc := quotaLocation(types.SystemSettings{QuotaResetTimezone: tt.timezone})
+		if loc.String() != tt.want {
+			t.Errorf("quotaLocation(%q) = %s, want %s", tt.timezone, loc, tt.want)
+		}
+	}
+}
+
+func TestIncrementUsage(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestQuota int64
+		tokenQuota   int64
+		tokens       []int64
+		wantExceeded []bool
+	}{
+		{name: "request quota", requestQuota: 2, tokens: []int64{10, 10, 10}, wantExceeded: []bool{false, true, true}},
+		{name: "token quota", tokenQuota: 100, tokens: []int64{40, 40, 40}, wantExceeded: []bool{false, false, true}},
+		{name: "either quota", requestQuota: 5, tokenQuota: 50, tokens: []int64{60}, wantExceeded: []bool{true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memoryStore := store.NewMemoryStore()
+			p := &KeyProvider{store: memoryStore, settingsManager: config.NewSystemSettingsManager()}
+			apiKey := &models.APIKey{
+				ID:           1,
+				KeyValue:     "sk-quota-test",
+				QuotaPeriod:  models.QuotaPeriodDay,
+				RequestQuota: tt.requestQuota,
+				TokenQuota:   tt.tokenQuota,
+			}
+			group := &models.Group{EffectiveConfig: types.SystemSettings{QuotaResetTimezone: "UTC"}}
+
+			var totalTokens int64
+			for i, tokens := range tt.tokens {
+				exceeded, err := p.incrementUsage(apiKey, group, tokens)
+				if err != nil {
+					t.Fatalf("request %d: incrementUsage() error = %v", i, err)
+				}
+				if exceeded != tt.wantExceeded[i] {
+					t.Errorf("request %d: incrementUsage() = %t, want %t", i, exceeded, tt.wantExceeded[i])
+				}
+				totalTokens += tokens
+			}
+
+			start, _ := quotaPeriodBounds(time.Now().UTC(), apiKey.QuotaPeriod)
+			usage, err := memoryStore.HGetAll(p.quotaUsageKey(apiKey, start))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if usage["requests"] != strconv.Itoa(len(tt.tokens)) || usage["tokens"] != strconv.FormatInt(totalTokens, 10) {
+				t.Errorf("usage = %v, want %d requests and %d tokens", usage, len(tt.tokens), totalTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// sharedKeyColumns 是同一密钥在各分组之间共享的状态和设置字段。
+// 模型列表与上游地址相关，不共享；失败次数和配额用量另外按 key_hash 在 store 中原子累加。
+var sharedKeyColumns = []string{
+	"status", "failure_count", "is_disabled", "cooldown_until", "exhausted_until",
+	"next_validation_at", "validation_failures", "remarks", "tags",
+	"quota_period", "request_quota", "token_quota",
+	"valid_from", "expires_at", "active_windows",
+	"balance", "balance_checked_at",
+}
+
+// copySharedKeyState 将 sharedKeyColumns 对应的字段从 src 复制到 dst
+func copySharedKeyState(dst, src *models.APIKey) {
+	dst.Status = src.Status
+	dst.FailureCount = src.FailureCount
+	dst.IsDisabled = src.IsDisabled
+	dst.CooldownUntil = src.CooldownUntil
+	dst.ExhaustedUntil = src.ExhaustedUntil
+	dst.NextValidationAt = src.NextValidationAt
+	dst.ValidationFailures = src.ValidationFailures
+	dst.Remarks = src.Remarks
+	dst.Tags = src.Tags
+	dst.QuotaPeriod = src.QuotaPeriod
+	dst.RequestQuota = src.RequestQuota
+	dst.TokenQuota = src.TokenQuota
+	dst.ValidFrom = src.ValidFrom
+	dst.ExpiresAt = src.ExpiresAt
+	dst.ActiveWindows = src.ActiveWindows
+	dst.Balance = src.Balance
+	dst.BalanceCheckedAt = src.BalanceCheckedAt
+}
+
+// inRotation 判断 Key 是否应处于分组的轮询列表中
+func (p *KeyProvider) inRotation(key *models.APIKey) bool {
+	return key.Status == models.KeyStatusActive && !key.IsDisabled && key.CooldownUntil == nil && !p.offSchedule(key)
+}
+
+// sharedKeyStateKey 返回开启共享状态时相同密钥共用的 store 哈希，保存按 key_hash 原子累加的失败次数
+func sharedKeyStateKey(keyHash string) string {
+	return "key_hash:" + keyHash
+}
+
+// SharedKeyStateEnabled 返回是否开启了跨分组共享密钥状态
+func (p *KeyProvider) SharedKeyStateEnabled() bool {
+	return p.settingsManager.GetSettings().SharedKeyState
+}
+
+// sharedKeyHash 在开启共享状态时返回 Key 的 key_hash，未开启时返回空字符串
+func (p *KeyProvider) sharedKeyHash(apiKey *models.APIKey) string {
+	if !p.SharedKeyStateEnabled() {
+		return ""
+	}
+	if apiKey.KeyHash != "" {
+		return apiKey.KeyHash
+	}
+	return encryption.HashKey(apiKey.KeyValue)
+}
+
+// SyncSharedKey 在事务 tx 中将 Key 的共享状态写入其他分组中相同密钥值的 Key，使拉黑、恢复和编辑在所有分组生效。
+// 调用方在修改 Key 的同一事务中调用，同步失败时状态变更随事务一起回滚。
+func (p *KeyProvider) SyncSharedKey(tx *gorm.DB, keyID uint) error {
+	if !p.SharedKeyStateEnabled() {
+		return nil
+	}
+
+	var source models.APIKey
+	if err := tx.First(&source, keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to load key %d: %w", keyID, err)
+	}
+
+	var siblings []models.APIKey
+	if err := tx.Where("key_hash = ? AND id <> ?", source.KeyHash, source.ID).Find(&siblings).Error; err != nil {
+		return fmt.Errorf("failed to find shared keys: %w", err)
+	}
+
+	if len(siblings) > 0 {
+		var state models.APIKey
+		copySharedKeyState(&state, &source)
+		err := tx.Model(&models.APIKey{}).Where("id IN ?", pluckIDs(siblings)).Select(sharedKeyColumns).Updates(&state).Error
+		if err != nil {
+			return fmt.Errorf("failed to update shared keys in DB: %w", err)
+		}
+	}
+
+	// 以同步源的失败次数作为共享计数的基准，后续失败在此基础上原子累加
+	if err := p.store.HSet(sharedKeyStateKey(source.KeyHash), map[string]any{"failure_count": source.FailureCount}); err != nil {
+		return fmt.Errorf("failed to update shared key state in store: %w", err)
+	}
+
+	for i := range siblings {
+		sibling := &siblings[i]
+		wasInRotation := p.inRotation(sibling)
+		copySharedKeyState(sibling, &source)
+
+		if err := p.store.HSet(fmt.Sprintf("key:%d", sibling.ID), p.apiKeyToMap(sibling)); err != nil {
+			return fmt.Errorf("failed to update shared key %d in store: %w", sibling.ID, err)
+		}
+
+		nowInRotation := p.inRotation(sibling)
+		if wasInRotation == nowInRotation {
+			continue
+		}
+		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", sibling.GroupID)
+		if err := p.store.LRem(activeKeysListKey, 0, sibling.ID); err != nil {
+			return fmt.Errorf("failed to LRem shared key %d: %w", sibling.ID, err)
+		}
+		if nowInRotation {
+			if err := p.store.LPush(activeKeysListKey, sibling.ID); err != nil {
+				return fmt.Errorf("failed to LPush shared key %d: %w", sibling.ID, err)
+			}
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"keyID": source.ID, "shared": len(siblings), "status": source.Status}).Debug("Synced shared key state")
+	return nil
+}
+
+// adoptSharedKeyState 在事务 tx 中使新加入分组的 Key 继承密钥库中已有的相同密钥的状态
+func (p *KeyProvider) adoptSharedKeyState(tx *gorm.DB, key *models.APIKey) error {
+	if !p.SharedKeyStateEnabled() {
+		return nil
+	}
+	var existing models.APIKey
+	err := tx.Select("id").Where("key_hash = ? AND id <> ?", key.KeyHash, key.ID).Order("id ASC").First(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to look up shared key: %w", err)
+	}
+	return p.SyncSharedKey(tx, existing.ID)
+}
+
+// syncSharedKeys 在事务 tx 中批量同步 Key 的共享状态
+func (p *KeyProvider) syncSharedKeys(tx *gorm.DB, keys []models.APIKey) error {
+	for i := range keys {
+		if err := p.SyncSharedKey(tx, keys[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package keypool
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestProvider 创建使用内存 SQLite 和 MemoryStore 的 KeyProvider，共享密钥状态使用默认配置（开启）。
+// 数据库只有一个连接，事务中误用 p.db 会直接死锁而不是静默地在事务外执行。
+func newTestProvider(t *testing.T) (*KeyProvider, *gorm.DB, store.Store) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	encryptionService, err := encryption.NewService("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memoryStore := store.NewMemoryStore()
+	return NewProvider(db, memoryStore, config.NewSystemSettingsManager(), nil, encryptionService), db, memoryStore
+}
+
+// addTestKey 向分组添加一个 Key 并返回数据库中的记录
+func addTestKey(t *testing.T, p *KeyProvider, groupID uint, keyValue string) *models.APIKey {
+	t.Helper()
+	keys := []models.APIKey{{GroupID: groupID, KeyValue: keyValue, Status: models.KeyStatusActive}}
+	if err := p.AddKeys(groupID, keys); err != nil {
+		t.Fatalf("AddKeys() error = %v", err)
+	}
+	return loadTestKey(t, p.db, keys[0].ID)
+}
+
+func loadTestKey(t *testing.T, db *gorm.DB, keyID uint) *models.APIKey {
+	t.Helper()
+	var key models.APIKey
+	if err := db.First(&key, keyID).Error; err != nil {
+		t.Fatalf("failed to load key %d: %v", keyID, err)
+	}
+	return &key
+}
+
+// inActiveList 判断 Key 是否在分组的轮询列表中
+func inActiveList(t *testing.T, s store.Store, key *models.APIKey) bool {
+	t.Helper()
+	ids, err := s.LRange(fmt.Sprintf("group:%d:active_keys", key.GroupID), 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slices.Contains(ids, strconv.FormatUint(uint64(key.ID), 10))
+}
+
+func TestSharedKeyStateFollowsStatusChanges(t *testing.T) {
+	p, db, memoryStore := newTestProvider(t)
+	source := addTestKey(t, p, 1, "sk-shared-key")
+	other := addTestKey(t, p, 1, "sk-other-key")
+	sibling := addTestKey(t, p, 2, "sk-shared-key")
+	group := &models.Group{ID: 1, Name: "one"}
+
+	if err := p.handleFailure(source, group, "key:1", "group:1:active_keys", true); err != nil {
+		t.Fatalf("handleFailure() error = %v", err)
+	}
+	if got := loadTestKey(t, db, sibling.ID); got.Status != models.KeyStatusInvalid {
+		t.Errorf("sibling status = %q after the source was blacklisted, want invalid", got.Status)
+	}
+	if details, _ := memoryStore.HGetAll(fmt.Sprintf("key:%d", sibling.ID)); details["status"] != models.KeyStatusInvalid {
+		t.Errorf("cached sibling status = %q, want invalid", details["status"])
+	}
+	if inActiveList(t, memoryStore, sibling) {
+		t.Error("blacklisted sibling is still in rotation")
+	}
+	if got := loadTestKey(t, db, other.ID); got.Status != models.KeyStatusActive || !inActiveList(t, memoryStore, got) {
+		t.Error("a different key in the source group was affected")
+	}
+
+	if err := p.handleSuccess(source, "key:1", "group:1:active_keys"); err != nil {
+		t.Fatalf("handleSuccess() error = %v", err)
+	}
+	if got := loadTestKey(t, db, sibling.ID); got.Status != models.KeyStatusActive || got.FailureCount != 0 {
+		t.Errorf("sibling = %s with %d failures after the source recovered, want active with 0", got.Status, got.FailureCount)
+	}
+	if !inActiveList(t, memoryStore, sibling) {
+		t.Error("recovered sibling was not put back into rotation")
+	}
+}
+
+func TestSharedKeyStateAdoptedByNewKeys(t *testing.T) {
+	p, db, memoryStore := newTestProvider(t)
+	source := addTestKey(t, p, 1, "sk-shared-key")
+
+	if err := p.disableKey(source, &models.Group{ID: 1, Name: "one"}); err != nil {
+		t.Fatalf("disableKey() error = %v", err)
+	}
+	if err := db.Model(source).Updates(map[string]any{"remarks": "billing account"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	added := addTestKey(t, p, 2, "sk-shared-key")
+	if !added.IsDisabled || added.Remarks != "billing account" {
+		t.Errorf("new key = disabled %t remarks %q, want the existing key's state", added.IsDisabled, added.Remarks)
+	}
+	if inActiveList(t, memoryStore, added) {
+		t.Error("new key adopting a disabled state was put into rotation")
+	}
+	if got := loadTestKey(t, db, source.ID); !got.IsDisabled {
+		t.Error("adding a key changed the existing key's state")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
 	"time"
 
@@ -59,13 +60,13 @@ func (s *KeyValidator) ValidateSingleKey(key *models.APIKey, group *models.Group
 		return false, fmt.Errorf("failed to get channel for group %s: %w", group.Name, err)
 	}
 
-	isValid, validationErr := ch.ValidateKey(ctx, key, group)
-
-	var errorMsg string
-	if !isValid && validationErr != nil {
-		errorMsg = validationErr.Error()
+	decryptedKey, err := s.keypoolProvider.DecryptKey(key)
+	if err != nil {
+		return false, err
 	}
-	s.keypoolProvider.UpdateStatus(key, group, isValid, errorMsg)
+	isValid, validationErr := ch.ValidateKey(ctx, decryptedKey, group)
+
+	s.keypoolProvider.UpdateStatus(key, group, isValid, validationErr)
 
 	if !isValid {
 		logrus.WithFields(logrus.Fields{
@@ -90,16 +91,16 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 
 	// Find which of the provided keys actually exist in the database for this group
 	var existingKeys []models.APIKey
-	if err := s.DB.Where("group_id = ? AND key_value IN ?", group.ID, keyValues).Find(&existingKeys).Error; err != nil {
+	if err := s.DB.Where("group_id = ? AND key_hash IN ?", group.ID, encryption.HashKeys(keyValues)).Find(&existingKeys).Error; err != nil {
 		return nil, fmt.Errorf("failed to query keys from DB: %w", err)
 	}
 	existingKeyMap := make(map[string]models.APIKey)
 	for _, k := range existingKeys {
-		existingKeyMap[k.KeyValue] = k
+		existingKeyMap[k.KeyHash] = k
 	}
 
 	for i, kv := range keyValues {
-		apiKey, exists := existingKeyMap[kv]
+		apiKey, exists := existingKeyMap[encryption.HashKey(kv)]
 		if !exists {
 			results[i] = KeyTestResult{
 				KeyValue: kv,
@@ -0,0 +1,220 @@
+// Package limiter provides a concurrency limiter with bounded, time-limited and prioritized wait queues.
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned when no slot is free and the wait queue is full or disabled.
+	ErrQueueFull = errors.New("limiter: wait queue is full")
+	// ErrTimeout is returned when a request waited for the maximum time without getting a slot.
+	ErrTimeout = errors.New("limiter: timed out waiting for a slot")
+	// ErrShed is returned when a queued request was evicted by a higher-priority request.
+	ErrShed = errors.New("limiter: request shed in favor of higher priority traffic")
+)
+
+// Priority 表示请求的调度优先级，数值越小优先级越高
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota
+	PriorityBatch
+	PriorityBackground
+)
+
+// Priority names used in settings.
+const (
+	PriorityNameInteractive = "interactive"
+	PriorityNameBatch       = "batch"
+	PriorityNameBackground  = "background"
+)
+
+// ParsePriority converts a priority name into a Priority, defaulting to interactive.
+func ParsePriority(name string) Priority {
+	switch name {
+	case PriorityNameBatch:
+		return PriorityBatch
+	case PriorityNameBackground:
+		return PriorityBackground
+	default:
+		return PriorityInteractive
+	}
+}
+
+// String returns the setting name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityBatch:
+		return PriorityNameBatch
+	case PriorityBackground:
+		return PriorityNameBackground
+	default:
+		return PriorityNameInteractive
+	}
+}
+
+// Limiter 是一个支持有界等待队列的并发限制器。
+// 当并发槽位用尽时，请求按所属队列（通常为分组名）排队等待，直到获得槽位、超时或请求被取消。
+// 等待者按优先级排序，同一优先级内先进先出；队列已满时高优先级请求会挤掉同队列中优先级最低的等待者。
+type Limiter struct {
+	mu        sync.Mutex
+	capacity  int
+	maxQueued int
+	active    int
+	waiters   *list.List
+	queued    map[string]int
+}
+
+type waiter struct {
+	queue    string
+	priority Priority
+	ready    chan struct{}
+	err      error
+	done     bool
+}
+
+// New creates a limiter allowing capacity concurrent holders and at most maxQueued waiters per queue.
+func New(capacity, maxQueued int) *Limiter {
+	return &Limiter{
+		capacity:  capacity,
+		maxQueued: maxQueued,
+		waiters:   list.New(),
+		queued:    make(map[string]int),
+	}
+}
+
+// Acquire 获取一个并发槽位。槽位已满时在指定队列中最多等待 timeout，timeout <= 0 表示不排队。
+func (l *Limiter) Acquire(ctx context.Context, queue string, priority Priority, timeout time.Duration) error {
+	l.mu.Lock()
+	if l.active < l.capacity && l.waiters.Len() == 0 {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+
+	if timeout <= 0 || l.maxQueued <= 0 {
+		l.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	if l.queued[queue] >= l.maxQueued && !l.shedLowerPriority(queue, priority) {
+		l.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	w := &waiter{queue: queue, priority: priority, ready: make(chan struct{})}
+	elem := l.insertWaiter(w)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return w.err
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.done {
+		// 超时与分配槽位（或被挤出）同时发生，以已确定的结果为准
+		return w.err
+	}
+	l.removeWaiter(elem)
+	return err
+}
+
+// Release 释放一个槽位，如有等待者则直接移交给优先级最高的等待者。
+// 容量调小后持有者多于容量时，释放的槽位不再移交。
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if front := l.waiters.Front(); front != nil && l.active <= l.capacity {
+		w := l.removeWaiter(front)
+		w.done = true
+		close(w.ready)
+		return
+	}
+
+	if l.active > 0 {
+		l.active--
+	}
+}
+
+// SetCapacity 原地调整并发上限，已持有槽位的请求继续计入。
+// 调大时空出的槽位立即交给等待者，调小时超出的持有者释放后才会让出槽位。
+func (l *Limiter) SetCapacity(capacity int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.capacity = capacity
+	for l.active < l.capacity {
+		front := l.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := l.removeWaiter(front)
+		w.done = true
+		close(w.ready)
+		l.active++
+	}
+}
+
+// Stats returns the number of active holders and queued waiters.
+func (l *Limiter) Stats() (active int, queued int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active, l.waiters.Len()
+}
+
+// insertWaiter 按优先级插入等待者，同优先级保持先进先出。Caller must hold l.mu.
+func (l *Limiter) insertWaiter(w *waiter) *list.Element {
+	l.queued[w.queue]++
+	for e := l.waiters.Back(); e != nil; e = e.Prev() {
+		if e.Value.(*waiter).priority <= w.priority {
+			return l.waiters.InsertAfter(w, e)
+		}
+	}
+	return l.waiters.PushFront(w)
+}
+
+// shedLowerPriority 挤出同队列中最后一个优先级低于 priority 的等待者。Caller must hold l.mu.
+func (l *Limiter) shedLowerPriority(queue string, priority Priority) bool {
+	for e := l.waiters.Back(); e != nil; e = e.Prev() {
+		w := e.Value.(*waiter)
+		if w.queue != queue {
+			continue
+		}
+		if w.priority <= priority {
+			return false
+		}
+		l.removeWaiter(e)
+		w.err = ErrShed
+		w.done = true
+		close(w.ready)
+		return true
+	}
+	return false
+}
+
+// removeWaiter removes a waiter from the queue. Caller must hold l.mu.
+func (l *Limiter) removeWaiter(elem *list.Element) *waiter {
+	w := l.waiters.Remove(elem).(*waiter)
+	if l.queued[w.queue] <= 1 {
+		delete(l.queued, w.queue)
+	} else {
+		l.queued[w.queue]--
+	}
+	return w
+}
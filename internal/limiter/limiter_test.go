@@ -0,0 +1,219 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// acquireAsync 在后台获取槽位，返回接收结果的通道
+func acquireAsync(l *Limiter, ctx context.Context, queue string, priority Priority, timeout time.Duration) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- l.Acquire(ctx, queue, priority, timeout)
+	}()
+	return result
+}
+
+// waitQueued 等待限制器中排队的请求数达到 n
+func waitQueued(t *testing.T, l *Limiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, queued := l.Stats(); queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, queued := l.Stats()
+	t.Fatalf("queued = %d, want %d", queued, n)
+}
+
+func receive(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() did not return")
+		return nil
+	}
+}
+
+func TestAcquireWithoutQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		maxQueued int
+		timeout   time.Duration
+		holders   int
+		wantErr   error
+	}{
+		{name: "free slot", capacity: 2, maxQueued: 0, holders: 1, wantErr: nil},
+		{name: "queue disabled", capacity: 1, maxQueued: 0, timeout: time.Second, holders: 1, wantErr: ErrQueueFull},
+		{name: "no wait timeout", capacity: 1, maxQueued: 5, timeout: 0, holders: 1, wantErr: ErrQueueFull},
+		{name: "wait times out", capacity: 1, maxQueued: 5, timeout: 10 * time.Millisecond, holders: 1, wantErr: ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.capacity, tt.maxQueued)
+			for i := 0; i < tt.holders; i++ {
+				if err := l.Acquire(context.Background(), "g", PriorityInteractive, 0); err != nil {
+					t.Fatalf("holder %d: Acquire() error = %v", i, err)
+				}
+			}
+			err := l.Acquire(context.Background(), "g", PriorityInteractive, tt.timeout)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Acquire() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, queued := l.Stats(); queued != 0 {
+				t.Errorf("queued = %d after Acquire() returned", queued)
+			}
+		})
+	}
+}
+
+func TestReleaseHandsSlotToWaiter(t *testing.T) {
+	l := New(1, 5)
+	if err := l.Acquire(context.Background(), "g", PriorityInteractive, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	result := acquireAsync(l, context.Background(), "g", PriorityInteractive, time.Second)
+	waitQueued(t, l, 1)
+
+	l.Release()
+	if err := receive(t, result); err != nil {
+		t.Fatalf("queued Acquire() error = %v", err)
+	}
+	if active, queued := l.Stats(); active != 1 || queued != 0 {
+		t.Errorf("Stats() = %d active, %d queued, want the slot handed over", active, queued)
+	}
+
+	l.Release()
+	if active, _ := l.Stats(); active != 0 {
+		t.Errorf("active = %d after the last release", active)
+	}
+}
+
+func TestQueueLimitIsPerQueue(t *testing.T) {
+	l := New(1, 1)
+	if err := l.Acquire(context.Background(), "a", PriorityInteractive, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := acquireAsync(l, ctx, "a", PriorityInteractive, time.Second)
+	waitQueued(t, l, 1)
+
+	if err := l.Acquire(context.Background(), "a", PriorityInteractive, time.Second); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("second waiter in the same queue: error = %v, want ErrQueueFull", err)
+	}
+
+	other := acquireAsync(l, ctx, "b", PriorityInteractive, time.Second)
+	waitQueued(t, l, 2)
+
+	cancel()
+	for _, result := range []<-chan error{first, other} {
+		if err := receive(t, result); !errors.Is(err, context.Canceled) {
+			t.Errorf("cancelled waiter: error = %v, want context.Canceled", err)
+		}
+	}
+	if _, queued := l.Stats(); queued != 0 {
+		t.Errorf("queued = %d after all waiters were cancelled", queued)
+	}
+}
+
+func TestReleaseServesHigherPriorityFirst(t *testing.T) {
+	l := New(1, 5)
+	if err := l.Acquire(context.Background(), "g", PriorityInteractive, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	background := acquireAsync(l, context.Background(), "g", PriorityBackground, time.Second)
+	waitQueued(t, l, 1)
+	batch := acquireAsync(l, context.Background(), "g", PriorityBatch, time.Second)
+	waitQueued(t, l, 2)
+	interactive := acquireAsync(l, context.Background(), "g", PriorityInteractive, time.Second)
+	waitQueued(t, l, 3)
+
+	for _, want := range []struct {
+		name   string
+		result <-chan error
+	}{
+		{"interactive", interactive},
+		{"batch", batch},
+		{"background", background},
+	} {
+		l.Release()
+		if err := receive(t, want.result); err != nil {
+			t.Fatalf("%s waiter: error = %v", want.name, err)
+		}
+	}
+}
+
+func TestFullQueueShedsLowerPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		queued   Priority
+		incoming Priority
+		wantShed bool
+	}{
+		{name: "interactive sheds background", queued: PriorityBackground, incoming: PriorityInteractive, wantShed: true},
+		{name: "batch sheds background", queued: PriorityBackground, incoming: PriorityBatch, wantShed: true},
+		{name: "same priority is rejected", queued: PriorityBatch, incoming: PriorityBatch},
+		{name: "lower priority is rejected", queued: PriorityInteractive, incoming: PriorityBackground},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(1, 1)
+			if err := l.Acquire(context.Background(), "g", PriorityInteractive, 0); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			queued := acquireAsync(l, ctx, "g", tt.queued, time.Second)
+			waitQueued(t, l, 1)
+
+			incoming := acquireAsync(l, ctx, "g", tt.incoming, time.Second)
+			if tt.wantShed {
+				if err := receive(t, queued); !errors.Is(err, ErrShed) {
+					t.Fatalf("queued waiter: error = %v, want ErrShed", err)
+				}
+				l.Release()
+				if err := receive(t, incoming); err != nil {
+					t.Errorf("incoming waiter: error = %v, want the slot", err)
+				}
+				return
+			}
+			if err := receive(t, incoming); !errors.Is(err, ErrQueueFull) {
+				t.Errorf("incoming waiter: error = %v, want ErrQueueFull", err)
+			}
+			cancel()
+			receive(t, queued)
+		})
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		name string
+		want Priority
+	}{
+		{PriorityNameInteractive, PriorityInteractive},
+		{PriorityNameBatch, PriorityBatch},
+		{PriorityNameBackground, PriorityBackground},
+		{"", PriorityInteractive},
+		{"urgent", PriorityInteractive},
+	}
+
+	for _, tt := range tests {
+		if got := ParsePriority(tt.name); got != tt.want {
+			t.Errorf("ParsePriority(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
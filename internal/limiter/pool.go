@@ -0,0 +1,46 @@
+package limiter
+
+import "sync"
+
+// Pool 按名称管理一组限制器，例如每个分组或每个代理密钥一个。
+// 配置的容量变化时原地调整已有的限制器，持有中的请求继续计入新的上限。
+type Pool struct {
+	mu        sync.Mutex
+	maxQueued int
+	limiters  map[string]*Limiter
+}
+
+// NewPool creates a pool whose limiters allow at most maxQueued waiters each.
+func NewPool(maxQueued int) *Pool {
+	return &Pool{
+		maxQueued: maxQueued,
+		limiters:  make(map[string]*Limiter),
+	}
+}
+
+// Get returns the limiter registered under name, resized to the given capacity.
+func (p *Pool) Get(name string, capacity int) *Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if l, ok := p.limiters[name]; ok {
+		l.SetCapacity(capacity)
+		return l
+	}
+	l := New(capacity, p.maxQueued)
+	p.limiters[name] = l
+	return l
+}
+
+// Prune 移除 keep 返回 false 的限制器，例如已删除或改名的分组。
+// 仍持有被移除限制器的请求在其上正常释放。
+func (p *Pool) Prune(keep func(name string) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name := range p.limiters {
+		if !keep(name) {
+			delete(p.limiters, name)
+		}
+	}
+}
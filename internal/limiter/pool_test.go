@@ -0,0 +1,88 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoolGetResizesInPlace(t *testing.T) {
+	p := NewPool(5)
+	l := p.Get("group", 1)
+	if err := l.Acquire(context.Background(), "group", PriorityInteractive, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	resized := p.Get("group", 2)
+	if resized != l {
+		t.Fatal("Get() replaced the limiter when the capacity changed")
+	}
+	if err := resized.Acquire(context.Background(), "group", PriorityInteractive, 0); err != nil {
+		t.Fatalf("Acquire() after growing the capacity: error = %v", err)
+	}
+	if err := resized.Acquire(context.Background(), "group", PriorityInteractive, 0); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Acquire() above the new capacity: error = %v, want ErrQueueFull", err)
+	}
+	if active, _ := resized.Stats(); active != 2 {
+		t.Errorf("active = %d, want the holder of the old capacity still counted", active)
+	}
+}
+
+func TestPoolPrune(t *testing.T) {
+	p := NewPool(5)
+	kept := p.Get("kept", 1)
+	removed := p.Get("removed", 1)
+
+	p.Prune(func(name string) bool { return name == "kept" })
+
+	if p.Get("kept", 1) != kept {
+		t.Error("Prune() removed a limiter that should be kept")
+	}
+	if p.Get("removed", 1) == removed {
+		t.Error("Prune() kept a limiter that should be removed")
+	}
+}
+
+func TestSetCapacity(t *testing.T) {
+	t.Run("growing hands slots to waiters", func(t *testing.T) {
+		l := New(1, 5)
+		if err := l.Acquire(context.Background(), "g", PriorityInteractive, 0); err != nil {
+			t.Fatal(err)
+		}
+		first := acquireAsync(l, context.Background(), "g", PriorityInteractive, time.Second)
+		second := acquireAsync(l, context.Background(), "g", PriorityInteractive, time.Second)
+		waitQueued(t, l, 2)
+
+		l.SetCapacity(3)
+		for _, result := range []<-chan error{first, second} {
+			if err := receive(t, result); err != nil {
+				t.Errorf("waiter: error = %v, want a slot after growing", err)
+			}
+		}
+		if active, queued := l.Stats(); active != 3 || queued != 0 {
+			t.Errorf("Stats() = %d active, %d queued, want 3 active", active, queued)
+		}
+	})
+
+	t.Run("shrinking waits for holders to release", func(t *testing.T) {
+		l := New(2, 5)
+		for i := 0; i < 2; i++ {
+			if err := l.Acquire(context.Background(), "g", PriorityInteractive, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		l.SetCapacity(1)
+		waiting := acquireAsync(l, context.Background(), "g", PriorityInteractive, time.Second)
+		waitQueued(t, l, 1)
+
+		l.Release()
+		if active, queued := l.Stats(); active != 1 || queued != 1 {
+			t.Fatalf("Stats() = %d active, %d queued, want the released slot dropped above the new capacity", active, queued)
+		}
+		l.Release()
+		if err := receive(t, waiting); err != nil {
+			t.Errorf("waiter: error = %v, want the slot once under the new capacity", err)
+		}
+	})
+}
@@ -8,14 +8,24 @@ import (
 	"time"
 
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/limiter"
+	"gpt-load/internal/models"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// RequestPriorityKey is the context key holding the limiter.Priority of a request.
+	RequestPriorityKey = "requestPriority"
+
+	authKeyContextKey = "authKey"
+)
+
 // Logger creates a high-performance logging middleware
 func Logger(config types.LogConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -157,11 +167,7 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 			return
 		}
 
-		// Check both key collections to prevent timing attacks
-		_, existsInEffective := group.EffectiveConfig.ProxyKeysMap[key]
-		_, existsInGroup := group.ProxyKeysMap[key]
-
-		if existsInEffective || existsInGroup {
+		if IsValidProxyKey(group, key) {
 			c.Next()
 			return
 		}
@@ -171,6 +177,14 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 	}
 }
 
+// IsValidProxyKey reports whether key is a global or group proxy key of the group.
+func IsValidProxyKey(group *models.Group, key string) bool {
+	// Check both key collections to prevent timing attacks
+	_, existsInEffective := group.EffectiveConfig.ProxyKeysMap[key]
+	_, existsInGroup := group.ProxyKeysMap[key]
+	return existsInEffective || existsInGroup
+}
+
 // Recovery creates a recovery middleware with custom error handling
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
@@ -180,23 +194,92 @@ func Recovery() gin.HandlerFunc {
 	})
 }
 
-// RateLimiter creates a simple rate limiting middleware
-func RateLimiter(config types.PerformanceConfig) gin.HandlerFunc {
-	// Simple semaphore-based rate limiting
-	semaphore := make(chan struct{}, config.MaxConcurrentRequests)
+// RateLimiter creates a concurrency limiting middleware for proxy routes.
+// Requests acquire a slot from the group limit, then the proxy key limit and finally the global limit,
+// so requests waiting on a saturated group or proxy key never hold global slots needed by other groups.
+// When a limit is reached, requests wait in a bounded queue until the queue timeout expires.
+// Proxy requests are scheduled by the priority of their group and proxy key.
+func RateLimiter(config types.PerformanceConfig, gm *services.GroupManager) gin.HandlerFunc {
+	global := limiter.New(config.MaxConcurrentRequests, config.MaxQueuedRequests)
+	groupLimiters := limiter.NewPool(config.MaxQueuedRequests)
+	keyLimiters := limiter.NewPool(config.MaxQueuedRequests)
+	timeout := time.Duration(config.QueueTimeoutSeconds) * time.Second
+
+	// 分组配置重新加载后移除已删除或改名的分组、已移除的代理密钥对应的限制器
+	gm.OnReload(func(groups map[string]*models.Group) {
+		groupLimiters.Prune(func(name string) bool {
+			_, ok := groups[name]
+			return ok
+		})
+		keyLimiters.Prune(func(name string) bool {
+			groupName, proxyKey, _ := strings.Cut(name, "|")
+			group, ok := groups[groupName]
+			return ok && IsValidProxyKey(group, proxyKey)
+		})
+	})
 
 	return func(c *gin.Context) {
-		select {
-		case semaphore <- struct{}{}:
-			defer func() { <-semaphore }()
-			c.Next()
-		default:
-			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Too many concurrent requests"))
+		groupName := c.Param("group_name")
+		group, err := gm.GetGroupByName(groupName)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to retrieve proxy group"))
 			c.Abort()
+			return
 		}
+
+		proxyKey := extractAuthKey(c)
+		priority := RequestPriority(group, proxyKey)
+		c.Set(RequestPriorityKey, priority)
+		ctx := c.Request.Context()
+
+		if limit := group.EffectiveConfig.GroupMaxConcurrency; limit > 0 {
+			groupLimiter := groupLimiters.Get(groupName, limit)
+			if err := groupLimiter.Acquire(ctx, groupName, priority, timeout); err != nil {
+				logrus.Debugf("Group limiter rejected %s request for group %s: %v", priority, groupName, err)
+				response.Error(c, app_errors.NewAPIError(app_errors.ErrTooManyRequests, fmt.Sprintf("Too many concurrent requests for group '%s'", groupName)))
+				c.Abort()
+				return
+			}
+			defer groupLimiter.Release()
+		}
+
+		if limit := group.EffectiveConfig.ProxyKeyMaxConcurrency; limit > 0 {
+			keyLimiter := keyLimiters.Get(groupName+"|"+proxyKey, limit)
+			if err := keyLimiter.Acquire(ctx, groupName, priority, timeout); err != nil {
+				logrus.Debugf("Proxy key limiter rejected %s request for group %s: %v", priority, groupName, err)
+				response.Error(c, app_errors.NewAPIError(app_errors.ErrTooManyRequests, fmt.Sprintf("Too many concurrent requests for this proxy key in group '%s'", groupName)))
+				c.Abort()
+				return
+			}
+			defer keyLimiter.Release()
+		}
+
+		if err := global.Acquire(ctx, groupName, priority, timeout); err != nil {
+			logrus.Debugf("Global limiter rejected %s request %s: %v", priority, c.Request.URL.Path, err)
+			response.Error(c, app_errors.ErrServerBusy)
+			c.Abort()
+			return
+		}
+		defer global.Release()
+
+		c.Next()
 	}
 }
 
+// RequestPriority 根据代理密钥和分组配置确定请求的调度优先级
+func RequestPriority(group *models.Group, proxyKey string) limiter.Priority {
+	cfg := group.EffectiveConfig
+	if proxyKey != "" {
+		if _, ok := utils.StringToSet(cfg.BackgroundProxyKeys, ",")[proxyKey]; ok {
+			return limiter.PriorityBackground
+		}
+		if _, ok := utils.StringToSet(cfg.BatchProxyKeys, ",")[proxyKey]; ok {
+			return limiter.PriorityBatch
+		}
+	}
+	return limiter.ParsePriority(cfg.RequestPriority)
+}
+
 // ErrorHandler creates an error handling middleware
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -230,8 +313,24 @@ func isMonitoringEndpoint(path string) bool {
 	return false
 }
 
+// ProxyKey returns the proxy key used to authenticate the current request.
+func ProxyKey(c *gin.Context) string {
+	return extractAuthKey(c)
+}
+
 // extractAuthKey extracts a auth key.
+// The result is cached on the context because the query key is stripped after the first extraction.
 func extractAuthKey(c *gin.Context) string {
+	if key, exists := c.Get(authKeyContextKey); exists {
+		return key.(string)
+	}
+	key := parseAuthKey(c)
+	c.Set(authKeyContextKey, key)
+	return key
+}
+
+// parseAuthKey reads the auth key from the query, Authorization, X-Api-Key or X-Goog-Api-Key.
+func parseAuthKey(c *gin.Context) string {
 	// Query key
 	if key := c.Query("key"); key != "" {
 		query := c.Request.URL.Query()
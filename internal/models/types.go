@@ -14,7 +14,15 @@ import (
 const (
 	KeyStatusActive    = "active"
 	KeyStatusInvalid   = "invalid"
-	KeyStatusDisabled  = "disabled" // 手动停用状态
+	KeyStatusDisabled  = "disabled"  // 手动停用状态
+	KeyStatusRetired   = "retired"   // 不可恢复错误导致的永久退役状态，不再参与定时验证
+	KeyStatusExhausted = "exhausted" // 用量配额已用尽，到达重置边界后自动恢复
+)
+
+// Key 配额周期
+const (
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
 )
 
 // SystemSetting 对应 system_settings 表
@@ -36,13 +44,44 @@ type GroupConfig struct {
 	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
 	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
 	ProxyURL                     *string `json:"proxy_url,omitempty"`
+	RequestPriority              *string `json:"request_priority,omitempty"`
+	BatchProxyKeys               *string `json:"batch_proxy_keys,omitempty"`
+	BackgroundProxyKeys          *string `json:"background_proxy_keys,omitempty"`
+	GroupMaxConcurrency          *int    `json:"group_max_concurrency,omitempty"`
+	ProxyKeyMaxConcurrency       *int    `json:"proxy_key_max_concurrency,omitempty"`
+	MaxPromptTokens              *int    `json:"max_prompt_tokens,omitempty"`
+	LongContextGroup             *string `json:"long_context_group,omitempty"`
 	MaxRetries                   *int    `json:"max_retries,omitempty"`
 	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
+	FailurePolicy                *string `json:"failure_policy,omitempty"`
+	FailureWindowSeconds         *int    `json:"failure_window_seconds,omitempty"`
+	FailureWindowMaxFailures     *int    `json:"failure_window_max_failures,omitempty"`
+	FailureWindowMaxRate         *int    `json:"failure_window_max_rate,omitempty"`
+	FailureWindowMinRequests     *int    `json:"failure_window_min_requests,omitempty"`
 	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
+	KeyValidationMaxMinutes      *int    `json:"key_validation_max_minutes,omitempty"`
+	KeyValidationCron            *string `json:"key_validation_cron,omitempty"`
+	ActiveKeyProbesPerHour       *int    `json:"active_key_probes_per_hour,omitempty"`
+	KeyModelDiscoveryHours       *int    `json:"key_model_discovery_hours,omitempty"`
+	KeyBalanceCheckHours         *int    `json:"key_balance_check_hours,omitempty"`
+	KeyBalanceEndpoint           *string `json:"key_balance_endpoint,omitempty"`
+	KeyBalanceJSONPath           *string `json:"key_balance_json_path,omitempty"`
+	KeyLowBalanceThreshold       *int    `json:"key_low_balance_threshold,omitempty"`
+	QuotaResetTimezone           *string `json:"quota_reset_timezone,omitempty"`
 	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
 	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
 	MaxResponseBodyLogSize       *int    `json:"max_response_body_log_size,omitempty"`
+	LogRedactionDetectors        *string `json:"log_redaction_detectors,omitempty"`
+	LogRedactionPatterns         *string `json:"log_redaction_patterns,omitempty"`
 	RetryIntervalMs              *int    `json:"retry_interval_ms,omitempty"`
+	RetryMaxIntervalMs           *int    `json:"retry_max_interval_ms,omitempty"`
+	RetryJitter                  *bool   `json:"retry_jitter,omitempty"`
+	RetryMaxTotalSeconds         *int    `json:"retry_max_total_seconds,omitempty"`
+	RetryStatusCodes             *string `json:"retry_status_codes,omitempty"`
+	RetrySameKey                 *bool   `json:"retry_same_key,omitempty"`
+	RetryErrorCategories         *string `json:"retry_error_categories,omitempty"`
+	KeyWaitQueueSize             *int    `json:"key_wait_queue_size,omitempty"`
+	KeyWaitTimeoutSeconds        *int    `json:"key_wait_timeout_seconds,omitempty"`
 }
 
 // HeaderRule defines a single rule for header manipulation.
@@ -52,6 +91,23 @@ type HeaderRule struct {
 	Action string `json:"action"` // "set" or "remove"
 }
 
+// KeyRoutingRule 定义按模型或代理密钥将请求限定到带有指定标签的 Key 的规则
+type KeyRoutingRule struct {
+	Model    string   `json:"model,omitempty"`     // 模型名称通配符，例如 o3*，为空匹配所有模型
+	ProxyKey string   `json:"proxy_key,omitempty"` // 代理密钥，为空匹配所有代理密钥
+	Tags     []string `json:"tags"`                // Key 必须包含的全部标签
+}
+
+// ValidationTemplate 定义分组自定义的密钥验证请求及成功判定条件
+type ValidationTemplate struct {
+	Method             string            `json:"method"`
+	Path               string            `json:"path"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	Body               string            `json:"body,omitempty"` // 支持 ${TEST_MODEL} 占位符
+	SuccessStatusCodes []int             `json:"success_status_codes,omitempty"`
+	SuccessJSONPath    string            `json:"success_json_path,omitempty"`
+}
+
 // Group 对应 groups 表
 type Group struct {
 	ID                 uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -70,6 +126,8 @@ type Group struct {
 	ParamOverrides     datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
 	Config             datatypes.JSONMap    `gorm:"type:json" json:"config"`
 	HeaderRules        datatypes.JSON       `gorm:"type:json" json:"header_rules"`
+	ValidationTemplate datatypes.JSON       `gorm:"type:json" json:"validation_template"`
+	KeyRoutingRules    datatypes.JSON       `gorm:"type:json" json:"key_routing_rules"`
 	ForceHTTP11        *bool                `gorm:"type:boolean" json:"force_http11"`
 	APIKeys            []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
 	LastValidatedAt    *time.Time           `json:"last_validated_at"`
@@ -80,23 +138,42 @@ type Group struct {
 	UpdatedAt          time.Time            `json:"updated_at"`
 
 	// For cache
-	ProxyKeysMap   map[string]struct{} `gorm:"-" json:"-"`
-	HeaderRuleList []HeaderRule        `gorm:"-" json:"-"`
+	ProxyKeysMap             map[string]struct{} `gorm:"-" json:"-"`
+	HeaderRuleList           []HeaderRule        `gorm:"-" json:"-"`
+	ValidationTemplateConfig *ValidationTemplate `gorm:"-" json:"-"`
+	KeyRoutingRuleList       []KeyRoutingRule    `gorm:"-" json:"-"`
 }
 
 // APIKey 对应 api_keys 表
 type APIKey struct {
-	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	KeyValue     string     `gorm:"type:varchar(700);not null;uniqueIndex:idx_group_key" json:"key_value"`
-	GroupID      uint       `gorm:"not null;uniqueIndex:idx_group_key" json:"group_id"`
-	Status       string     `gorm:"type:varchar(50);not null;default:'active'" json:"status"`
-	IsDisabled   bool       `gorm:"not null;default:false" json:"is_disabled"` // 手动停用标志
-	Remarks      string     `gorm:"type:varchar(500)" json:"remarks"`           // 备注信息
-	RequestCount int64      `gorm:"not null;default:0" json:"request_count"`
-	FailureCount int64      `gorm:"not null;default:0" json:"failure_count"`
-	LastUsedAt   *time.Time `json:"last_used_at"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID                 uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	KeyValue           string     `gorm:"type:text;not null" json:"key_value"`                                          // 配置主密钥时为加密后的值
+	KeyHash            string     `gorm:"type:varchar(64);not null;default:'';uniqueIndex:idx_group_key_hash" json:"-"` // 明文密钥的 SHA-256，用于按值查找
+	GroupID            uint       `gorm:"not null;uniqueIndex:idx_group_key_hash" json:"group_id"`
+	Status             string     `gorm:"type:varchar(50);not null;default:'active'" json:"status"`
+	IsDisabled         bool       `gorm:"not null;default:false" json:"is_disabled"` // 手动停用标志
+	Remarks            string     `gorm:"type:varchar(500)" json:"remarks"`          // 备注信息
+	Tags               string     `gorm:"type:varchar(1024)" json:"tags"`            // 标签，逗号分隔，例如 tier:paid,region:us
+	RequestCount       int64      `gorm:"not null;default:0" json:"request_count"`
+	FailureCount       int64      `gorm:"not null;default:0" json:"failure_count"`
+	LastUsedAt         *time.Time `json:"last_used_at"`
+	CooldownUntil      *time.Time `json:"cooldown_until"`                                // 错误规则触发的冷却截止时间
+	NextValidationAt   *time.Time `gorm:"index" json:"next_validation_at"`               // 无效密钥下次定时验证的时间
+	ValidationFailures int        `gorm:"not null;default:0" json:"validation_failures"` // 无效后连续验证失败的次数
+	LastProbedAt       *time.Time `json:"last_probed_at"`                                // 有效密钥最近一次健康探测的时间
+	SupportedModels    string     `gorm:"type:text" json:"supported_models"`             // 模型发现得到的可用模型，逗号分隔，为空表示未知
+	ModelsDiscoveredAt *time.Time `json:"models_discovered_at"`                          // 最近一次模型发现的时间
+	QuotaPeriod        string     `gorm:"type:varchar(10)" json:"quota_period"`          // 配额周期：day 或 month，为空表示不限额
+	RequestQuota       int64      `gorm:"not null;default:0" json:"request_quota"`       // 每周期请求次数上限，0 为不限制
+	TokenQuota         int64      `gorm:"not null;default:0" json:"token_quota"`         // 每周期 Token 用量上限，0 为不限制
+	ExhaustedUntil     *time.Time `json:"exhausted_until"`                               // 配额用尽后自动恢复的时间
+	ValidFrom          *time.Time `json:"valid_from"`                                    // 生效时间，之前不参与轮询
+	ExpiresAt          *time.Time `gorm:"index" json:"expires_at"`                       // 过期时间，之后不再参与轮询
+	ActiveWindows      string     `gorm:"type:varchar(255)" json:"active_windows"`       // 每天可用时段，例如 22:00-06:00，为空表示全天可用
+	Balance            *float64   `json:"balance"`                                       // 最近一次查询到的剩余余额，nil 表示未知
+	BalanceCheckedAt   *time.Time `json:"balance_checked_at"`                            // 最近一次查询余额的时间
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 // RequestType 请求类型常量
@@ -146,7 +223,9 @@ type RequestLog struct {
 	Timestamp    time.Time    `gorm:"not null;index" json:"timestamp"`
 	GroupID      uint         `gorm:"not null;index" json:"group_id"`
 	GroupName    string       `gorm:"type:varchar(255);index" json:"group_name"`
-	KeyValue     string       `gorm:"type:varchar(700)" json:"key_value"`
+	KeyID        uint         `gorm:"not null;default:0;index" json:"key_id"`
+	KeyValue     string       `gorm:"type:text" json:"key_value"` // 脱敏后的密钥预览，不保存完整密钥
+	KeyHash      string       `gorm:"type:varchar(64);index" json:"-"` // 完整密钥的 SHA-256，不在接口中返回
 	Model        string       `gorm:"type:varchar(255);index" json:"model"`
 	IsSuccess    bool         `gorm:"not null" json:"is_success"`
 	SourceIP     string       `gorm:"type:varchar(64)" json:"source_ip"`
@@ -154,6 +233,7 @@ type RequestLog struct {
 	RequestPath  string       `gorm:"type:varchar(500)" json:"request_path"`
 	Duration     int64        `gorm:"not null" json:"duration_ms"`
 	ErrorMessage string       `gorm:"type:text" json:"error_message"`
+	ErrorCategory string      `gorm:"type:varchar(50);index" json:"error_category"`
 	UserAgent    string       `gorm:"type:varchar(512)" json:"user_agent"`
 	RequestType  string       `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
 	UpstreamAddr string       `gorm:"type:varchar(500)" json:"upstream_addr"`
@@ -174,10 +254,20 @@ type StatCard struct {
 
 // DashboardStatsResponse 用于仪表盘基础统计的API响应
 type DashboardStatsResponse struct {
-	KeyCount     StatCard `json:"key_count"`
-	RPM          StatCard `json:"rpm"`
-	RequestCount StatCard `json:"request_count"`
-	ErrorRate    StatCard `json:"error_rate"`
+	KeyCount     StatCard      `json:"key_count"`
+	RPM          StatCard      `json:"rpm"`
+	RequestCount StatCard      `json:"request_count"`
+	ErrorRate    StatCard      `json:"error_rate"`
+	ExpiringKeys []ExpiringKey `json:"expiring_keys"`
+}
+
+// ExpiringKey 即将过期的密钥，用于仪表盘提醒
+type ExpiringKey struct {
+	ID        uint      `json:"id"`
+	GroupID   uint      `json:"group_id"`
+	GroupName string    `json:"group_name"`
+	KeyValue  string    `json:"key_value"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // ChartDataset 用于图表的数据集
@@ -202,6 +292,32 @@ type Category struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// 错误规则动作
+const (
+	ErrorRuleActionCount    = "count"    // 计入失败次数
+	ErrorRuleActionIgnore   = "ignore"   // 不计入失败次数
+	ErrorRuleActionDisable  = "disable"  // 永久停用密钥
+	ErrorRuleActionCooldown = "cooldown" // 密钥冷却指定秒数
+	ErrorRuleActionNoRetry  = "no_retry" // 不计数且不再重试
+	ErrorRuleActionRetire   = "retire"   // 永久退役密钥
+)
+
+// ErrorRule 对应 error_rules 表，定义上游错误的处理规则
+type ErrorRule struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID         *uint     `gorm:"index" json:"group_id"` // 为空表示全局规则
+	Name            string    `gorm:"type:varchar(255)" json:"name"`
+	StatusCode      int       `gorm:"not null;default:0" json:"status_code"`    // 0 表示任意状态码
+	MessagePattern  string    `gorm:"type:varchar(500)" json:"message_pattern"` // 匹配解析后错误信息的正则（忽略大小写），为空表示任意
+	ChannelType     string    `gorm:"type:varchar(50)" json:"channel_type"`     // 为空表示任意渠道
+	Action          string    `gorm:"type:varchar(20);not null" json:"action"`
+	CooldownSeconds int       `gorm:"not null;default:0" json:"cooldown_seconds"`
+	Sort            int       `gorm:"default:0" json:"sort"`
+	Enabled         bool      `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
 // GroupHourlyStat 对应 group_hourly_stats 表，用于存储每个分组每小时的请求统计
 type GroupHourlyStat struct {
 	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
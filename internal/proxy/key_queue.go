@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/limiter"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// keyWaitPollInterval 是排队请求重新尝试选择密钥的间隔
+const keyWaitPollInterval = 500 * time.Millisecond
+
+// keyWaiter 是一个正在等待可用密钥的请求，handoff 用于接收其他请求转交的密钥
+type keyWaiter struct {
+	priority limiter.Priority
+	criteria keypool.KeyCriteria
+	handoff  chan *models.APIKey
+}
+
+// keyWaitQueue 按到达顺序记录每个分组中等待可用密钥的请求
+type keyWaitQueue struct {
+	mu      sync.Mutex
+	waiting map[uint][]*keyWaiter
+}
+
+func newKeyWaitQueue() *keyWaitQueue {
+	return &keyWaitQueue{waiting: make(map[uint][]*keyWaiter)}
+}
+
+// enter 尝试进入分组的等待队列，队列已满时返回 nil
+func (q *keyWaitQueue) enter(groupID uint, priority limiter.Priority, criteria keypool.KeyCriteria, limit int) *keyWaiter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting[groupID]) >= limit {
+		return nil
+	}
+	w := &keyWaiter{priority: priority, criteria: criteria, handoff: make(chan *models.APIKey, 1)}
+	q.waiting[groupID] = append(q.waiting[groupID], w)
+	return w
+}
+
+// leave 离开分组的等待队列。已经有请求转交了密钥时返回该密钥
+func (q *keyWaitQueue) leave(groupID uint, w *keyWaiter) *models.APIKey {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.remove(groupID, w) {
+		return nil
+	}
+	select {
+	case apiKey := <-w.handoff:
+		return apiKey
+	default:
+		return nil
+	}
+}
+
+// handOff 把 from 选中的密钥转交给同分组中优先级更高、且条件允许使用该密钥的最早的等待者。
+// 条件不同的高优先级请求不会阻止低优先级请求使用只有自己能用的密钥
+func (q *keyWaitQueue) handOff(groupID uint, from *keyWaiter, apiKey *models.APIKey) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var target *keyWaiter
+	for _, w := range q.waiting[groupID] {
+		if w.priority < from.priority && (target == nil || w.priority < target.priority) && w.criteria.Accepts(apiKey) {
+			target = w
+		}
+	}
+	if target == nil {
+		return false
+	}
+	q.remove(groupID, target)
+	target.handoff <- apiKey
+	return true
+}
+
+// remove 在持有锁时移除等待者，等待者已不在队列中时返回 false
+func (q *keyWaitQueue) remove(groupID uint, w *keyWaiter) bool {
+	waiters := q.waiting[groupID]
+	i := slices.Index(waiters, w)
+	if i < 0 {
+		return false
+	}
+	waiters = slices.Delete(waiters, i, i+1)
+	if len(waiters) == 0 {
+		delete(q.waiting, groupID)
+	} else {
+		q.waiting[groupID] = waiters
+	}
+	return true
+}
+
+// isKeyUnavailable 判断选择失败是否因为暂时没有可用或满足条件的密钥，满足条件的密钥可能正在冷却、不在可用时段或尚未被扫描到
+func isKeyUnavailable(err error) bool {
+	return errors.Is(err, app_errors.ErrNoActiveKeys) || errors.Is(err, app_errors.ErrNoMatchingKeys)
+}
+
+// selectKeyWithWait 从密钥池中选择满足 criteria 的密钥，没有可用密钥时在分组队列中等待，直到超时或请求被取消。
+// 排队期间每个请求都会轮询，低优先级请求选到的密钥如果也满足同分组中等待的高优先级请求，会先交给高优先级请求。
+func (ps *ProxyServer) selectKeyWithWait(c *gin.Context, group *models.Group, criteria keypool.KeyCriteria) (*models.APIKey, error) {
+	apiKey, err := ps.keyProvider.SelectKey(group.ID, criteria)
+	if err == nil || !isKeyUnavailable(err) {
+		return apiKey, err
+	}
+
+	priority := limiter.PriorityInteractive
+	if p, exists := c.Get(middleware.RequestPriorityKey); exists {
+		priority = p.(limiter.Priority)
+	}
+
+	cfg := group.EffectiveConfig
+	if cfg.KeyWaitTimeoutSeconds <= 0 {
+		return nil, err
+	}
+	waiter := ps.keyWaitQueue.enter(group.ID, priority, criteria, cfg.KeyWaitQueueSize)
+	if waiter == nil {
+		return nil, err
+	}
+
+	logrus.Debugf("No available key for group %s, %s request waiting up to %d seconds", group.Name, priority, cfg.KeyWaitTimeoutSeconds)
+
+	deadline := time.NewTimer(time.Duration(cfg.KeyWaitTimeoutSeconds) * time.Second)
+	defer deadline.Stop()
+	ticker := time.NewTicker(keyWaitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			ps.keyWaitQueue.leave(group.ID, waiter)
+			return nil, c.Request.Context().Err()
+		case <-deadline.C:
+			// 超时的同时恰好有请求转交了密钥时仍然使用该密钥
+			if handed := ps.keyWaitQueue.leave(group.ID, waiter); handed != nil {
+				return handed, nil
+			}
+			return nil, err
+		case handed := <-waiter.handoff:
+			return handed, nil
+		case <-ticker.C:
+			apiKey, err = ps.keyProvider.SelectKey(group.ID, criteria)
+			if err != nil && isKeyUnavailable(err) {
+				continue
+			}
+			// 选中的密钥也能满足更高优先级的等待者时先交给对方，自己继续等待
+			if err == nil && ps.keyWaitQueue.handOff(group.ID, waiter, apiKey) {
+				continue
+			}
+			ps.keyWaitQueue.leave(group.ID, waiter)
+			return apiKey, err
+		}
+	}
+}
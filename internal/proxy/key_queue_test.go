@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"testing"
+
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/limiter"
+	"gpt-load/internal/models"
+)
+
+func TestKeyWaitQueueEnter(t *testing.T) {
+	q := newKeyWaitQueue()
+	first := q.enter(1, limiter.PriorityBatch, keypool.KeyCriteria{}, 2)
+	second := q.enter(1, limiter.PriorityInteractive, keypool.KeyCriteria{}, 2)
+	if first == nil || second == nil {
+		t.Fatal("enter() rejected a waiter below the limit")
+	}
+	if q.enter(1, limiter.PriorityInteractive, keypool.KeyCriteria{}, 2) != nil {
+		t.Error("enter() accepted a waiter above the limit")
+	}
+	if q.enter(2, limiter.PriorityBatch, keypool.KeyCriteria{}, 2) == nil {
+		t.Error("the limit of one group affected another group")
+	}
+
+	q.leave(1, first)
+	if q.enter(1, limiter.PriorityBatch, keypool.KeyCriteria{}, 2) == nil {
+		t.Error("enter() rejected a waiter after another one left")
+	}
+}
+
+func TestKeyWaitQueueHandOff(t *testing.T) {
+	gpt4Key := &models.APIKey{ID: 1, SupportedModels: "gpt-4o,gpt-4o-mini", Tags: "tier:paid"}
+
+	tests := []struct {
+		name      string
+		waiting   []*keyWaiter
+		from      limiter.Priority
+		wantIndex int // 期望收到密钥的等待者，-1 表示不转交
+	}{
+		{
+			name:      "no other waiters",
+			from:      limiter.PriorityBatch,
+			wantIndex: -1,
+		},
+		{
+			name:      "higher priority waiter with the same criteria",
+			waiting:   []*keyWaiter{{priority: limiter.PriorityInteractive}},
+			from:      limiter.PriorityBatch,
+			wantIndex: 0,
+		},
+		{
+			name:      "higher priority waiter for another model does not block",
+			waiting:   []*keyWaiter{{priority: limiter.PriorityInteractive, criteria: keypool.KeyCriteria{Model: "claude-3-opus"}}},
+			from:      limiter.PriorityBatch,
+			wantIndex: -1,
+		},
+		{
+			name:      "higher priority waiter for another tag does not block",
+			waiting:   []*keyWaiter{{priority: limiter.PriorityInteractive, criteria: keypool.KeyCriteria{Tags: []string{"region:eu"}}}},
+			from:      limiter.PriorityBackground,
+			wantIndex: -1,
+		},
+		{
+			name: "highest priority matching waiter wins",
+			waiting: []*keyWaiter{
+				{priority: limiter.PriorityBatch},
+				{priority: limiter.PriorityInteractive, criteria: keypool.KeyCriteria{Model: "claude-3-opus"}},
+				{priority: limiter.PriorityInteractive, criteria: keypool.KeyCriteria{Model: "gpt-4o", Tags: []string{"tier:paid"}}},
+				{priority: limiter.PriorityInteractive},
+			},
+			from:      limiter.PriorityBackground,
+			wantIndex: 2,
+		},
+		{
+			name:      "same priority waiter does not take the key",
+			waiting:   []*keyWaiter{{priority: limiter.PriorityBatch}},
+			from:      limiter.PriorityBatch,
+			wantIndex: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newKeyWaitQueue()
+			var waiters []*keyWaiter
+			for _, w := range tt.waiting {
+				waiters = append(waiters, q.enter(1, w.priority, w.criteria, 10))
+			}
+			from := q.enter(1, tt.from, keypool.KeyCriteria{}, 10)
+
+			handed := q.handOff(1, from, gpt4Key)
+			if handed != (tt.wantIndex >= 0) {
+				t.Fatalf("handOff() = %t, want %t", handed, tt.wantIndex >= 0)
+			}
+			for i, w := range waiters {
+				got := q.leave(1, w)
+				if i == tt.wantIndex && got != gpt4Key {
+					t.Errorf("waiter %d did not receive the key", i)
+				}
+				if i != tt.wantIndex && got != nil {
+					t.Errorf("waiter %d unexpectedly received the key", i)
+				}
+			}
+			if q.leave(1, from) != nil {
+				t.Error("the waiter that handed off the key received a key")
+			}
+			if len(q.waiting) != 0 {
+				t.Errorf("queue not empty after all waiters left: %v", q.waiting)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"path"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyCriteria 根据模型发现设置和分组的 Key 路由规则生成本次请求的选 Key 条件
+func keyCriteria(c *gin.Context, channelHandler channel.ChannelProxy, group *models.Group, bodyBytes []byte) keypool.KeyCriteria {
+	discovery := group.EffectiveConfig.KeyModelDiscoveryHours > 0
+	if !discovery && len(group.KeyRoutingRuleList) == 0 {
+		return keypool.KeyCriteria{}
+	}
+
+	model := channelHandler.ExtractModel(c, bodyBytes)
+
+	var criteria keypool.KeyCriteria
+	if discovery {
+		criteria.Model = model
+	}
+	if rule := matchKeyRoutingRule(group.KeyRoutingRuleList, model, middleware.ProxyKey(c)); rule != nil {
+		criteria.Tags = rule.Tags
+	}
+	return criteria
+}
+
+// matchKeyRoutingRule 按顺序返回第一条匹配的规则
+func matchKeyRoutingRule(rules []models.KeyRoutingRule, model, proxyKey string) *models.KeyRoutingRule {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ProxyKey != "" && rule.ProxyKey != proxyKey {
+			continue
+		}
+		if rule.Model != "" {
+			if matched, _ := path.Match(rule.Model, model); !matched {
+				continue
+			}
+		}
+		return rule
+	}
+	return nil
+}
@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/middleware"
 	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/tokenizer"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,6 +37,94 @@ func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, group *models.Group
 	return json.Marshal(requestData)
 }
 
+// ContextLengthRouter creates a middleware that estimates prompt tokens before the rate limiter runs.
+// Oversized requests are routed to the configured long-context group by rewriting the group_name
+// parameter, so the concurrency limits and handler of the target group apply. Single-key requests are not routed.
+func (ps *ProxyServer) ContextLengthRouter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Param("path"), "/id_") {
+			c.Next()
+			return
+		}
+
+		group, err := ps.groupManager.GetGroupByName(c.Param("group_name"))
+		if err != nil || group.EffectiveConfig.MaxPromptTokens <= 0 {
+			c.Next()
+			return
+		}
+
+		startTime := time.Now()
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logrus.Errorf("Failed to read request body: %v", err)
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+		routedGroup, routeErr := ps.routeByContextLength(group, bodyBytes, middleware.ProxyKey(c))
+		if routeErr != nil {
+			response.Error(c, routeErr)
+			ps.logRequest(c, group, nil, startTime, routeErr.HTTPStatus, routeErr, false, "", nil, bodyBytes, models.RequestTypeFinal, "")
+			c.Abort()
+			return
+		}
+
+		if routedGroup != group {
+			for i := range c.Params {
+				if c.Params[i].Key == "group_name" {
+					c.Params[i].Value = routedGroup.Name
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// routeByContextLength estimates prompt tokens before forwarding.
+// Oversized requests are routed to the configured long-context group, or rejected when none is available
+// or the proxy key of the request is not valid for it.
+func (ps *ProxyServer) routeByContextLength(group *models.Group, bodyBytes []byte, proxyKey string) (*models.Group, *app_errors.APIError) {
+	limit := group.EffectiveConfig.MaxPromptTokens
+	if limit <= 0 {
+		return group, nil
+	}
+
+	estimated := tokenizer.EstimatePromptTokens(group.ChannelType, bodyBytes)
+	if estimated <= limit {
+		return group, nil
+	}
+
+	tooLongErr := app_errors.NewAPIError(app_errors.ErrContextTooLong, fmt.Sprintf("Estimated prompt tokens (%d) exceed the limit (%d) of group '%s'", estimated, limit, group.Name))
+
+	target := group.EffectiveConfig.LongContextGroup
+	if target == "" || target == group.Name {
+		return nil, tooLongErr
+	}
+
+	longGroup, err := ps.groupManager.GetGroupByName(target)
+	if err != nil {
+		logrus.Warnf("Long context group '%s' of group '%s' not found: %v", target, group.Name, err)
+		return nil, tooLongErr
+	}
+	if longGroup.ChannelType != group.ChannelType {
+		logrus.Warnf("Long context group '%s' has channel type %s, expected %s", target, longGroup.ChannelType, group.ChannelType)
+		return nil, tooLongErr
+	}
+	if !middleware.IsValidProxyKey(longGroup, proxyKey) {
+		logrus.Debugf("Proxy key of the request is not valid for long context group '%s', not routing", target)
+		return nil, tooLongErr
+	}
+	if longLimit := longGroup.EffectiveConfig.MaxPromptTokens; longLimit > 0 && estimated > longLimit {
+		return nil, app_errors.NewAPIError(app_errors.ErrContextTooLong, fmt.Sprintf("Estimated prompt tokens (%d) exceed the limit (%d) of long context group '%s'", estimated, longLimit, longGroup.Name))
+	}
+
+	logrus.Debugf("Routing request with ~%d prompt tokens from group %s to long context group %s", estimated, group.Name, longGroup.Name)
+	return longGroup, nil
+}
+
 // logUpstreamError provides a centralized way to log errors from upstream interactions.
 func logUpstreamError(context string, err error) {
 	if err == nil {
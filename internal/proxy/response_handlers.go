@@ -4,16 +4,18 @@ import (
 	"bytes"
 	"io"
 	"net/http"
-	"strings"
 
 	"gpt-load/internal/channel"
 	"gpt-load/internal/models"
+	"gpt-load/internal/tokenizer"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, group *models.Group) (string, *models.StreamContent) {
+// handleStreamingResponse 把上游流式响应转发给客户端，返回用于日志的完整响应体、解析出的流式内容，
+// 以及从完整的流中提取的 Token 用量。响应体由日志服务脱敏后再截断
+func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, group *models.Group) (string, *models.StreamContent, int64) {
 	logrus.Debugf("【插桩日志】handleStreamingResponse开始，组: %s, 响应状态: %d", group.Name, resp.StatusCode)
 	
 	c.Header("Content-Type", "text/event-stream")
@@ -24,13 +26,11 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
-		responseBody, _ := ps.handleNormalResponse(c, resp, group)
-		return responseBody, nil
+		responseBody, _, usageTokens := ps.handleNormalResponse(c, resp, group)
+		return responseBody, nil, usageTokens
 	}
 
-	var responseBuffer strings.Builder
 	var parseBuffer bytes.Buffer
-	maxSize := group.EffectiveConfig.MaxResponseBodyLogSize
 
 	buf := make([]byte, 4*1024)
 	for {
@@ -44,18 +44,7 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 				break
 			}
 			
-			// Record to buffer for logging within size limit
-			if responseBuffer.Len() < maxSize {
-				remainingSpace := maxSize - responseBuffer.Len()
-				if len(chunk) <= remainingSpace {
-					responseBuffer.Write(chunk)
-				} else {
-					// Write partial chunk to stay within limit
-					responseBuffer.Write(chunk[:remainingSpace])
-				}
-			}
-
-			// Always record for stream parsing
+			// Record for stream parsing and logging
 			parseBuffer.Write(chunk)
 			
 			flusher.Flush()
@@ -95,29 +84,26 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 		logrus.Debug("解析缓冲区为空，跳过流式内容解析")
 	}
 
-	response := responseBuffer.String()
-	// Add truncation indicator if response was cut off
-	if responseBuffer.Len() >= maxSize {
-		response += "\n[TRUNCATED: Response exceeded maximum log size]"
-	}
 	// 处理流式响应的gzip压缩问题
 	// 检查响应是否使用了gzip压缩，如果是则解压后记录到日志
+	response := parseBuffer.String()
 	if resp.Header.Get("Content-Encoding") == "gzip" && len(response) > 0 {
-		decompressedResponse := handleGzipCompression(resp, []byte(response))
-		logrus.Debugf("【插桩日志】handleStreamingResponse结束，经过gzip解压，最终响应体长度: %d", len(decompressedResponse))
-		return string(decompressedResponse), streamContent
+		response = string(handleGzipCompression(resp, parseBuffer.Bytes()))
+		logrus.Debugf("【插桩日志】handleStreamingResponse结束，经过gzip解压，最终响应体长度: %d", len(response))
+	} else {
+		logrus.Debugf("【插桩日志】handleStreamingResponse结束，最终响应体长度: %d", len(response))
 	}
-	logrus.Debugf("【插桩日志】handleStreamingResponse结束，最终响应体长度: %d", len(response))
-	return response, streamContent
+	return response, streamContent, tokenizer.ExtractUsageTokens(response)
 }
 
-func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response, group *models.Group) (string, *models.StreamContent) {
+// handleNormalResponse 把上游响应转发给客户端，返回用于日志的完整响应体和从中提取的 Token 用量。响应体由日志服务脱敏后再截断
+func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response, group *models.Group) (string, *models.StreamContent, int64) {
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logUpstreamError("reading response body", err)
 		c.String(http.StatusInternalServerError, "Failed to read response body")
-		return "", nil
+		return "", nil, 0
 	}
 
 	// 如果上游使用了 gzip 压缩，则解压后再参与日志记录与返回
@@ -129,13 +115,6 @@ func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response,
 	}
 
 	// Return response content for logging
-	maxSize := group.EffectiveConfig.MaxResponseBodyLogSize
-	if len(decompressed) <= maxSize {
-		return string(decompressed), nil
-	} else {
-		// Truncate if too large
-		truncated := string(decompressed[:maxSize])
-		truncated += "\n[TRUNCATED: Response exceeded maximum log size]"
-		return truncated, nil
-	}
+	response := string(decompressed)
+	return response, nil, tokenizer.ExtractUsageTokens(response)
 }
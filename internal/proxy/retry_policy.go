@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+)
+
+// retryPolicy 描述分组的重试策略，由分组的有效配置生成
+type retryPolicy struct {
+	maxRetries    int
+	baseInterval  time.Duration
+	maxInterval   time.Duration
+	jitter        bool
+	maxTotal      time.Duration
+	sameKey       bool
+	statusCodes   map[int]struct{}
+	statusClasses map[int]struct{}
+	categories    map[string]struct{}
+}
+
+// newRetryPolicy builds a retry policy from the effective group configuration.
+func newRetryPolicy(cfg types.SystemSettings) *retryPolicy {
+	p := &retryPolicy{
+		maxRetries:    cfg.MaxRetries,
+		baseInterval:  time.Duration(cfg.RetryIntervalMs) * time.Millisecond,
+		maxInterval:   time.Duration(cfg.RetryMaxIntervalMs) * time.Millisecond,
+		jitter:        cfg.RetryJitter,
+		maxTotal:      time.Duration(cfg.RetryMaxTotalSeconds) * time.Second,
+		sameKey:       cfg.RetrySameKey,
+		statusCodes:   make(map[int]struct{}),
+		statusClasses: make(map[int]struct{}),
+		categories:    utils.StringToSet(cfg.RetryErrorCategories, ","),
+	}
+
+	for _, item := range utils.SplitAndTrim(cfg.RetryStatusCodes, ",") {
+		item = strings.ToLower(item)
+		if len(item) == 3 && strings.HasSuffix(item, "xx") {
+			if class, err := strconv.Atoi(item[:1]); err == nil {
+				p.statusClasses[class] = struct{}{}
+			}
+			continue
+		}
+		if code, err := strconv.Atoi(item); err == nil {
+			p.statusCodes[code] = struct{}{}
+		}
+	}
+
+	return p
+}
+
+// isRetryable reports whether a classified upstream failure should be retried,
+// either because its category or its status code is configured as retryable.
+func (p *retryPolicy) isRetryable(upstreamErr *app_errors.UpstreamError) bool {
+	if _, ok := p.categories[upstreamErr.Category]; ok {
+		return true
+	}
+	return upstreamErr.StatusCode > 0 && p.isRetryableStatus(upstreamErr.StatusCode)
+}
+
+// isRetryableStatus reports whether an upstream status code should be retried.
+func (p *retryPolicy) isRetryableStatus(statusCode int) bool {
+	if _, ok := p.statusCodes[statusCode]; ok {
+		return true
+	}
+	_, ok := p.statusClasses[statusCode/100]
+	return ok
+}
+
+// backoff 计算第 retryCount 次失败后的等待时间：按指数翻倍，不超过上限，可选随机抖动
+func (p *retryPolicy) backoff(retryCount int) time.Duration {
+	if p.baseInterval <= 0 {
+		return 0
+	}
+
+	delay := p.baseInterval
+	for range retryCount {
+		delay *= 2
+		if p.maxInterval > 0 && delay >= p.maxInterval {
+			delay = p.maxInterval
+			break
+		}
+	}
+	if p.maxInterval > 0 && delay > p.maxInterval {
+		delay = p.maxInterval
+	}
+
+	if p.jitter && delay > 1 {
+		half := delay / 2
+		delay = half + rand.N(delay-half)
+	}
+	return delay
+}
+
+// canRetry 判断在已用时间和下一次等待后是否仍允许重试
+func (p *retryPolicy) canRetry(retryCount int, startTime time.Time, delay time.Duration) bool {
+	if retryCount >= p.maxRetries {
+		return false
+	}
+	if p.maxTotal > 0 && time.Since(startTime)+delay >= p.maxTotal {
+		return false
+	}
+	return true
+}
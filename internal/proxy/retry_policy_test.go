@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/types"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseMs      int
+		maxMs       int
+		retryCounts []int
+		want        []time.Duration
+	}{
+		{
+			name:        "doubles per retry",
+			baseMs:      100,
+			maxMs:       0,
+			retryCounts: []int{0, 1, 2, 3},
+			want:        []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:        "capped at the maximum interval",
+			baseMs:      100,
+			maxMs:       300,
+			retryCounts: []int{0, 1, 2, 50},
+			want:        []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		},
+		{
+			name:        "base above the maximum is capped",
+			baseMs:      1000,
+			maxMs:       500,
+			retryCounts: []int{0},
+			want:        []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:        "equal base and maximum is a fixed interval",
+			baseMs:      250,
+			maxMs:       250,
+			retryCounts: []int{0, 1, 5},
+			want:        []time.Duration{250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond},
+		},
+		{
+			name:        "zero base disables waiting",
+			baseMs:      0,
+			maxMs:       5000,
+			retryCounts: []int{0, 3},
+			want:        []time.Duration{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newRetryPolicy(types.SystemSettings{RetryIntervalMs: tt.baseMs, RetryMaxIntervalMs: tt.maxMs})
+			for i, retryCount := range tt.retryCounts {
+				if got := p.backoff(retryCount); got != tt.want[i] {
+					t.Errorf("backoff(%d) = %v, want %v", retryCount, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	p := newRetryPolicy(types.SystemSettings{RetryIntervalMs: 100, RetryMaxIntervalMs: 1000, RetryJitter: true})
+	for range 100 {
+		if got := p.backoff(2); got < 200*time.Millisecond || got >= 400*time.Millisecond {
+			t.Fatalf("backoff(2) with jitter = %v, want within [200ms, 400ms)", got)
+		}
+	}
+}
+
+func TestRetryPolicyIsRetryable(t *testing.T) {
+	p := newRetryPolicy(types.SystemSettings{
+		RetryStatusCodes:     "408, 429 ,5XX,invalid,4x",
+		RetryErrorCategories: "network,rate_limited",
+	})
+
+	tests := []struct {
+		name string
+		err  *app_errors.UpstreamError
+		want bool
+	}{
+		{name: "listed status code", err: &app_errors.UpstreamError{StatusCode: 408, Category: app_errors.ErrorCategoryUnknown}, want: true},
+		{name: "status class", err: &app_errors.UpstreamError{StatusCode: 503, Category: app_errors.ErrorCategoryUnknown}, want: true},
+		{name: "unlisted status code", err: &app_errors.UpstreamError{StatusCode: 400, Category: app_errors.ErrorCategoryBadRequest}, want: false},
+		{name: "retryable category without status", err: &app_errors.UpstreamError{Category: app_errors.ErrorCategoryNetwork}, want: true},
+		{name: "retryable category with unlisted status", err: &app_errors.UpstreamError{StatusCode: 400, Category: app_errors.ErrorCategoryRateLimited}, want: true},
+		{name: "invalid entries are ignored", err: &app_errors.UpstreamError{StatusCode: 404, Category: app_errors.ErrorCategoryUnknown}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%+v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyCanRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		maxTotal   int
+		retryCount int
+		elapsed    time.Duration
+		delay      time.Duration
+		want       bool
+	}{
+		{name: "below max retries", maxRetries: 3, retryCount: 2, want: true},
+		{name: "max retries reached", maxRetries: 3, retryCount: 3, want: false},
+		{name: "retries disabled", maxRetries: 0, retryCount: 0, want: false},
+		{name: "within total budget", maxRetries: 3, maxTotal: 10, elapsed: 5 * time.Second, delay: time.Second, want: true},
+		{name: "delay would exceed total budget", maxRetries: 3, maxTotal: 10, elapsed: 9 * time.Second, delay: 2 * time.Second, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newRetryPolicy(types.SystemSettings{MaxRetries: tt.maxRetries, RetryMaxTotalSeconds: tt.maxTotal})
+			if got := p.canRetry(tt.retryCount, time.Now().Add(-tt.elapsed), tt.delay); got != tt.want {
+				t.Errorf("canRetry() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -33,6 +32,8 @@ type ProxyServer struct {
 	settingsManager   *config.SystemSettingsManager
 	channelFactory    *channel.Factory
 	requestLogService *services.RequestLogService
+	errorRules        *keypool.ErrorRuleManager
+	keyWaitQueue      *keyWaitQueue
 }
 
 // NewProxyServer creates a new proxy server
@@ -42,6 +43,7 @@ func NewProxyServer(
 	settingsManager *config.SystemSettingsManager,
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
+	errorRules *keypool.ErrorRuleManager,
 ) (*ProxyServer, error) {
 	return &ProxyServer{
 		keyProvider:       keyProvider,
@@ -49,6 +51,8 @@ func NewProxyServer(
 		settingsManager:   settingsManager,
 		channelFactory:    channelFactory,
 		requestLogService: requestLogService,
+		errorRules:        errorRules,
+		keyWaitQueue:      newKeyWaitQueue(),
 	}, nil
 }
 
@@ -83,21 +87,12 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		actualPath = path
 	}
 
-	// 临时修改 gin.Context 的路径参数，以便后续处理使用正确的路径
-	c.Request.URL.Path = "/proxy/" + groupName + actualPath
-
 	group, err := ps.groupManager.GetGroupByName(groupName)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
 	}
 
-	channelHandler, err := ps.channelFactory.GetChannel(group)
-	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", groupName, err)))
-		return
-	}
-
 	bodyBytes, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		logrus.Errorf("Failed to read request body: %v", err)
@@ -106,6 +101,15 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 	}
 	c.Request.Body.Close()
 
+	// 临时修改 gin.Context 的路径参数，以便后续处理使用正确的路径
+	c.Request.URL.Path = "/proxy/" + group.Name + actualPath
+
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", group.Name, err)))
+		return
+	}
+
 	finalBodyBytes, err := ps.applyParamOverrides(bodyBytes, group)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply parameter overrides: %v", err)))
@@ -114,7 +118,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 
 	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
 
-	ps.executeRequestWithRetry(c, channelHandler, group, finalBodyBytes, isStream, startTime, 0, isSpecificKey, specificKeyID)
+	ps.executeRequestWithRetry(c, channelHandler, group, finalBodyBytes, isStream, startTime, 0, isSpecificKey, specificKeyID, nil)
 }
 
 // executeRequestWithRetry is the core recursive function for handling requests and retries.
@@ -128,13 +132,18 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	retryCount int,
 	isSpecificKey bool,
 	specificKeyID uint,
+	retryKey *models.APIKey,
 ) {
 	cfg := group.EffectiveConfig
+	policy := newRetryPolicy(cfg)
 
 	var apiKey *models.APIKey
 	var err error
 
-	if isSpecificKey {
+	if retryKey != nil {
+		// 重试策略要求使用同一密钥
+		apiKey = retryKey
+	} else if isSpecificKey {
 		// 使用指定的密钥ID
 		apiKey, err = ps.keyProvider.SelectKeyByID(group.ID, specificKeyID)
 		if err != nil {
@@ -148,8 +157,8 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 	} else {
-		// 使用密钥池轮询
-		apiKey, err = ps.keyProvider.SelectKey(group.ID)
+		// 使用密钥池轮询，只选择满足模型和路由规则的密钥，无可用密钥时排队等待
+		apiKey, err = ps.selectKeyWithWait(c, group, keyCriteria(c, channelHandler, group, bodyBytes))
 		if err != nil {
 			logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
 			response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
@@ -210,22 +219,16 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		defer resp.Body.Close()
 	}
 
-	// Unified error handling for retries. Exclude 404 from being a retryable error.
+	// Unified error handling for upstream failures. Exclude 404 from being treated as a key failure.
 	if err != nil || (resp != nil && resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
-		if err != nil && app_errors.IsIgnorableError(err) {
-			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
-			ps.logRequest(c, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, "")
-			return
-		}
-
 		var statusCode int
 		var errorMessage string
-		var parsedError string
+		var upstreamErr *app_errors.UpstreamError
 
 		if err != nil {
 			statusCode = 500
 			errorMessage = err.Error()
-			parsedError = errorMessage
+			upstreamErr = app_errors.NewNetworkError(err)
 			logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
 		} else {
 			// HTTP-level error (status >= 400)
@@ -238,12 +241,22 @@ func (ps *ProxyServer) executeRequestWithRetry(
 
 			errorBody = handleGzipCompression(resp, errorBody)
 			errorMessage = string(errorBody)
-			parsedError = app_errors.ParseUpstreamError(errorBody)
-			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
+			upstreamErr = app_errors.NewUpstreamError(statusCode, errorBody)
+			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Category: %s, Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), upstreamErr.Category, upstreamErr.Message)
+		}
+
+		rule := ps.errorRules.Match(group, upstreamErr)
+		noRetry := rule != nil && rule.Action == models.ErrorRuleActionNoRetry
+
+		// 客户端断开等不重试的网络错误，直接终止且不影响密钥状态
+		if err != nil && noRetry {
+			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+			ps.logRequest(c, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, "")
+			return
 		}
 
-		// 使用解析后的错误信息更新密钥状态
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
+		// 根据错误规则或错误分类更新密钥状态
+		ps.keyProvider.UpdateStatus(apiKey, group, false, upstreamErr)
 
 		// 单密钥模式下不进行重试，直接返回错误
 		if isSpecificKey {
@@ -253,18 +266,20 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			} else {
 				response.Error(c, app_errors.NewAPIErrorWithUpstream(statusCode, "UPSTREAM_ERROR", errorMessage))
 			}
-			ps.logRequest(c, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, "")
+			ps.logRequest(c, group, apiKey, startTime, statusCode, upstreamErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, "")
 			return
 		}
 
-		// 判断是否为最后一次尝试
-		isLastAttempt := retryCount >= cfg.MaxRetries
+		// 根据错误规则和重试策略判断是否为最后一次尝试：按错误分类或状态码判断是否可重试
+		retryable := policy.isRetryable(upstreamErr)
+		delay := policy.backoff(retryCount)
+		isLastAttempt := noRetry || !retryable || !policy.canRetry(retryCount, startTime, delay)
 		requestType := models.RequestTypeRetry
 		if isLastAttempt {
 			requestType = models.RequestTypeFinal
 		}
 
-		ps.logRequest(c, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType, "")
+		ps.logRequest(c, group, apiKey, startTime, statusCode, upstreamErr, isStream, upstreamURL, channelHandler, bodyBytes, requestType, "")
 
 		// 如果是最后一次尝试，直接返回错误，不再递归
 		if isLastAttempt {
@@ -277,17 +292,27 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
-		// 添加重试间隔等待
-		if cfg.RetryIntervalMs > 0 {
-			logrus.Debugf("Waiting %d ms before retry attempt %d", cfg.RetryIntervalMs, retryCount+2)
-			time.Sleep(time.Duration(cfg.RetryIntervalMs) * time.Millisecond)
+		// 按退避策略等待后重试
+		if delay > 0 {
+			logrus.Debugf("Waiting %v before retry attempt %d", delay, retryCount+2)
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				logrus.Debugf("Client canceled request while waiting to retry: %v", c.Request.Context().Err())
+				return
+			}
 		}
 
-		ps.executeRequestWithRetry(c, channelHandler, group, bodyBytes, isStream, startTime, retryCount+1, isSpecificKey, specificKeyID)
+		var nextKey *models.APIKey
+		if policy.sameKey {
+			nextKey = apiKey
+		}
+		ps.executeRequestWithRetry(c, channelHandler, group, bodyBytes, isStream, startTime, retryCount+1, isSpecificKey, specificKeyID, nextKey)
 		return
 	}
 
 	// ps.keyProvider.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
+	ps.keyProvider.RecordSuccess(apiKey, group)
 	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
 
 	for key, values := range resp.Header {
@@ -301,16 +326,19 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	
 	var responseBody string
 	var streamContent *models.StreamContent
+	var usageTokens int64
 	if isStream {
 		logrus.Debugf("【插桩日志】调用handleStreamingResponse处理流式响应")
-		responseBody, streamContent = ps.handleStreamingResponse(c, resp, group)
+		responseBody, streamContent, usageTokens = ps.handleStreamingResponse(c, resp, group)
 		logrus.Debugf("【插桩日志】handleStreamingResponse完成，响应体长度: %d", len(responseBody))
 	} else {
 		logrus.Debugf("【插桩日志】调用handleNormalResponse处理普通响应")
-		responseBody, _ = ps.handleNormalResponse(c, resp, group)
+		responseBody, _, usageTokens = ps.handleNormalResponse(c, resp, group)
 		logrus.Debugf("【插桩日志】handleNormalResponse完成，响应体长度: %d", len(responseBody))
 	}
 
+	ps.keyProvider.RecordUsage(apiKey, group, usageTokens)
+
 	logrus.Debugf("【插桩日志】准备记录请求日志，响应体长度: %d, 流式内容: %v", len(responseBody), streamContent != nil)
 	ps.logRequestWithStreamContent(c, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, responseBody, streamContent)
 	logrus.Debugf("【插桩日志】请求日志记录完成")
@@ -337,7 +365,8 @@ func (ps *ProxyServer) logRequest(
 
 	var requestBodyToLog, userAgent string
 
-	requestBodyToLog = utils.TruncateString(string(bodyBytes), group.EffectiveConfig.MaxRequestBodyLogSize)
+	// 请求体和响应体由 RequestLogService 脱敏后再按日志大小上限截断
+	requestBodyToLog = string(bodyBytes)
 	userAgent = c.Request.UserAgent()
 
 	duration := time.Since(startTime).Milliseconds()
@@ -355,7 +384,7 @@ func (ps *ProxyServer) logRequest(
 		IsStream:     isStream,
 		UpstreamAddr: utils.TruncateString(upstreamAddr, 500),
 		RequestBody:  requestBodyToLog,
-		ResponseBody: utils.TruncateString(responseBody, 65000),
+		ResponseBody: responseBody,
 	}
 
 	if channelHandler != nil && bodyBytes != nil {
@@ -363,11 +392,13 @@ func (ps *ProxyServer) logRequest(
 	}
 
 	if apiKey != nil {
+		logEntry.KeyID = apiKey.ID
 		logEntry.KeyValue = apiKey.KeyValue
 	}
 
 	if finalError != nil {
 		logEntry.ErrorMessage = finalError.Error()
+		logEntry.ErrorCategory = app_errors.CategoryOf(finalError)
 	}
 
 	if err := ps.requestLogService.Record(logEntry); err != nil {
@@ -402,7 +433,8 @@ func (ps *ProxyServer) logRequestWithStreamContent(
 
 	var requestBodyToLog, userAgent string
 
-	requestBodyToLog = utils.TruncateString(string(bodyBytes), group.EffectiveConfig.MaxRequestBodyLogSize)
+	// 请求体和响应体由 RequestLogService 脱敏后再按日志大小上限截断
+	requestBodyToLog = string(bodyBytes)
 	userAgent = c.Request.UserAgent()
 
 	duration := time.Since(startTime).Milliseconds()
@@ -420,7 +452,7 @@ func (ps *ProxyServer) logRequestWithStreamContent(
 		IsStream:      isStream,
 		UpstreamAddr:  utils.TruncateString(upstreamAddr, 500),
 		RequestBody:   requestBodyToLog,
-		ResponseBody:  utils.TruncateString(responseBody, 65000),
+		ResponseBody:  responseBody,
 		StreamContent: streamContent,
 	}
 
@@ -429,11 +461,13 @@ func (ps *ProxyServer) logRequestWithStreamContent(
 	}
 
 	if apiKey != nil {
+		logEntry.KeyID = apiKey.ID
 		logEntry.KeyValue = apiKey.KeyValue
 	}
 
 	if finalError != nil {
 		logEntry.ErrorMessage = finalError.Error()
+		logEntry.ErrorCategory = app_errors.CategoryOf(finalError)
 	}
 
 	logrus.Debugf("【插桩日志】准备调用requestLogService.Record，日志条目ID: %s", logEntry.ID)
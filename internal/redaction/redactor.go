@@ -0,0 +1,227 @@
+// Package redaction masks secrets and personal data in logged request and response bodies.
+package redaction
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 内置检测器名称
+const (
+	DetectorAPIKey     = "api_key"
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+)
+
+const customPlaceholder = "[REDACTED_CUSTOM]"
+
+// detector 是一个内置检测器，valid 不为空时只替换通过校验的匹配
+type detector struct {
+	placeholder string
+	pattern     *regexp.Regexp
+	valid       func(match string) bool
+}
+
+// detectors 按替换顺序排列：先替换密钥和卡号，避免其中的数字片段被识别为电话号码
+var detectors = []struct {
+	name string
+	detector
+}{
+	{DetectorAPIKey, detector{
+		placeholder: "[REDACTED_API_KEY]",
+		pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}` + // OpenAI、Anthropic、DeepSeek 等
+			`|\bAIza[0-9A-Za-z_\-]{35}` + // Google
+			`|\bgh[pousr]_[A-Za-z0-9]{36,}` + // GitHub
+			`|\bxox[abprs]-[A-Za-z0-9\-]{10,}` + // Slack
+			`|\bAKIA[0-9A-Z]{16}\b` + // AWS Access Key
+			`|\beyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}` + // JWT
+			`|(?i:\bbearer\s+)[A-Za-z0-9._~+/\-]{20,}=*`),
+	}},
+	{DetectorCreditCard, detector{
+		placeholder: "[REDACTED_CARD]",
+		pattern:     regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		valid:       cardNumberValid,
+	}},
+	{DetectorEmail, detector{
+		placeholder: "[REDACTED_EMAIL]",
+		pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	}},
+	{DetectorPhone, detector{
+		placeholder: "[REDACTED_PHONE]",
+		pattern: regexp.MustCompile(`\+\d{1,3}[ \-]?(?:\(\d{1,4}\)[ \-]?)?\d{2,4}[ \-]?\d{3,4}[ \-]?\d{3,4}\b` + // 国际格式
+			`|\b1[3-9]\d{9}\b` + // 中国大陆手机号
+			`|\(\d{3}\)[ \-]?\d{3}-\d{4}\b|\b\d{3}-\d{3}-\d{4}\b`), // 北美格式
+	}},
+}
+
+// Redactor 按配置的内置检测器和自定义正则替换文本中的敏感内容
+type Redactor struct {
+	detectors []detector
+	custom    []*regexp.Regexp
+}
+
+// New 创建 Redactor。detectorNames 为逗号分隔的内置检测器名称，patterns 为空格或换行分隔的自定义正则。
+func New(detectorNames, patterns string) (*Redactor, error) {
+	names, err := ParseDetectors(detectorNames)
+	if err != nil {
+		return nil, err
+	}
+	custom, err := CompilePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Redactor{custom: custom}
+	for _, d := range detectors {
+		if _, ok := names[d.name]; ok {
+			r.detectors = append(r.detectors, d.detector)
+		}
+	}
+	return r, nil
+}
+
+// ParseDetectors 解析逗号分隔的内置检测器名称，包含未知名称时返回错误
+func ParseDetectors(value string) (map[string]struct{}, error) {
+	names := make(map[string]struct{})
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !isDetector(name) {
+			return nil, fmt.Errorf("unknown redaction detector %q, must be one of %s", name, strings.Join(DetectorNames(), ", "))
+		}
+		names[name] = struct{}{}
+	}
+	return names, nil
+}
+
+// CompilePatterns 编译空格或换行分隔的自定义正则，正则中的空格需写作 \s
+func CompilePatterns(value string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, expr := range strings.Fields(value) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// DetectorNames 返回所有内置检测器名称
+func DetectorNames() []string {
+	names := make([]string, len(detectors))
+	for i, d := range detectors {
+		names[i] = d.name
+	}
+	return names
+}
+
+// Enabled 返回是否配置了任何检测器或自定义正则
+func (r *Redactor) Enabled() bool {
+	return r != nil && (len(r.detectors) > 0 || len(r.custom) > 0)
+}
+
+// Redact 用带类型的占位符替换文本中的敏感内容，例如 [REDACTED_EMAIL]
+func (r *Redactor) Redact(text string) string {
+	if !r.Enabled() || text == "" {
+		return text
+	}
+	for _, d := range r.detectors {
+		if d.valid == nil {
+			text = d.pattern.ReplaceAllLiteralString(text, d.placeholder)
+			continue
+		}
+		text = d.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid(match) {
+				return d.placeholder
+			}
+			return match
+		})
+	}
+	for _, re := range r.custom {
+		text = re.ReplaceAllLiteralString(text, customPlaceholder)
+	}
+	return text
+}
+
+func isDetector(name string) bool {
+	for _, d := range detectors {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cardNumberValid 判断匹配是否像一个卡号：带分隔符时必须按卡面分组，连续数字不能像 Unix 时间戳或雪花 ID，
+// 最后通过 Luhn 校验。只靠 Luhn 会把约 10% 的 13 位毫秒时间戳和长数字 ID 误判为卡号
+func cardNumberValid(match string) bool {
+	groups := strings.FieldsFunc(match, func(r rune) bool { return r == ' ' || r == '-' })
+	if len(groups) > 1 {
+		if strings.ContainsRune(match, ' ') && strings.ContainsRune(match, '-') {
+			return false
+		}
+		if !cardGrouping(groups) {
+			return false
+		}
+	} else if timestampLike(match) {
+		return false
+	}
+	return luhnValid(match)
+}
+
+// cardGrouping 判断分组是否符合卡面印刷格式：4-4-4-4（最后一组 1-7 位）或美国运通的 4-6-5
+func cardGrouping(groups []string) bool {
+	if len(groups) == 3 && len(groups[0]) == 4 && len(groups[1]) == 6 && len(groups[2]) == 5 {
+		return true
+	}
+	if len(groups) < 4 || len(groups) > 5 {
+		return false
+	}
+	for _, group := range groups[:len(groups)-1] {
+		if len(group) != 4 {
+			return false
+		}
+	}
+	last := len(groups[len(groups)-1])
+	return last >= 1 && last <= 7
+}
+
+// timestampLike 判断连续数字的前 10 位是否落在 2001 年到 2038 年之间的 Unix 秒级时间戳范围内，
+// 毫秒、微秒、纳秒时间戳和以时间为高位的雪花 ID 都符合该特征
+func timestampLike(digits string) bool {
+	if len(digits) < 10 {
+		return false
+	}
+	seconds, err := strconv.ParseInt(digits[:10], 10, 64)
+	return err == nil && seconds >= 1_000_000_000 && seconds <= math.MaxInt32
+}
+
+// luhnValid 用 Luhn 校验过滤掉恰好是 13-19 位数字的普通数值
+func luhnValid(match string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		n := int(c - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -54,7 +54,6 @@ func NewRouter(
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.Logger(configManager.GetLogConfig()))
 	router.Use(middleware.CORS(configManager.GetCORSConfig()))
-	router.Use(middleware.RateLimiter(configManager.GetPerformanceConfig()))
 	startTime := time.Now()
 	router.Use(func(c *gin.Context) {
 		c.Set("serverStartTime", startTime)
@@ -64,7 +63,7 @@ func NewRouter(
 	// 注册路由
 	registerSystemRoutes(router, serverHandler)
 	registerAPIRoutes(router, serverHandler, configManager)
-	registerProxyRoutes(router, proxyServer, groupManager)
+	registerProxyRoutes(router, proxyServer, configManager, groupManager)
 	registerFrontendRoutes(router, buildFS, indexPage)
 
 	return router
@@ -138,6 +137,7 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	keys.POST("/add-async-with-remarks", serverHandler.AddMultipleKeysAsyncWithRemarks)
 		keys.POST("/delete-multiple", serverHandler.DeleteMultipleKeys)
 		keys.POST("/delete-async", serverHandler.DeleteMultipleKeysAsync)
+		keys.POST("/transfer-async", serverHandler.TransferKeysAsync)
 		keys.POST("/restore-multiple", serverHandler.RestoreMultipleKeys)
 		keys.POST("/restore-all-invalid", serverHandler.RestoreAllInvalidKeys)
 		keys.POST("/restore-all-disabled", serverHandler.RestoreAllDisabledKeys)
@@ -147,6 +147,22 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
 		keys.POST("/toggle-disable", serverHandler.ToggleKeyDisableStatus)
 		keys.POST("/update-remarks", serverHandler.UpdateKeyRemarks)
+		keys.POST("/update-tags", serverHandler.UpdateKeyTags)
+		keys.POST("/update-quota", serverHandler.UpdateKeyQuota)
+		keys.POST("/update-schedule", serverHandler.UpdateKeySchedule)
+		keys.GET("/library", serverHandler.ListLibraryKeys)
+		keys.POST("/library/assign", serverHandler.AssignLibraryKey)
+		keys.GET("/duplicates", serverHandler.ListDuplicateKeys)
+		keys.POST("/duplicates/dedupe", serverHandler.DedupeKeys)
+	}
+
+	// 错误规则
+	errorRules := api.Group("/error-rules")
+	{
+		errorRules.GET("", serverHandler.ListErrorRules)
+		errorRules.POST("", serverHandler.CreateErrorRule)
+		errorRules.PUT("/:id", serverHandler.UpdateErrorRule)
+		errorRules.DELETE("/:id", serverHandler.DeleteErrorRule)
 	}
 
 	// Tasks
@@ -186,11 +202,16 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 func registerProxyRoutes(
 	router *gin.Engine,
 	proxyServer *proxy.ProxyServer,
+	configManager types.ConfigManager,
 	groupManager *services.GroupManager,
 ) {
 	proxyGroup := router.Group("/proxy")
 
+	// 并发限制仅作用于代理请求，管理接口与前端资源不受影响
 	proxyGroup.Use(middleware.ProxyAuth(groupManager))
+	// 长上下文路由在鉴权之后、并发限制之前执行，使目标分组的代理密钥和并发限制生效
+	proxyGroup.Use(proxyServer.ContextLengthRouter())
+	proxyGroup.Use(middleware.RateLimiter(configManager.GetPerformanceConfig(), groupManager))
 
 	proxyGroup.Any("/:group_name/*path", proxyServer.HandleProxy)
 }
@@ -0,0 +1,79 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"abc * * * *",
+		"@every 5m",
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		expr  string
+		after string
+		want  string // 空字符串表示五年内没有匹配的时间
+	}{
+		{name: "every 15 minutes", expr: "*/15 * * * *", after: "2024-06-01 10:07:00", want: "2024-06-01 10:15:00"},
+		{name: "strictly after the current minute", expr: "5 * * * *", after: "2024-06-01 10:05:30", want: "2024-06-01 11:05:00"},
+		{name: "weekdays by name", expr: "0 9 * * mon-fri", after: "2024-06-01 12:00:00", want: "2024-06-03 09:00:00"},
+		{name: "list and range with step", expr: "0 8,20 * * *", after: "2024-06-01 09:00:00", want: "2024-06-01 20:00:00"},
+		{name: "step from a value", expr: "30 10/6 * * *", after: "2024-06-01 17:00:00", want: "2024-06-01 22:30:00"},
+		{name: "descriptor", expr: "@daily", after: "2024-06-01 23:59:00", want: "2024-06-02 00:00:00"},
+		{name: "sunday as 7", expr: "0 0 * * 7", after: "2024-06-01 12:00:00", want: "2024-06-02 00:00:00"},
+		{name: "day of month or weekday", expr: "0 0 15 * mon", after: "2024-06-01 12:00:00", want: "2024-06-03 00:00:00"},
+		{name: "skips short months", expr: "0 0 31 * *", after: "2024-04-15 00:00:00", want: "2024-05-31 00:00:00"},
+		{name: "month names", expr: "0 0 1 jan,jul *", after: "2024-02-01 00:00:00", want: "2024-07-01 00:00:00"},
+		{name: "leap day", expr: "0 0 29 2 *", after: "2024-03-01 00:00:00", want: "2028-02-29 00:00:00"},
+		{name: "impossible date", expr: "0 0 30 2 *", after: "2024-01-01 00:00:00", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			got := s.Next(at(tt.after))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Next() = %v, want no match", got)
+				}
+				return
+			}
+			if want := at(tt.want); !got.Equal(want) {
+				t.Errorf("Next(%s) = %v, want %v", tt.after, got, want)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/utils"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -22,6 +23,9 @@ type GroupManager struct {
 	db              *gorm.DB
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
+
+	reloadMu    sync.RWMutex
+	reloadHooks []func(groups map[string]*models.Group)
 }
 
 // NewGroupManager creates a new, uninitialized GroupManager.
@@ -61,6 +65,24 @@ func (gm *GroupManager) Initialize() error {
 				g.HeaderRuleList = []models.HeaderRule{}
 			}
 
+			// 解析 Key 路由规则
+			if len(group.KeyRoutingRules) > 0 {
+				if err := json.Unmarshal(group.KeyRoutingRules, &g.KeyRoutingRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse key routing rules for group")
+					g.KeyRoutingRuleList = nil
+				}
+			}
+
+			// 解析自定义验证请求模板，解析失败时回退到渠道默认的验证请求
+			if len(group.ValidationTemplate) > 0 && string(group.ValidationTemplate) != "null" {
+				var tmpl models.ValidationTemplate
+				if err := json.Unmarshal(group.ValidationTemplate, &tmpl); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse validation template for group")
+				} else {
+					g.ValidationTemplateConfig = &tmpl
+				}
+			}
+
 			groupMap[g.Name] = &g
 			logrus.WithFields(logrus.Fields{
 				"group_name":         g.Name,
@@ -77,7 +99,7 @@ func (gm *GroupManager) Initialize() error {
 		gm.store,
 		GroupUpdateChannel,
 		logrus.WithField("syncer", "groups"),
-		nil,
+		gm.afterReload,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create group syncer: %w", err)
@@ -108,6 +130,21 @@ func (gm *GroupManager) Invalidate() error {
 	return gm.syncer.Invalidate()
 }
 
+// OnReload 注册分组缓存重新加载后的回调，用于清理按分组保存的状态
+func (gm *GroupManager) OnReload(hook func(groups map[string]*models.Group)) {
+	gm.reloadMu.Lock()
+	defer gm.reloadMu.Unlock()
+	gm.reloadHooks = append(gm.reloadHooks, hook)
+}
+
+func (gm *GroupManager) afterReload(groups map[string]*models.Group) {
+	gm.reloadMu.RLock()
+	defer gm.reloadMu.RUnlock()
+	for _, hook := range gm.reloadHooks {
+		hook(groups)
+	}
+}
+
 // Stop gracefully stops the GroupManager's background syncer.
 func (gm *GroupManager) Stop(ctx context.Context) {
 	if gm.syncer != nil {
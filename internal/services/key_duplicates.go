@@ -0,0 +1,271 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 重复密钥的类型
+const (
+	DuplicateTypeExact      = "exact"      // 相同密钥出现在多个分组
+	DuplicateTypeNormalized = "normalized" // 去掉空白、引号、Bearer 或前缀后相同
+)
+
+// 去重时保留哪一个密钥
+const (
+	DedupeKeepOldest   = "oldest"
+	DedupeKeepMostUsed = "most_used"
+)
+
+const (
+	maxDuplicateSets = 200
+	// minKeyCoreLength 去掉前缀后至少保留的长度，过短时不去前缀以免误判
+	minKeyCoreLength = 16
+)
+
+// keyPrefixPattern 匹配 sk-、sk-proj-、sk-ant-api03- 这类由字母开头、以 - 或 _ 结尾的前缀
+var keyPrefixPattern = regexp.MustCompile(`^(?:[A-Za-z][A-Za-z0-9]{0,9}[-_])+`)
+
+// ErrSharedKeysNotDeduped is returned by DedupeKeys when identical keys in different groups are shared on purpose.
+var ErrSharedKeysNotDeduped = errors.New("shared key state is enabled, identical keys in different groups are shared and not removed")
+
+// DuplicateKeyMember is one key of a duplicate set.
+type DuplicateKeyMember struct {
+	KeyID        uint      `json:"key_id"`
+	GroupID      uint      `json:"group_id"`
+	GroupName    string    `json:"group_name"`
+	KeyValue     string    `json:"key_value"` // masked
+	Status       string    `json:"status"`
+	IsDisabled   bool      `json:"is_disabled"`
+	RequestCount int64     `json:"request_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DuplicateKeySet is a group of keys that are the same upstream key.
+type DuplicateKeySet struct {
+	Type    string               `json:"type"`
+	Members []DuplicateKeyMember `json:"members"`
+}
+
+// DuplicateKeyReport lists the duplicate sets found across all groups.
+type DuplicateKeyReport struct {
+	ExactSets      int               `json:"exact_sets"`
+	NormalizedSets int               `json:"normalized_sets"`
+	RedundantKeys  int               `json:"redundant_keys"` // 对完全相同的密钥去重后会移除的密钥数
+	Sets           []DuplicateKeySet `json:"sets"`
+	Truncated      bool              `json:"truncated"`
+}
+
+type duplicateSet struct {
+	Type string
+	Keys []models.APIKey
+}
+
+// normalizeKeyValue 返回用于比较的密钥值：去掉空白、引号和 Bearer，并尽量去掉厂商前缀
+func normalizeKeyValue(keyValue string) string {
+	value := strings.Trim(strings.TrimSpace(keyValue), `"'`)
+	if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+		value = strings.TrimSpace(value[7:])
+	}
+
+	core := keyPrefixPattern.ReplaceAllString(value, "")
+	if len(core) < minKeyCoreLength {
+		return value
+	}
+	return core
+}
+
+// FindDuplicateKeys 查找出现在多个分组中的相同密钥，以及规范化后相同的密钥，duplicateType 为空时返回全部
+func (s *KeyService) FindDuplicateKeys(duplicateType string) (*DuplicateKeyReport, error) {
+	sets, err := s.findDuplicateSets(duplicateType)
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames, err := s.groupNames()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DuplicateKeyReport{Sets: []DuplicateKeySet{}}
+	for _, set := range sets {
+		if set.Type == DuplicateTypeExact {
+			report.ExactSets++
+			report.RedundantKeys += len(set.Keys) - 1
+		} else {
+			report.NormalizedSets++
+		}
+
+		if len(report.Sets) >= maxDuplicateSets {
+			report.Truncated = true
+			continue
+		}
+		members := make([]DuplicateKeyMember, 0, len(set.Keys))
+		for _, key := range set.Keys {
+			members = append(members, DuplicateKeyMember{
+				KeyID:        key.ID,
+				GroupID:      key.GroupID,
+				GroupName:    groupNames[key.GroupID],
+				KeyValue:     utils.MaskAPIKey(strings.TrimSpace(key.KeyValue)),
+				Status:       key.Status,
+				IsDisabled:   key.IsDisabled,
+				RequestCount: key.RequestCount,
+				CreatedAt:    key.CreatedAt,
+			})
+		}
+		report.Sets = append(report.Sets, DuplicateKeySet{Type: set.Type, Members: members})
+	}
+
+	return report, nil
+}
+
+// DedupeKeys 对每组完全相同的重复密钥只保留一个，其余从所在分组中移除，返回移除数量。
+// 规范化后相同的密钥可能是不同的密钥，只能手动处理；开启跨分组共享密钥状态时，多个分组中的相同密钥是有意共享的，不做去重。
+// keepGroupID 不为 0 时优先保留该分组中的密钥，否则按 keep 保留最早添加或请求最多的密钥。
+func (s *KeyService) DedupeKeys(duplicateType, keep string, keepGroupID uint) (int64, error) {
+	if duplicateType != DuplicateTypeExact {
+		return 0, fmt.Errorf("only exact duplicates can be removed automatically, got type %q", duplicateType)
+	}
+	if keep != DedupeKeepOldest && keep != DedupeKeepMostUsed {
+		return 0, fmt.Errorf("invalid keep strategy: %s", keep)
+	}
+	if s.KeyProvider.SharedKeyStateEnabled() {
+		return 0, ErrSharedKeysNotDeduped
+	}
+
+	sets, err := s.findDuplicateSets(duplicateType)
+	if err != nil {
+		return 0, err
+	}
+
+	toRemove := make(map[uint][]string)
+	for _, set := range sets {
+		keeper := pickDuplicateKeeper(set.Keys, keep, keepGroupID)
+		for _, key := range set.Keys {
+			if key.ID != keeper.ID {
+				toRemove[key.GroupID] = append(toRemove[key.GroupID], key.KeyValue)
+			}
+		}
+	}
+
+	var removedCount int64
+	for groupID, keyValues := range toRemove {
+		for i := 0; i < len(keyValues); i += chunkSize {
+			end := min(i+chunkSize, len(keyValues))
+			removed, err := s.KeyProvider.RemoveKeys(groupID, keyValues[i:end])
+			if err != nil {
+				return removedCount, err
+			}
+			removedCount += removed
+		}
+	}
+
+	return removedCount, nil
+}
+
+// pickDuplicateKeeper 选出一组重复密钥中要保留的密钥，keys 已按 ID 升序排列
+func pickDuplicateKeeper(keys []models.APIKey, keep string, keepGroupID uint) models.APIKey {
+	if keepGroupID != 0 {
+		for _, key := range keys {
+			if key.GroupID == keepGroupID {
+				return key
+			}
+		}
+	}
+
+	keeper := keys[0]
+	if keep == DedupeKeepMostUsed {
+		for _, key := range keys[1:] {
+			if key.RequestCount > keeper.RequestCount {
+				keeper = key
+			}
+		}
+	}
+	return keeper
+}
+
+// findDuplicateSets 按规范化后的密钥值分组，返回包含两个及以上密钥的集合。
+// 原始值完全相同的密钥组成 exact 集合；规范化后相同但原始值不全相同时，整组另作为 normalized 集合返回。
+func (s *KeyService) findDuplicateSets(duplicateType string) ([]duplicateSet, error) {
+	if duplicateType != "" && duplicateType != DuplicateTypeExact && duplicateType != DuplicateTypeNormalized {
+		return nil, fmt.Errorf("invalid duplicate type: %s", duplicateType)
+	}
+
+	buckets := make(map[string][]models.APIKey)
+	var batch []models.APIKey
+	err := s.DB.Model(&models.APIKey{}).
+		Select("id, key_value, group_id, status, is_disabled, request_count, created_at").
+		FindInBatches(&batch, chunkSize, func(tx *gorm.DB, _ int) error {
+			if err := s.DecryptKeys(batch); err != nil {
+				return err
+			}
+			for _, key := range batch {
+				normalized := normalizeKeyValue(key.KeyValue)
+				buckets[normalized] = append(buckets[normalized], key)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := func(a, b models.APIKey) int {
+		return cmp.Compare(a.ID, b.ID)
+	}
+
+	var sets []duplicateSet
+	for _, keys := range buckets {
+		if len(keys) < 2 {
+			continue
+		}
+		slices.SortFunc(keys, byID)
+
+		// 规范化后相同的集合中再按原始值拆出完全相同的子集，保证完全相同的密钥总能被报告和去重
+		exact := make(map[string][]models.APIKey)
+		for _, key := range keys {
+			exact[key.KeyValue] = append(exact[key.KeyValue], key)
+		}
+		if duplicateType != DuplicateTypeNormalized {
+			for _, same := range exact {
+				if len(same) >= 2 {
+					sets = append(sets, duplicateSet{Type: DuplicateTypeExact, Keys: same})
+				}
+			}
+		}
+		if duplicateType != DuplicateTypeExact && len(exact) > 1 {
+			sets = append(sets, duplicateSet{Type: DuplicateTypeNormalized, Keys: keys})
+		}
+	}
+
+	// 完全相同的排在前面，其次按最早的密钥排序，保证结果稳定
+	slices.SortFunc(sets, func(a, b duplicateSet) int {
+		if a.Type != b.Type {
+			return cmp.Compare(a.Type, b.Type)
+		}
+		return cmp.Compare(a.Keys[0].ID, b.Keys[0].ID)
+	})
+
+	return sets, nil
+}
+
+// groupNames 返回分组 ID 到名称的映射
+func (s *KeyService) groupNames() (map[uint]string, error) {
+	var groups []models.Group
+	if err := s.DB.Select("id, name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		names[group.ID] = group.Name
+	}
+	return names, nil
+}
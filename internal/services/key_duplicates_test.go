@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/db"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestNormalizeKeyValue(t *testing.T) {
+	const core = "abcdefghijklmnopqrstuvwx"
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "openai prefix", input: "sk-" + core, want: core},
+		{name: "project prefix", input: "sk-proj-" + core, want: core},
+		{name: "anthropic prefix", input: "sk-ant-api03-" + core, want: core},
+		{name: "bearer and whitespace", input: "  Bearer sk-" + core + " ", want: core},
+		{name: "quoted", input: `"sk-` + core + `"`, want: core},
+		{name: "single quoted bearer", input: "'bearer sk-" + core + "'", want: core},
+		{name: "short core keeps prefix", input: "sk-short123", want: "sk-short123"},
+		{name: "no prefix", input: "AIzaSyA1234567890abcdefghij", want: "AIzaSyA1234567890abcdefghij"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeKeyValue(tt.input); got != tt.want {
+				t.Errorf("normalizeKeyValue(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// newDuplicateTestService 创建使用内存 SQLite 的 KeyService，并写入一组重复密钥：
+// 1、2 完全相同，3 与它们规范化后相同，4、5 完全相同，6 没有重复
+func newDuplicateTestService(t *testing.T, sharedKeyState bool) *KeyService {
+	t.Helper()
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := testDB.AutoMigrate(&models.APIKey{}, &models.Group{}, &models.SystemSetting{}); err != nil {
+		t.Fatal(err)
+	}
+
+	setting := models.SystemSetting{SettingKey: "shared_key_state", SettingValue: "false"}
+	if sharedKeyState {
+		setting.SettingValue = "true"
+	}
+	if err := testDB.Create(&setting).Error; err != nil {
+		t.Fatal(err)
+	}
+	memoryStore := store.NewMemoryStore()
+	previousDB := db.DB
+	db.DB = testDB
+	t.Cleanup(func() { db.DB = previousDB })
+	settingsManager := config.NewSystemSettingsManager()
+	if err := settingsManager.Initialize(memoryStore, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { settingsManager.Stop(context.Background()) })
+
+	encryptionService, err := encryption.NewService("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one", "two", "three"} {
+		if err := testDB.Create(&models.Group{Name: name, Upstreams: datatypes.JSON("[]")}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys := []models.APIKey{
+		{GroupID: 1, KeyValue: "sk-aaaaaaaaaaaaaaaaaaaaaaaa"},
+		{GroupID: 2, KeyValue: "sk-aaaaaaaaaaaaaaaaaaaaaaaa", RequestCount: 9},
+		{GroupID: 3, KeyValue: "aaaaaaaaaaaaaaaaaaaaaaaa"},
+		{GroupID: 1, KeyValue: "sk-bbbbbbbbbbbbbbbbbbbbbbbb"},
+		{GroupID: 2, KeyValue: "sk-bbbbbbbbbbbbbbbbbbbbbbbb"},
+		{GroupID: 1, KeyValue: "sk-cccccccccccccccccccccccc"},
+	}
+	for i := range keys {
+		keys[i].KeyHash = encryption.HashKey(keys[i].KeyValue)
+		keys[i].Status = models.KeyStatusActive
+	}
+	if err := testDB.Create(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	provider := keypool.NewProvider(testDB, memoryStore, settingsManager, nil, encryptionService)
+	return NewKeyService(testDB, provider, nil, encryptionService)
+}
+
+func TestFindDuplicateSets(t *testing.T) {
+	s := newDuplicateTestService(t, false)
+
+	type wantSet struct {
+		setType string
+		keyIDs  []uint
+	}
+	tests := []struct {
+		duplicateType string
+		want          []wantSet
+	}{
+		{
+			duplicateType: "",
+			want: []wantSet{
+				{DuplicateTypeExact, []uint{1, 2}},
+				{DuplicateTypeExact, []uint{4, 5}},
+				{DuplicateTypeNormalized, []uint{1, 2, 3}},
+			},
+		},
+		{
+			duplicateType: DuplicateTypeExact,
+			want: []wantSet{
+				{DuplicateTypeExact, []uint{1, 2}},
+				{DuplicateTypeExact, []uint{4, 5}},
+			},
+		},
+		{
+			duplicateType: DuplicateTypeNormalized,
+			want:          []wantSet{{DuplicateTypeNormalized, []uint{1, 2, 3}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("type "+tt.duplicateType, func(t *testing.T) {
+			sets, err := s.findDuplicateSets(tt.duplicateType)
+			if err != nil {
+				t.Fatalf("findDuplicateSets() error = %v", err)
+			}
+			if len(sets) != len(tt.want) {
+				t.Fatalf("findDuplicateSets() returned %d sets, want %d: %+v", len(sets), len(tt.want), sets)
+			}
+			for i, set := range sets {
+				ids := make([]uint, 0, len(set.Keys))
+				for _, key := range set.Keys {
+					ids = append(ids, key.ID)
+				}
+				if set.Type != tt.want[i].setType || !slices.Equal(ids, tt.want[i].keyIDs) {
+					t.Errorf("set %d = %s %v, want %s %v", i, set.Type, ids, tt.want[i].setType, tt.want[i].keyIDs)
+				}
+			}
+		})
+	}
+
+	if _, err := s.findDuplicateSets("fuzzy"); err == nil {
+		t.Error("findDuplicateSets() accepted an invalid type")
+	}
+
+	report, err := s.FindDuplicateKeys("")
+	if err != nil {
+		t.Fatalf("FindDuplicateKeys() error = %v", err)
+	}
+	if report.ExactSets != 2 || report.NormalizedSets != 1 || report.RedundantKeys != 2 {
+		t.Errorf("report = %d exact, %d normalized, %d redundant, want 2, 1, 2",
+			report.ExactSets, report.NormalizedSets, report.RedundantKeys)
+	}
+}
+
+func TestDedupeKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		keep        string
+		keepGroupID uint
+		wantIDs     []uint
+	}{
+		{name: "keep oldest", keep: DedupeKeepOldest, wantIDs: []uint{1, 3, 4, 6}},
+		{name: "keep most used", keep: DedupeKeepMostUsed, wantIDs: []uint{2, 3, 4, 6}},
+		{name: "keep group", keep: DedupeKeepOldest, keepGroupID: 2, wantIDs: []uint{2, 3, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newDuplicateTestService(t, false)
+			removed, err := s.DedupeKeys(DuplicateTypeExact, tt.keep, tt.keepGroupID)
+			if err != nil {
+				t.Fatalf("DedupeKeys() error = %v", err)
+			}
+			if removed != 2 {
+				t.Errorf("DedupeKeys() removed %d keys, want 2", removed)
+			}
+			var ids []uint
+			if err := s.DB.Model(&models.APIKey{}).Order("id").Pluck("id", &ids).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("remaining keys = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestDedupeKeysRefusesSharedOrNormalized(t *testing.T) {
+	s := newDuplicateTestService(t, false)
+	if _, err := s.DedupeKeys(DuplicateTypeNormalized, DedupeKeepOldest, 0); err == nil {
+		t.Error("DedupeKeys() removed normalized duplicates")
+	}
+
+	shared := newDuplicateTestService(t, true)
+	if _, err := shared.DedupeKeys(DuplicateTypeExact, DedupeKeepOldest, 0); !errors.Is(err, ErrSharedKeysNotDeduped) {
+		t.Errorf("DedupeKeys() with shared key state error = %v, want ErrSharedKeysNotDeduped", err)
+	}
+}
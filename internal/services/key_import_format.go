@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/utils"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 导入格式
+const (
+	KeyImportFormatText  = "text"
+	KeyImportFormatCSV   = "csv"
+	KeyImportFormatJSONL = "jsonl"
+)
+
+const maxImportRemarksLength = 500
+
+// keyColumnAliases 是 CSV 表头和 JSONL 字段中可以表示密钥的名称
+var keyColumnAliases = []string{"key", "key_value", "api_key"}
+
+// keySettingColumns 是 CSV 和 JSONL 中除密钥外支持导入的列，其他列会在导入结果中报告为不支持
+var keySettingColumns = []string{
+	"remarks", "tags", "quota_period", "request_quota", "token_quota",
+	"valid_from", "expires_at", "active_windows",
+}
+
+// isSupportedImportColumn 判断列名是否为可以导入的列
+func isSupportedImportColumn(column string) bool {
+	return slices.Contains(keyColumnAliases, column) || slices.Contains(keySettingColumns, column)
+}
+
+// KeyImportRecord 是导入文件中的一行密钥及其附带的设置
+type KeyImportRecord struct {
+	Row           int
+	KeyValue      string
+	Remarks       string
+	Tags          string
+	QuotaPeriod   string
+	RequestQuota  int64
+	TokenQuota    int64
+	ValidFrom     *time.Time
+	ExpiresAt     *time.Time
+	ActiveWindows string
+	Error         string // 非空表示该行格式无效
+}
+
+// jsonlKeyRecord 是 JSONL 中一行的结构，tags 可以是字符串或字符串数组，限额可以是数字或数字字符串
+type jsonlKeyRecord struct {
+	Key           string          `json:"key"`
+	KeyValue      string          `json:"key_value"`
+	APIKey        string          `json:"api_key"`
+	Remarks       string          `json:"remarks"`
+	Tags          any             `json:"tags"`
+	QuotaPeriod   string          `json:"quota_period"`
+	RequestQuota  json.RawMessage `json:"request_quota"`
+	TokenQuota    json.RawMessage `json:"token_quota"`
+	ValidFrom     string          `json:"valid_from"`
+	ExpiresAt     string          `json:"expires_at"`
+	ActiveWindows string          `json:"active_windows"`
+}
+
+// DetectKeyImportFormat 根据首个非空行判断导入文本的格式：
+// 以 { 开头为 JSONL，表头包含 key、key_value 或 api_key 列为 CSV，否则为纯文本
+func DetectKeyImportFormat(text string) string {
+	firstLine := ""
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			firstLine = line
+			break
+		}
+	}
+
+	if strings.HasPrefix(firstLine, "{") {
+		return KeyImportFormatJSONL
+	}
+	if keyColumnIndex(parseCSVHeader(firstLine)) >= 0 {
+		return KeyImportFormatCSV
+	}
+	return KeyImportFormatText
+}
+
+// ParseKeyRecords 按格式解析导入文本，format 为空时自动识别。
+// 纯文本只包含密钥；CSV 和 JSONL 的每行还可以带 remarks、tags、quota_period、request_quota、
+// token_quota、valid_from、expires_at 和 active_windows。格式无效的行保留在结果中并带有错误信息。
+// 同时返回按名称排序的不支持的列，这些列的值不会被导入。
+func (s *KeyService) ParseKeyRecords(text, format string) ([]KeyImportRecord, []string, error) {
+	if format == "" {
+		format = DetectKeyImportFormat(text)
+	}
+
+	var records []KeyImportRecord
+	var unsupported []string
+	var err error
+	switch format {
+	case KeyImportFormatText:
+		records = s.parseTextKeyRecords(text)
+	case KeyImportFormatCSV:
+		records, unsupported, err = parseCSVKeyRecords(text)
+	case KeyImportFormatJSONL:
+		records, unsupported, err = parseJSONLKeyRecords(text)
+	default:
+		return nil, nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range records {
+		if records[i].Error == "" {
+			records[i].Error = s.validateKeyRecord(&records[i])
+		}
+	}
+	return records, unsupported, nil
+}
+
+// parseTextKeyRecords 沿用纯文本的分隔规则，每个密钥为一行记录
+func (s *KeyService) parseTextKeyRecords(text string) []KeyImportRecord {
+	var keys []string
+	if json.Unmarshal([]byte(text), &keys) != nil || len(keys) == 0 {
+		keys = splitKeysText(text)
+	}
+
+	records := make([]KeyImportRecord, 0, len(keys))
+	for i, key := range keys {
+		records = append(records, KeyImportRecord{Row: i + 1, KeyValue: strings.TrimSpace(key)})
+	}
+	return records
+}
+
+func parseCSVHeader(line string) []string {
+	header, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return nil
+	}
+	return normalizeCSVHeader(header)
+}
+
+func normalizeCSVHeader(header []string) []string {
+	normalized := make([]string, len(header))
+	for i, column := range header {
+		normalized[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+	}
+	return normalized
+}
+
+func keyColumnIndex(header []string) int {
+	for i, column := range header {
+		for _, alias := range keyColumnAliases {
+			if column == alias {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseCSVKeyRecords(text string) ([]KeyImportRecord, []string, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, "\ufeff")))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rawHeader, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	header := normalizeCSVHeader(rawHeader)
+	columns := make(map[string]int, len(header))
+	var unsupported []string
+	for i, column := range header {
+		columns[column] = i
+		if column != "" && !isSupportedImportColumn(column) && !slices.Contains(unsupported, column) {
+			unsupported = append(unsupported, column)
+		}
+	}
+	slices.Sort(unsupported)
+	keyIndex := keyColumnIndex(header)
+	if keyIndex < 0 {
+		return nil, nil, fmt.Errorf("CSV header must contain a key column")
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []KeyImportRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			rowNumber := 0
+			if errors.As(err, &parseErr) {
+				rowNumber = parseErr.StartLine
+			}
+			records = append(records, KeyImportRecord{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		// csv.Reader 会跳过空行，行号取记录在文件中的起始行
+		rowNumber, _ := reader.FieldPos(0)
+
+		record := KeyImportRecord{
+			Row:           rowNumber,
+			Remarks:       field(row, "remarks"),
+			Tags:          field(row, "tags"),
+			QuotaPeriod:   field(row, "quota_period"),
+			ActiveWindows: field(row, "active_windows"),
+		}
+		if keyIndex < len(row) {
+			record.KeyValue = strings.TrimSpace(row[keyIndex])
+		}
+		record.Error = firstError(
+			parseImportInt(field(row, "request_quota"), &record.RequestQuota),
+			parseImportInt(field(row, "token_quota"), &record.TokenQuota),
+			parseImportTime(field(row, "valid_from"), &record.ValidFrom),
+			parseImportTime(field(row, "expires_at"), &record.ExpiresAt),
+		)
+		records = append(records, record)
+	}
+	return records, unsupported, nil
+}
+
+func parseJSONLKeyRecords(text string) ([]KeyImportRecord, []string, error) {
+	var records []KeyImportRecord
+	unsupported := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for rowNumber := 1; scanner.Scan(); rowNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var raw jsonlKeyRecord
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			records = append(records, KeyImportRecord{Row: rowNumber, Error: fmt.Sprintf("invalid JSON: %v", err)})
+			continue
+		}
+		if json.Unmarshal([]byte(line), &fields) == nil {
+			for name := range fields {
+				if !isSupportedImportColumn(name) {
+					unsupported[name] = struct{}{}
+				}
+			}
+		}
+
+		record := KeyImportRecord{
+			Row:           rowNumber,
+			KeyValue:      strings.TrimSpace(firstNonEmpty(raw.Key, raw.KeyValue, raw.APIKey)),
+			Remarks:       strings.TrimSpace(raw.Remarks),
+			QuotaPeriod:   strings.TrimSpace(raw.QuotaPeriod),
+			ActiveWindows: strings.TrimSpace(raw.ActiveWindows),
+		}
+		switch tags := raw.Tags.(type) {
+		case nil:
+		case string:
+			record.Tags = tags
+		case []any:
+			parts := make([]string, 0, len(tags))
+			for _, tag := range tags {
+				parts = append(parts, fmt.Sprint(tag))
+			}
+			record.Tags = strings.Join(parts, ",")
+		default:
+			record.Error = "tags must be a string or an array of strings"
+		}
+		if record.Error == "" {
+			record.Error = firstError(
+				parseImportJSONInt(raw.RequestQuota, &record.RequestQuota),
+				parseImportJSONInt(raw.TokenQuota, &record.TokenQuota),
+				parseImportTime(raw.ValidFrom, &record.ValidFrom),
+				parseImportTime(raw.ExpiresAt, &record.ExpiresAt),
+			)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read JSONL: %w", err)
+	}
+	return records, slices.Sorted(maps.Keys(unsupported)), nil
+}
+
+// validateKeyRecord 校验并规范化一行记录，返回错误信息
+func (s *KeyService) validateKeyRecord(record *KeyImportRecord) string {
+	if !s.isValidKeyFormat(record.KeyValue) {
+		return "invalid key format"
+	}
+	if utf8.RuneCountInString(record.Remarks) > maxImportRemarksLength {
+		return fmt.Sprintf("remarks exceed %d characters", maxImportRemarksLength)
+	}
+
+	tags, err := utils.NormalizeTags(strings.FieldsFunc(record.Tags, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	}))
+	if err != nil {
+		return err.Error()
+	}
+	record.Tags = strings.Join(tags, ",")
+
+	record.RequestQuota, record.TokenQuota, err = validateKeyQuota(record.QuotaPeriod, record.RequestQuota, record.TokenQuota)
+	if err != nil {
+		return err.Error()
+	}
+
+	if record.ValidFrom != nil && record.ExpiresAt != nil && !record.ExpiresAt.After(*record.ValidFrom) {
+		return "expires_at must be later than valid_from"
+	}
+	record.ActiveWindows, err = utils.NormalizeTimeWindows(record.ActiveWindows)
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func parseImportInt(value string, target *int64) error {
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	*target = n
+	return nil
+}
+
+// parseImportJSONInt 解析 JSONL 中的整数字段，支持数字和数字字符串，如 1000 或 "1000"
+func parseImportJSONInt(raw json.RawMessage, target *int64) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return parseImportInt(strings.TrimSpace(text), target)
+	}
+	return parseImportInt(string(raw), target)
+}
+
+// parseImportTime 支持 RFC3339、"2006-01-02 15:04:05" 和 "2006-01-02"，后两者按服务器本地时间解析
+func parseImportTime(value string, target **time.Time) error {
+	if value == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		*target = &t
+		return nil
+	}
+	for _, layout := range []string{time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			*target = &t
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q, expected RFC3339 or YYYY-MM-DD", value)
+}
+
+func firstError(errs ...error) string {
+	for _, err := range errs {
+		if err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
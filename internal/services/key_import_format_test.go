@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDetectKeyImportFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain keys", text: "sk-a\nsk-b", want: KeyImportFormatText},
+		{name: "jsonl", text: "\n  {\"key\":\"sk-a\"}\n", want: KeyImportFormatJSONL},
+		{name: "csv with key column", text: "key,remarks\nsk-a,test", want: KeyImportFormatCSV},
+		{name: "csv with api_key column and bom", text: "\ufeffName, API_KEY\nx,sk-a", want: KeyImportFormatCSV},
+		{name: "comma separated keys", text: "sk-a,sk-b", want: KeyImportFormatText},
+		{name: "empty", text: "", want: KeyImportFormatText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectKeyImportFormat(tt.text); got != tt.want {
+				t.Errorf("DetectKeyImportFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVKeyRecords(t *testing.T) {
+	text := "\ufeffKey,Remarks,Tags,Request_Quota,Token_Quota,Weight,Owner\n" +
+		"sk-one, first ,\"a,b\",100,,3,alice\n" +
+		"\n" +
+		"sk-two,,,abc,,1,bob\n" +
+		"sk-three\n" +
+		"sk-\"bad\n"
+
+	records, unsupported, err := parseCSVKeyRecords(text)
+	if err != nil {
+		t.Fatalf("parseCSVKeyRecords() error = %v", err)
+	}
+	if want := []string{"owner", "weight"}; !slices.Equal(unsupported, want) {
+		t.Errorf("unsupported = %v, want %v", unsupported, want)
+	}
+
+	tests := []struct {
+		row          int
+		keyValue     string
+		remarks      string
+		tags         string
+		requestQuota int64
+		wantErr      bool
+	}{
+		{row: 2, keyValue: "sk-one", remarks: "first", tags: "a,b", requestQuota: 100},
+		{row: 4, keyValue: "sk-two", wantErr: true},
+		{row: 5, keyValue: "sk-three"},
+		{row: 6, wantErr: true},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d: %+v", len(records), len(tests), records)
+	}
+	for i, tt := range tests {
+		got := records[i]
+		if got.Row != tt.row || got.KeyValue != tt.keyValue || got.Remarks != tt.remarks ||
+			got.Tags != tt.tags || got.RequestQuota != tt.requestQuota || (got.Error != "") != tt.wantErr {
+			t.Errorf("record %d = %+v, want row %d key %q remarks %q tags %q request quota %d error %t",
+				i, got, tt.row, tt.keyValue, tt.remarks, tt.tags, tt.requestQuota, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCSVKeyRecordsRequiresKeyColumn(t *testing.T) {
+	if _, _, err := parseCSVKeyRecords("name,remarks\nx,y"); err == nil {
+		t.Error("parseCSVKeyRecords() without a key column should fail")
+	}
+}
+
+func TestParseJSONLKeyRecords(t *testing.T) {
+	text := `{"key":"sk-one","tags":["a","b"],"request_quota":"1000","token_quota":50,"weight":2}` + "\n" +
+		`{"api_key":" sk-two ","tags":"x;y","request_quota":null}` + "\n" +
+		"\n" +
+		`{"key":"sk-three",` + "\n" +
+		`{"key":"sk-four","tags":{"a":1}}` + "\n" +
+		`{"key_value":"sk-five","token_quota":"lots","owner":"bob"}` + "\n"
+
+	records, unsupported, err := parseJSONLKeyRecords(text)
+	if err != nil {
+		t.Fatalf("parseJSONLKeyRecords() error = %v", err)
+	}
+	if want := []string{"owner", "weight"}; !slices.Equal(unsupported, want) {
+		t.Errorf("unsupported = %v, want %v", unsupported, want)
+	}
+
+	tests := []struct {
+		row          int
+		keyValue     string
+		tags         string
+		requestQuota int64
+		tokenQuota   int64
+		wantErr      bool
+	}{
+		{row: 1, keyValue: "sk-one", tags: "a,b", requestQuota: 1000, tokenQuota: 50},
+		{row: 2, keyValue: "sk-two", tags: "x;y"},
+		{row: 4, wantErr: true},
+		{row: 5, keyValue: "sk-four", wantErr: true},
+		{row: 6, keyValue: "sk-five", wantErr: true},
+	}
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d: %+v", len(records), len(tests), records)
+	}
+	for i, tt := range tests {
+		got := records[i]
+		if got.Row != tt.row || got.KeyValue != tt.keyValue || got.Tags != tt.tags ||
+			got.RequestQuota != tt.requestQuota || got.TokenQuota != tt.tokenQuota || (got.Error != "") != tt.wantErr {
+			t.Errorf("record %d = %+v, want row %d key %q tags %q quotas %d/%d error %t",
+				i, got, tt.row, tt.keyValue, tt.tags, tt.requestQuota, tt.tokenQuota, tt.wantErr)
+		}
+	}
+}
+
+func TestParseImportJSONInt(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int64
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "null", want: 0},
+		{raw: "1000", want: 1000},
+		{raw: `"1000"`, want: 1000},
+		{raw: `" 42 "`, want: 42},
+		{raw: `""`, want: 0},
+		{raw: "1.5", wantErr: true},
+		{raw: `"abc"`, wantErr: true},
+		{raw: "true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var got int64
+		err := parseImportJSONInt(json.RawMessage(tt.raw), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseImportJSONInt(%s) error = %v, wantErr %t", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseImportJSONInt(%s) = %d, want %d", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"slices"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,10 +15,61 @@ const (
 	importTimeout   = 30 * time.Minute
 )
 
+// 导入报告中每行的结果
+const (
+	KeyImportRowAdded     = "added"
+	KeyImportRowShared    = "shared" // 已添加，且其他分组中已有相同密钥
+	KeyImportRowDuplicate = "duplicate"
+	KeyImportRowInvalid   = "invalid"
+)
+
+// maxImportReportRows caps the per-row report kept with the task result.
+const maxImportReportRows = 5000
+
 // KeyImportResult holds the result of an import task.
 type KeyImportResult struct {
-	AddedCount   int `json:"added_count"`
-	IgnoredCount int `json:"ignored_count"`
+	AddedCount         int                  `json:"added_count"`
+	IgnoredCount       int                  `json:"ignored_count"`
+	SharedCount        int                  `json:"shared_count"`
+	DuplicateCount     int                  `json:"duplicate_count"`
+	InvalidCount       int                  `json:"invalid_count"`
+	Rows               []KeyImportRowResult `json:"rows,omitempty"`
+	RowsTruncated      bool                 `json:"rows_truncated,omitempty"`
+	UnsupportedColumns []string             `json:"unsupported_columns,omitempty"` // CSV 表头或 JSONL 中未被导入的列
+}
+
+// KeyImportRowResult is the outcome of one row of the import.
+type KeyImportRowResult struct {
+	Row     int    `json:"row"`
+	Key     string `json:"key"` // masked
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+}
+
+func (r *KeyImportResult) addRow(record *KeyImportRecord, result, message string) {
+	switch result {
+	case KeyImportRowDuplicate:
+		r.DuplicateCount++
+	case KeyImportRowInvalid:
+		r.InvalidCount++
+	}
+	r.Rows = append(r.Rows, KeyImportRowResult{
+		Row:     record.Row,
+		Key:     utils.MaskAPIKey(record.KeyValue),
+		Result:  result,
+		Message: message,
+	})
+}
+
+// finishRows orders the report by row number and truncates it to maxImportReportRows.
+func (r *KeyImportResult) finishRows() {
+	slices.SortFunc(r.Rows, func(a, b KeyImportRowResult) int {
+		return a.Row - b.Row
+	})
+	if len(r.Rows) > maxImportReportRows {
+		r.Rows = r.Rows[:maxImportReportRows]
+		r.RowsTruncated = true
+	}
 }
 
 // KeyImportService handles the asynchronous import of a large number of keys.
@@ -34,84 +87,48 @@ func NewKeyImportService(taskService *TaskService, keyService *KeyService) *KeyI
 }
 
 // StartImportTask initiates a new asynchronous key import task.
-func (s *KeyImportService) StartImportTask(group *models.Group, keysText string) (*TaskStatus, error) {
-	keys := s.KeyService.ParseKeysFromText(keysText)
-	if len(keys) == 0 {
-		return nil, fmt.Errorf("no valid keys found in the input text")
-	}
-
-	initialStatus, err := s.TaskService.StartTask(TaskTypeKeyImport, group.Name, len(keys), importTimeout)
-	if err != nil {
-		return nil, err
-	}
-
-	go s.runImport(group, keys)
-
-	return initialStatus, nil
+// The text may be plain keys, CSV or JSONL; format is detected when empty.
+func (s *KeyImportService) StartImportTask(group *models.Group, keysText, format string) (*TaskStatus, error) {
+	return s.StartImportTaskWithRemarks(group, keysText, format, "", false)
 }
 
 // StartImportTaskWithRemarks initiates a new asynchronous key import task with remarks.
-func (s *KeyImportService) StartImportTaskWithRemarks(group *models.Group, keysText string, remarks string, useForAll bool) (*TaskStatus, error) {
-	keys := s.KeyService.ParseKeysFromText(keysText)
-	if len(keys) == 0 {
-		return nil, fmt.Errorf("no valid keys found in input text")
-	}
-
-	initialStatus, err := s.TaskService.StartTask(TaskTypeKeyImport, group.Name, len(keys), importTimeout)
+// Remarks from the imported rows take precedence over the given remarks.
+func (s *KeyImportService) StartImportTaskWithRemarks(group *models.Group, keysText, format, remarks string, useForAll bool) (*TaskStatus, error) {
+	records, unsupportedColumns, err := s.KeyService.ParseKeyRecords(keysText, format)
 	if err != nil {
 		return nil, err
 	}
-
-	go s.runImportWithRemarks(group, keys, remarks, useForAll)
-
-	return initialStatus, nil
-}
-
-// runImportWithRemarks handles the actual import process with remarks.
-func (s *KeyImportService) runImportWithRemarks(group *models.Group, keys []string, remarks string, useForAll bool) {
-	progressCallback := func(processed int) {
-		if err := s.TaskService.UpdateProgress(processed); err != nil {
-			logrus.Warnf("Failed to update task progress for group %d: %v", group.ID, err)
-		}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no valid keys found in the input text")
 	}
 
-	addedCount, ignoredCount, err := s.KeyService.processAndCreateKeysWithRemarks(group.ID, keys, remarks, useForAll, progressCallback)
+	initialStatus, err := s.TaskService.StartTask(TaskTypeKeyImport, group.Name, len(records), importTimeout)
 	if err != nil {
-		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
-			logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", group.ID, endErr, err)
-		}
-		return
+		return nil, err
 	}
 
-	result := KeyImportResult{
-		AddedCount:   addedCount,
-		IgnoredCount: ignoredCount,
-	}
+	go s.runImport(group, records, unsupportedColumns, remarks, useForAll)
 
-	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
-		logrus.Errorf("Failed to end task with success result for group %d: %v", group.ID, endErr)
-	}
+	return initialStatus, nil
 }
 
-func (s *KeyImportService) runImport(group *models.Group, keys []string) {
+// runImport handles the actual import process.
+func (s *KeyImportService) runImport(group *models.Group, records []KeyImportRecord, unsupportedColumns []string, remarks string, useForAll bool) {
 	progressCallback := func(processed int) {
 		if err := s.TaskService.UpdateProgress(processed); err != nil {
 			logrus.Warnf("Failed to update task progress for group %d: %v", group.ID, err)
 		}
 	}
 
-	addedCount, ignoredCount, err := s.KeyService.processAndCreateKeys(group.ID, keys, progressCallback)
+	result, err := s.KeyService.processAndCreateKeyRecords(group.ID, records, remarks, useForAll, progressCallback)
 	if err != nil {
 		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
 			logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", group.ID, endErr, err)
 		}
 		return
 	}
-
-	result := KeyImportResult{
-		AddedCount:   addedCount,
-		IgnoredCount: ignoredCount,
-	}
+	result.UnsupportedColumns = unsupportedColumns
 
 	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
 		logrus.Errorf("Failed to end task with success result for group %d: %v", group.ID, endErr)
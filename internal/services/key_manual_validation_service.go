@@ -46,6 +46,9 @@ func (s *KeyManualValidationService) StartValidationTask(group *models.Group, st
 	query := s.DB.Where("group_id = ?", group.ID)
 	if status != "" {
 		query = query.Where("status = ?", status)
+	} else {
+		// 退役和配额耗尽的密钥仅在显式指定状态时才重新验证
+		query = query.Where("status NOT IN ?", []string{models.KeyStatusRetired, models.KeyStatusExhausted})
 	}
 	if err := query.Find(&keys).Error; err != nil {
 		return nil, fmt.Errorf("failed to get keys for group %s with status '%s': %w", group.Name, status, err)
@@ -3,11 +3,15 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -43,14 +47,16 @@ type KeyService struct {
 	DB           *gorm.DB
 	KeyProvider  *keypool.KeyProvider
 	KeyValidator *keypool.KeyValidator
+	Encryption   encryption.Service
 }
 
 // NewKeyService creates a new KeyService.
-func NewKeyService(db *gorm.DB, keyProvider *keypool.KeyProvider, keyValidator *keypool.KeyValidator) *KeyService {
+func NewKeyService(db *gorm.DB, keyProvider *keypool.KeyProvider, keyValidator *keypool.KeyValidator, encryptionService encryption.Service) *KeyService {
 	return &KeyService{
 		DB:           db,
 		KeyProvider:  keyProvider,
 		KeyValidator: keyValidator,
+		Encryption:   encryptionService,
 	}
 }
 
@@ -89,14 +95,10 @@ func (s *KeyService) processAndCreateKeys(
 	progressCallback func(processed int),
 ) (addedCount int, ignoredCount int, err error) {
 	// 1. Get existing keys in the group for deduplication
-	var existingKeys []models.APIKey
-	if err := s.DB.Where("group_id = ?", groupID).Select("key_value").Find(&existingKeys).Error; err != nil {
+	existingKeyMap, err := s.existingKeyHashes(groupID)
+	if err != nil {
 		return 0, 0, err
 	}
-	existingKeyMap := make(map[string]bool)
-	for _, k := range existingKeys {
-		existingKeyMap[k.KeyValue] = true
-	}
 
 	// 2. Prepare new keys for creation
 	var newKeysToCreate []models.APIKey
@@ -107,7 +109,7 @@ func (s *KeyService) processAndCreateKeys(
 		if trimmedKey == "" {
 			continue
 		}
-		if existingKeyMap[trimmedKey] || uniqueNewKeys[trimmedKey] {
+		if existingKeyMap[encryption.HashKey(trimmedKey)] || uniqueNewKeys[trimmedKey] {
 			continue
 		}
 		if s.isValidKeyFormat(trimmedKey) {
@@ -144,81 +146,146 @@ func (s *KeyService) processAndCreateKeys(
 	return addedCount, len(keys) - addedCount, nil
 }
 
-// processAndCreateKeysWithRemarks is lowest-level reusable function for adding keys with remarks.
-func (s *KeyService) processAndCreateKeysWithRemarks(
+// processAndCreateKeyRecords adds parsed import records to a group and reports the outcome of every row.
+// Records without remarks get defaultRemarks: all of them when useRemarksForAll is set, otherwise only the first new key.
+func (s *KeyService) processAndCreateKeyRecords(
 	groupID uint,
-	keys []string,
-	remarks string,
-	useForAll bool,
+	records []KeyImportRecord,
+	defaultRemarks string,
+	useRemarksForAll bool,
 	progressCallback func(processed int),
-) (addedCount int, ignoredCount int, err error) {
-	// 1. Get existing keys in group for deduplication
-	var existingKeys []models.APIKey
-	if err := s.DB.Where("group_id = ?", groupID).Select("key_value").Find(&existingKeys).Error; err != nil {
-		return 0, 0, err
-	}
-	existingKeyMap := make(map[string]bool)
-	for _, k := range existingKeys {
-		existingKeyMap[k.KeyValue] = true
+) (*KeyImportResult, error) {
+	result := &KeyImportResult{}
+
+	// 1. Get existing keys in the group for deduplication
+	existingKeyMap, err := s.existingKeyHashes(groupID)
+	if err != nil {
+		return result, err
 	}
 
-	// 2. Prepare new keys for creation
+	// 2. Classify rows and prepare new keys for creation
 	var newKeysToCreate []models.APIKey
+	var newRows []int
 	uniqueNewKeys := make(map[string]bool)
 
-	for _, keyVal := range keys {
-		trimmedKey := strings.TrimSpace(keyVal)
-		if trimmedKey == "" {
-			continue
-		}
-		if existingKeyMap[trimmedKey] || uniqueNewKeys[trimmedKey] {
-			continue
-		}
-		if s.isValidKeyFormat(trimmedKey) {
-			uniqueNewKeys[trimmedKey] = true
-			
-			// Prepare key with remarks
-			key := models.APIKey{
-				GroupID:  groupID,
-				KeyValue: trimmedKey,
-				Status:   models.KeyStatusActive,
-			}
-			
-			// Apply remarks if provided
-			if remarks != "" && useForAll {
-				key.Remarks = remarks
-			} else if remarks != "" {
-				// If not using remarks for all, only apply to first key
-				if len(newKeysToCreate) == 0 {
-					key.Remarks = remarks
-				}
+	for i := range records {
+		record := &records[i]
+		switch {
+		case record.Error != "":
+			result.addRow(record, KeyImportRowInvalid, record.Error)
+		case existingKeyMap[encryption.HashKey(record.KeyValue)]:
+			result.addRow(record, KeyImportRowDuplicate, "already exists in this group")
+		case uniqueNewKeys[record.KeyValue]:
+			result.addRow(record, KeyImportRowDuplicate, "duplicated in the import")
+		default:
+			uniqueNewKeys[record.KeyValue] = true
+
+			remarks := record.Remarks
+			if remarks == "" && defaultRemarks != "" && (useRemarksForAll || len(newKeysToCreate) == 0) {
+				remarks = defaultRemarks
 			}
-			
-			newKeysToCreate = append(newKeysToCreate, key)
+			newKeysToCreate = append(newKeysToCreate, models.APIKey{
+				GroupID:       groupID,
+				KeyValue:      record.KeyValue,
+				Status:        models.KeyStatusActive,
+				Remarks:       remarks,
+				Tags:          record.Tags,
+				QuotaPeriod:   record.QuotaPeriod,
+				RequestQuota:  record.RequestQuota,
+				TokenQuota:    record.TokenQuota,
+				ValidFrom:     record.ValidFrom,
+				ExpiresAt:     record.ExpiresAt,
+				ActiveWindows: record.ActiveWindows,
+			})
+			newRows = append(newRows, i)
 		}
 	}
 
-	if len(newKeysToCreate) == 0 {
-		return 0, len(keys), nil
+	// 3. Keys already in other groups join the key library and share its state
+	otherGroups, err := s.findKeysInOtherGroups(groupID, newKeysToCreate)
+	if err != nil {
+		return result, err
+	}
+	for _, i := range newRows {
+		record := &records[i]
+		if groupNames, ok := otherGroups[encryption.HashKey(record.KeyValue)]; ok {
+			result.SharedCount++
+			result.addRow(record, KeyImportRowShared, "also in group: "+strings.Join(groupNames, ", "))
+		} else {
+			result.addRow(record, KeyImportRowAdded, "")
+		}
 	}
+	result.finishRows()
 
-	// 3. Use KeyProvider to add keys in chunks
+	// 4. Use KeyProvider to add keys in chunks
 	for i := 0; i < len(newKeysToCreate); i += chunkSize {
-		end := i + chunkSize
-		if end > len(newKeysToCreate) {
-			end = len(newKeysToCreate)
-		}
+		end := min(i+chunkSize, len(newKeysToCreate))
 		chunk := newKeysToCreate[i:end]
 		if err := s.KeyProvider.AddKeys(groupID, chunk); err != nil {
-			return 0, 0, err
+			return result, err
 		}
-		addedCount += len(chunk)
+		result.AddedCount += len(chunk)
+
 		if progressCallback != nil {
 			progressCallback(i + len(chunk))
 		}
 	}
+	result.IgnoredCount = result.DuplicateCount + result.InvalidCount
 
-	return addedCount, len(keys) - addedCount, nil
+	return result, nil
+}
+
+// existingKeyHashes returns the hashes of all keys in the group for deduplication.
+func (s *KeyService) existingKeyHashes(groupID uint) (map[string]bool, error) {
+	var keyHashes []string
+	if err := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &keyHashes).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(keyHashes))
+	for _, keyHash := range keyHashes {
+		existing[keyHash] = true
+	}
+	return existing, nil
+}
+
+// findKeysInOtherGroups returns the names of the other groups that already hold each of the given plaintext keys, keyed by key hash.
+func (s *KeyService) findKeysInOtherGroups(groupID uint, keys []models.APIKey) (map[string][]string, error) {
+	keyHashes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyHashes = append(keyHashes, encryption.HashKey(key.KeyValue))
+	}
+
+	var matches []models.APIKey
+	for i := 0; i < len(keyHashes); i += chunkSize {
+		end := min(i+chunkSize, len(keyHashes))
+		var chunk []models.APIKey
+		if err := s.DB.Select("key_hash, group_id").Where("group_id <> ? AND key_hash IN ?", groupID, keyHashes[i:end]).Find(&chunk).Error; err != nil {
+			return nil, err
+		}
+		matches = append(matches, chunk...)
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	groupIDs := make([]uint, 0, len(matches))
+	for _, match := range matches {
+		groupIDs = append(groupIDs, match.GroupID)
+	}
+	var groups []models.Group
+	if err := s.DB.Select("id, name").Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	groupNames := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	result := make(map[string][]string)
+	for _, match := range matches {
+		result[match.KeyHash] = append(result[match.KeyHash], groupNames[match.GroupID])
+	}
+	return result, nil
 }
 
 // ParseKeysFromText parses a string of keys from various formats into a string slice.
@@ -231,7 +298,26 @@ func (s *KeyService) ParseKeysFromText(text string) []string {
 		return s.filterValidKeys(keys)
 	}
 
-	// 通用解析：通过分隔符分割文本，不使用复杂的正则表达式
+	// CSV 和 JSONL 只取密钥列，忽略格式无效的行
+	if format := DetectKeyImportFormat(text); format != KeyImportFormatText {
+		records, _, err := s.ParseKeyRecords(text, format)
+		if err != nil {
+			return nil
+		}
+		for _, record := range records {
+			if record.Error == "" {
+				keys = append(keys, record.KeyValue)
+			}
+		}
+		return keys
+	}
+
+	return s.filterValidKeys(splitKeysText(text))
+}
+
+// splitKeysText 通用解析：通过分隔符分割文本，不使用复杂的正则表达式
+func splitKeysText(text string) []string {
+	var keys []string
 	delimiters := regexp.MustCompile(`[\s,;|\n\r\t]+`)
 	splitKeys := delimiters.Split(strings.TrimSpace(text), -1)
 
@@ -241,8 +327,7 @@ func (s *KeyService) ParseKeysFromText(text string) []string {
 			keys = append(keys, key)
 		}
 	}
-
-	return s.filterValidKeys(keys)
+	return keys
 }
 
 // filterValidKeys validates and filters potential API keys
@@ -368,23 +453,47 @@ func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteK
 	}, nil
 }
 
-// ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status.
-func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchKeyword string) *gorm.DB {
+// ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status and tag.
+func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchKeyword string, tag string) *gorm.DB {
 	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID)
 
 	if statusFilter != "" {
 		query = query.Where("status = ?", statusFilter)
 	}
 
-	if searchKeyword != "" {
-		query = query.Where("key_value LIKE ?", "%"+searchKeyword+"%")
-	}
+	query = whereHasTag(query, tag)
+
+	query = s.whereKeyValueMatches(query, searchKeyword)
 
 	query = query.Order("last_used_at desc, updated_at desc")
 
 	return query
 }
 
+// whereKeyValueMatches 按密钥值搜索。密钥值加密后无法模糊匹配，只能按完整密钥的哈希精确查找
+func (s *KeyService) whereKeyValueMatches(query *gorm.DB, searchKeyword string) *gorm.DB {
+	searchKeyword = strings.TrimSpace(searchKeyword)
+	if searchKeyword == "" {
+		return query
+	}
+	if s.Encryption.Enabled() {
+		return query.Where("key_hash = ?", encryption.HashKey(searchKeyword))
+	}
+	return query.Where("key_value LIKE ?", "%"+searchKeyword+"%")
+}
+
+// DecryptKeys 解密查询结果中的密钥值，用于在管理界面展示
+func (s *KeyService) DecryptKeys(keys []models.APIKey) error {
+	for i := range keys {
+		keyValue, err := s.Encryption.Decrypt(keys[i].KeyValue)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key %d: %w", keys[i].ID, err)
+		}
+		keys[i].KeyValue = keyValue
+	}
+	return nil
+}
+
 // TestMultipleKeys handles a one-off validation test for multiple keys.
 func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]keypool.KeyTestResult, error) {
 	keysToTest := s.ParseKeysFromText(keysText)
@@ -413,11 +522,12 @@ func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]k
 }
 
 // StreamKeysToWriter fetches keys from the database in batches and writes them to the provided writer.
-func (s *KeyService) StreamKeysToWriter(groupID uint, statusFilter string, writer io.Writer) error {
+func (s *KeyService) StreamKeysToWriter(groupID uint, statusFilter string, tag string, writer io.Writer) error {
 	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Select("id, key_value")
+	query = whereHasTag(query, tag)
 
 	switch statusFilter {
-	case models.KeyStatusActive, models.KeyStatusInvalid:
+	case models.KeyStatusActive, models.KeyStatusInvalid, models.KeyStatusRetired, models.KeyStatusExhausted:
 		query = query.Where("status = ?", statusFilter)
 	case "all":
 	default:
@@ -426,6 +536,9 @@ func (s *KeyService) StreamKeysToWriter(groupID uint, statusFilter string, write
 
 	var keys []models.APIKey
 	err := query.FindInBatches(&keys, chunkSize, func(tx *gorm.DB, batch int) error {
+		if err := s.DecryptKeys(keys); err != nil {
+			return err
+		}
 		for _, key := range keys {
 			if _, err := writer.Write([]byte(key.KeyValue + "\n")); err != nil {
 				return err
@@ -442,37 +555,369 @@ func (s *KeyService) ToggleKeyDisableStatus(groupID uint, keyValue string, isDis
 	var key models.APIKey
 	
 	// 首先获取密钥信息
-	if err := s.DB.Where("group_id = ? AND key_value = ?", groupID, keyValue).First(&key).Error; err != nil {
+	if err := s.DB.Where("group_id = ? AND key_hash = ?", groupID, encryption.HashKey(keyValue)).First(&key).Error; err != nil {
 		return err
 	}
 
-	// 更新数据库中的停用状态
-	if err := s.DB.Model(&key).Update("is_disabled", isDisabled).Error; err != nil {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		// 更新数据库中的停用状态
+		if err := tx.Model(&key).Update("is_disabled", isDisabled).Error; err != nil {
+			return err
+		}
+
+		// 更新密钥对象
+		key.IsDisabled = isDisabled
+
+		// 根据新的状态，更新KeyProvider中的active列表
+		if isDisabled {
+			// 手动停用：从active列表中移除（只移除这一个密钥）
+			s.KeyProvider.RemoveKeysFromActiveList(groupID, []uint{key.ID})
+		} else if key.Status == models.KeyStatusActive {
+			// 手动启用且状态为active：添加回active列表（使用专门的方法，不创建新记录），失败时回滚数据库更改
+			if err := s.KeyProvider.AddKeyToActiveList(&key); err != nil {
+				return err
+			}
+		}
+
+		return s.KeyProvider.SyncSharedKey(tx, key.ID)
+	})
+}
+
+// UpdateKeyRemarks 更新密钥备注
+func (s *KeyService) UpdateKeyRemarks(groupID uint, keyValue string, remarks string) error {
+	var key models.APIKey
+	if err := s.DB.Select("id").Where("group_id = ? AND key_hash = ?", groupID, encryption.HashKey(keyValue)).First(&key).Error; err != nil {
 		return err
 	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&key).Update("remarks", remarks).Error; err != nil {
+			return err
+		}
+		return s.KeyProvider.SyncSharedKey(tx, key.ID)
+	})
+}
 
-	// 更新密钥对象
-	key.IsDisabled = isDisabled
+// tagLikeEscaper 转义 LIKE 通配符。使用 ! 作为转义字符，避免 MySQL 字符串中反斜杠本身需要转义的差异
+var tagLikeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
 
-	// 根据新的状态，更新KeyProvider中的active列表
-	if isDisabled {
-		// 手动停用：从active列表中移除（只移除这一个密钥）
-		s.KeyProvider.RemoveKeysFromActiveList(groupID, []uint{key.ID})
-	} else if key.Status == models.KeyStatusActive {
-		// 手动启用且状态为active：添加回active列表（使用专门的方法，不创建新记录）
-		if err := s.KeyProvider.AddKeyToActiveList(&key); err != nil {
-			// 如果添加失败，回滚数据库更改
-			s.DB.Model(&key).Update("is_disabled", !isDisabled)
-			return err
+// whereHasTag 过滤包含指定标签的 Key，标签以逗号分隔存储
+func whereHasTag(query *gorm.DB, tag string) *gorm.DB {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return query
+	}
+	escaped := tagLikeEscaper.Replace(tag)
+	return query.Where("(tags = ? OR tags LIKE ? ESCAPE '!' OR tags LIKE ? ESCAPE '!' OR tags LIKE ? ESCAPE '!')",
+		tag, escaped+",%", "%,"+escaped, "%,"+escaped+",%")
+}
+
+// UpdateKeyTags 批量更新密钥标签，action 为 set（替换）、add（追加）或 remove（移除）
+func (s *KeyService) UpdateKeyTags(groupID uint, keysText string, tags []string, action string) (int, error) {
+	keyValues := s.ParseKeysFromText(keysText)
+	if len(keyValues) > maxRequestKeys {
+		return 0, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keyValues))
+	}
+	if len(keyValues) == 0 {
+		return 0, fmt.Errorf("no valid keys found in the input text")
+	}
+
+	normalized, err := utils.NormalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+
+	var updatedCount int
+	for i := 0; i < len(keyValues); i += chunkSize {
+		end := min(i+chunkSize, len(keyValues))
+
+		var keys []models.APIKey
+		if err := s.DB.Select("id, tags").Where("group_id = ? AND key_hash IN ?", groupID, encryption.HashKeys(keyValues[i:end])).Find(&keys).Error; err != nil {
+			return updatedCount, err
+		}
+
+		for _, key := range keys {
+			newTags, err := mergeTags(utils.SplitAndTrim(key.Tags, ","), normalized, action)
+			if err != nil {
+				return updatedCount, err
+			}
+			if newTags == key.Tags {
+				continue
+			}
+			err = s.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&models.APIKey{}).Where("id = ?", key.ID).Update("tags", newTags).Error; err != nil {
+					return err
+				}
+				if err := s.KeyProvider.UpdateKeyTags(key.ID, newTags); err != nil {
+					return err
+				}
+				return s.KeyProvider.SyncSharedKey(tx, key.ID)
+			})
+			if err != nil {
+				return updatedCount, err
+			}
+			updatedCount++
+		}
+	}
+
+	return updatedCount, nil
+}
+
+// UpdateKeyQuota 批量设置密钥的用量配额，period 为空表示不限制
+func (s *KeyService) UpdateKeyQuota(groupID uint, keysText string, period string, requestQuota, tokenQuota int64) (int, error) {
+	keyValues := s.ParseKeysFromText(keysText)
+	if len(keyValues) > maxRequestKeys {
+		return 0, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keyValues))
+	}
+	if len(keyValues) == 0 {
+		return 0, fmt.Errorf("no valid keys found in the input text")
+	}
+
+	requestQuota, tokenQuota, err := validateKeyQuota(period, requestQuota, tokenQuota)
+	if err != nil {
+		return 0, err
+	}
+
+	var updatedCount int
+	for i := 0; i < len(keyValues); i += chunkSize {
+		end := min(i+chunkSize, len(keyValues))
+
+		var keys []models.APIKey
+		if err := s.DB.Select("id").Where("group_id = ? AND key_hash IN ?", groupID, encryption.HashKeys(keyValues[i:end])).Find(&keys).Error; err != nil {
+			return updatedCount, err
+		}
+
+		for _, key := range keys {
+			updates := map[string]any{
+				"quota_period":  period,
+				"request_quota": requestQuota,
+				"token_quota":   tokenQuota,
+			}
+			err := s.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				if err := s.KeyProvider.UpdateKeyQuota(key.ID, period, requestQuota, tokenQuota); err != nil {
+					return err
+				}
+				return s.KeyProvider.SyncSharedKey(tx, key.ID)
+			})
+			if err != nil {
+				return updatedCount, err
+			}
+			updatedCount++
 		}
 	}
 
+	return updatedCount, nil
+}
+
+// UpdateKeySchedule 批量设置密钥的生效时间、过期时间和每天可用时段，nil 或空字符串表示不限制
+func (s *KeyService) UpdateKeySchedule(group *models.Group, keysText string, validFrom, expiresAt *time.Time, activeWindows string) (int, error) {
+	keyValues := s.ParseKeysFromText(keysText)
+	if len(keyValues) > maxRequestKeys {
+		return 0, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keyValues))
+	}
+	if len(keyValues) == 0 {
+		return 0, fmt.Errorf("no valid keys found in the input text")
+	}
+
+	if validFrom != nil && expiresAt != nil && !expiresAt.After(*validFrom) {
+		return 0, fmt.Errorf("expires_at must be later than valid_from")
+	}
+	windows, err := utils.NormalizeTimeWindows(activeWindows)
+	if err != nil {
+		return 0, err
+	}
+
+	var updatedCount int
+	for i := 0; i < len(keyValues); i += chunkSize {
+		end := min(i+chunkSize, len(keyValues))
+
+		var keys []models.APIKey
+		if err := s.DB.Where("group_id = ? AND key_hash IN ?", group.ID, encryption.HashKeys(keyValues[i:end])).Find(&keys).Error; err != nil {
+			return updatedCount, err
+		}
+
+		for j := range keys {
+			key := &keys[j]
+			updates := map[string]any{
+				"valid_from":     validFrom,
+				"expires_at":     expiresAt,
+				"active_windows": windows,
+			}
+			key.ValidFrom, key.ExpiresAt, key.ActiveWindows = validFrom, expiresAt, windows
+			err := s.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&models.APIKey{}).Where("id = ?", key.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				if err := s.KeyProvider.UpdateKeySchedule(key, group); err != nil {
+					return err
+				}
+				return s.KeyProvider.SyncSharedKey(tx, key.ID)
+			})
+			if err != nil {
+				return updatedCount, err
+			}
+			updatedCount++
+		}
+	}
+
+	return updatedCount, nil
+}
+
+// validateKeyQuota 校验配额设置，period 为空时清零配额
+func validateKeyQuota(period string, requestQuota, tokenQuota int64) (int64, int64, error) {
+	switch period {
+	case "":
+		return 0, 0, nil
+	case models.QuotaPeriodDay, models.QuotaPeriodMonth:
+		if requestQuota < 0 || tokenQuota < 0 {
+			return 0, 0, fmt.Errorf("quota values cannot be negative")
+		}
+		if requestQuota == 0 && tokenQuota == 0 {
+			return 0, 0, fmt.Errorf("at least one of request_quota and token_quota must be set")
+		}
+		return requestQuota, tokenQuota, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid quota period: %s", period)
+	}
+}
+
+// mergeTags 按 action 合并标签并返回逗号分隔的结果
+func mergeTags(current, tags []string, action string) (string, error) {
+	var result []string
+	switch action {
+	case "", "set":
+		result = tags
+	case "add":
+		result = append(current, tags...)
+	case "remove":
+		remove := make(map[string]struct{}, len(tags))
+		for _, tag := range tags {
+			remove[tag] = struct{}{}
+		}
+		for _, tag := range current {
+			if _, ok := remove[tag]; !ok {
+				result = append(result, tag)
+			}
+		}
+	default:
+		return "", fmt.Errorf("invalid tag action: %s", action)
+	}
+
+	merged, err := utils.NormalizeTags(result)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(merged, ","), nil
+}
+
+// LibraryKeyGroup is a group that references a library key.
+type LibraryKeyGroup struct {
+	KeyID     uint   `json:"key_id"`
+	GroupID   uint   `json:"group_id"`
+	GroupName string `json:"group_name"`
+}
+
+// LibraryKey is a distinct key value in the key library together with the groups that reference it.
+type LibraryKey struct {
+	ID           uint              `json:"id"`
+	KeyValue     string            `json:"key_value"`
+	KeyHash      string            `json:"-"`
+	Status       string            `json:"status"`
+	IsDisabled   bool              `json:"is_disabled"`
+	FailureCount int64             `json:"failure_count"`
+	Remarks      string            `json:"remarks"`
+	Tags         string            `json:"tags"`
+	GroupCount   int64             `json:"group_count"`
+	Groups       []LibraryKeyGroup `json:"groups" gorm:"-"`
+}
+
+// LibraryKeysQuery 按密钥值去重列出密钥库，每个密钥取最早加入的一条作为共享状态的代表
+func (s *KeyService) LibraryKeysQuery(searchKeyword string) *gorm.DB {
+	grouped := s.DB.Model(&models.APIKey{}).Select("MIN(id) as id, COUNT(*) as group_count").Group("key_hash")
+	grouped = s.whereKeyValueMatches(grouped, searchKeyword)
+	return s.DB.Table("api_keys").
+		Select("api_keys.id, api_keys.key_value, api_keys.key_hash, api_keys.status, api_keys.is_disabled, api_keys.failure_count, api_keys.remarks, api_keys.tags, library.group_count").
+		Joins("JOIN (?) AS library ON library.id = api_keys.id", grouped).
+		Order("api_keys.id DESC")
+}
+
+// FillLibraryKeyGroups 解密密钥库条目的密钥值，并填充其所引用的分组
+func (s *KeyService) FillLibraryKeyGroups(libraryKeys []LibraryKey) error {
+	if len(libraryKeys) == 0 {
+		return nil
+	}
+	keyHashes := make([]string, len(libraryKeys))
+	for i := range libraryKeys {
+		keyValue, err := s.Encryption.Decrypt(libraryKeys[i].KeyValue)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key %d: %w", libraryKeys[i].ID, err)
+		}
+		libraryKeys[i].KeyValue = keyValue
+		keyHashes[i] = libraryKeys[i].KeyHash
+	}
+
+	var keys []models.APIKey
+	if err := s.DB.Select("id, key_hash, group_id").Where("key_hash IN ?", keyHashes).Order("id ASC").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	groupIDs := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		groupIDs = append(groupIDs, key.GroupID)
+	}
+	var groups []models.Group
+	if err := s.DB.Select("id, name").Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+		return err
+	}
+	groupNames := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	groupsByKey := make(map[string][]LibraryKeyGroup, len(libraryKeys))
+	for _, key := range keys {
+		groupsByKey[key.KeyHash] = append(groupsByKey[key.KeyHash], LibraryKeyGroup{KeyID: key.ID, GroupID: key.GroupID, GroupName: groupNames[key.GroupID]})
+	}
+	for i := range libraryKeys {
+		libraryKeys[i].Groups = groupsByKey[libraryKeys[i].KeyHash]
+	}
 	return nil
 }
 
-// UpdateKeyRemarks 更新密钥备注
-func (s *KeyService) UpdateKeyRemarks(groupID uint, keyValue string, remarks string) error {
-	return s.DB.Model(&models.APIKey{}).
-		Where("group_id = ? AND key_value = ?", groupID, keyValue).
-		Update("remarks", remarks).Error
+// AssignLibraryKey 将密钥库中的密钥加入其他分组，新加入的 Key 继承已有的共享状态
+func (s *KeyService) AssignLibraryKey(keyValue string, groupIDs []uint) (int, error) {
+	keyValue = strings.TrimSpace(keyValue)
+	keyHash := encryption.HashKey(keyValue)
+	var count int64
+	if err := s.DB.Model(&models.APIKey{}).Where("key_hash = ?", keyHash).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("key not found in the key library")
+	}
+
+	var assignedCount int
+	for _, groupID := range slices.Compact(slices.Sorted(slices.Values(groupIDs))) {
+		var group models.Group
+		if err := s.DB.Select("id").First(&group, groupID).Error; err != nil {
+			return assignedCount, fmt.Errorf("group %d not found: %w", groupID, err)
+		}
+
+		var existing int64
+		if err := s.DB.Model(&models.APIKey{}).Where("group_id = ? AND key_hash = ?", groupID, keyHash).Count(&existing).Error; err != nil {
+			return assignedCount, err
+		}
+		if existing > 0 {
+			continue
+		}
+
+		newKeys := []models.APIKey{{GroupID: groupID, KeyValue: keyValue, Status: models.KeyStatusActive}}
+		if err := s.KeyProvider.AddKeys(groupID, newKeys); err != nil {
+			return assignedCount, err
+		}
+		assignedCount++
+	}
+	return assignedCount, nil
 }
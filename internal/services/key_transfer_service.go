@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	KeyTransferModeMove = "move"
+	KeyTransferModeCopy = "copy"
+
+	transferChunkSize = 500
+	transferTimeout   = 30 * time.Minute
+)
+
+// KeyTransferFilter selects the keys of the source group to transfer.
+// KeysText takes precedence; otherwise all keys matching Status and Tag are selected.
+type KeyTransferFilter struct {
+	KeysText string
+	Status   string
+	Tag      string
+}
+
+// KeyTransferResult holds the result of a move or copy task.
+type KeyTransferResult struct {
+	TransferredCount int `json:"transferred_count"`
+	SkippedCount     int `json:"skipped_count"`
+}
+
+// KeyTransferService handles moving and copying keys between existing groups asynchronously.
+type KeyTransferService struct {
+	TaskService *TaskService
+	KeyService  *KeyService
+}
+
+// NewKeyTransferService creates a new KeyTransferService.
+func NewKeyTransferService(taskService *TaskService, keyService *KeyService) *KeyTransferService {
+	return &KeyTransferService{
+		TaskService: taskService,
+		KeyService:  keyService,
+	}
+}
+
+// StartTransferTask initiates a new asynchronous task moving or copying keys from source to target.
+func (s *KeyTransferService) StartTransferTask(source, target *models.Group, mode string, filter KeyTransferFilter) (*TaskStatus, error) {
+	if source.ID == target.ID {
+		return nil, fmt.Errorf("source and target group must be different")
+	}
+
+	taskType := TaskTypeKeyMove
+	if mode == KeyTransferModeCopy {
+		taskType = TaskTypeKeyCopy
+	} else if mode != KeyTransferModeMove {
+		return nil, fmt.Errorf("invalid transfer mode: %s", mode)
+	}
+
+	keyIDs, err := s.selectKeyIDs(source.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyIDs) == 0 {
+		return nil, fmt.Errorf("no matching keys found in group %s", source.Name)
+	}
+
+	initialStatus, err := s.TaskService.StartTask(taskType, source.Name, len(keyIDs), transferTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runTransfer(source, target, mode, keyIDs)
+
+	return initialStatus, nil
+}
+
+// selectKeyIDs resolves the filter to the IDs of keys in the source group.
+func (s *KeyTransferService) selectKeyIDs(groupID uint, filter KeyTransferFilter) ([]uint, error) {
+	var keyIDs []uint
+
+	if filter.KeysText != "" {
+		keyValues := s.KeyService.ParseKeysFromText(filter.KeysText)
+		if len(keyValues) == 0 {
+			return nil, fmt.Errorf("no valid keys found in the input text")
+		}
+		for i := 0; i < len(keyValues); i += deleteChunkSize {
+			end := min(i+deleteChunkSize, len(keyValues))
+			var chunkIDs []uint
+			if err := s.KeyService.DB.Model(&models.APIKey{}).
+				Where("group_id = ? AND key_hash IN ?", groupID, encryption.HashKeys(keyValues[i:end])).
+				Pluck("id", &chunkIDs).Error; err != nil {
+				return nil, err
+			}
+			keyIDs = append(keyIDs, chunkIDs...)
+		}
+		return keyIDs, nil
+	}
+
+	query := s.KeyService.ListKeysInGroupQuery(groupID, filter.Status, "", filter.Tag)
+	if err := query.Pluck("id", &keyIDs).Error; err != nil {
+		return nil, err
+	}
+	return keyIDs, nil
+}
+
+func (s *KeyTransferService) runTransfer(source, target *models.Group, mode string, keyIDs []uint) {
+	transfer := s.KeyService.KeyProvider.MoveKeys
+	if mode == KeyTransferModeCopy {
+		transfer = s.KeyService.KeyProvider.CopyKeys
+	}
+
+	var transferredCount, skippedCount int64
+	for i := 0; i < len(keyIDs); i += transferChunkSize {
+		end := min(i+transferChunkSize, len(keyIDs))
+
+		transferred, skipped, err := transfer(source.ID, target.ID, keyIDs[i:end])
+		if err != nil {
+			logrus.Errorf("Failed to %s keys from group %d to group %d: %v", mode, source.ID, target.ID, err)
+			if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
+				logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", source.ID, endErr, err)
+			}
+			return
+		}
+		transferredCount += transferred
+		skippedCount += skipped
+
+		if err := s.TaskService.UpdateProgress(end); err != nil {
+			logrus.Warnf("Failed to update task progress for group %d: %v", source.ID, err)
+		}
+	}
+
+	result := KeyTransferResult{
+		TransferredCount: int(transferredCount),
+		SkippedCount:     int(skippedCount),
+	}
+
+	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
+		logrus.Errorf("Failed to end task with success result for group %d: %v", source.ID, endErr)
+	}
+}
@@ -1,11 +1,15 @@
 package services
 
 import (
+	"cmp"
 	"encoding/csv"
 	"fmt"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
 	"io"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,22 +25,29 @@ type ExportableLogKey struct {
 
 // LogService provides services related to request logs.
 type LogService struct {
-	DB *gorm.DB
+	DB         *gorm.DB
+	Encryption encryption.Service
 }
 
 // NewLogService creates a new LogService.
-func NewLogService(db *gorm.DB) *LogService {
-	return &LogService{DB: db}
+func NewLogService(db *gorm.DB, encryptionService encryption.Service) *LogService {
+	return &LogService{DB: db, Encryption: encryptionService}
 }
 
 // logFiltersScope returns a GORM scope function that applies filters from the Gin context.
-func logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if groupName := c.Query("group_name"); groupName != "" {
 			db = db.Where("group_name LIKE ?", "%"+groupName+"%")
 		}
-		if keyValue := c.Query("key_value"); keyValue != "" {
-			db = db.Where("key_value LIKE ?", "%"+keyValue+"%")
+		if keyIDStr := c.Query("key_id"); keyIDStr != "" {
+			if keyID, err := strconv.ParseUint(keyIDStr, 10, 64); err == nil {
+				db = db.Where("key_id = ?", keyID)
+			}
+		}
+		if keyValue := strings.TrimSpace(c.Query("key_value")); keyValue != "" {
+			// 日志只保存脱敏预览，先在 api_keys 中匹配密钥，再按 key_id 过滤
+			db = db.Where("key_id IN (?)", s.matchingKeyIDs(keyValue))
 		}
 		if model := c.Query("model"); model != "" {
 			db = db.Where("model LIKE ?", "%"+model+"%")
@@ -60,6 +71,9 @@ func logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 		if errorContains := c.Query("error_contains"); errorContains != "" {
 			db = db.Where("error_message LIKE ?", "%"+errorContains+"%")
 		}
+		if errorCategory := c.Query("error_category"); errorCategory != "" {
+			db = db.Where("error_category = ?", errorCategory)
+		}
 		if startTimeStr := c.Query("start_time"); startTimeStr != "" {
 			if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
 				db = db.Where("timestamp >= ?", startTime)
@@ -74,12 +88,23 @@ func logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// matchingKeyIDs 返回按密钥值匹配 api_keys 的子查询。密钥值加密后只能按完整密钥的哈希精确查找
+func (s *LogService) matchingKeyIDs(keyValue string) *gorm.DB {
+	query := s.DB.Model(&models.APIKey{}).Select("id")
+	if s.Encryption.Enabled() {
+		return query.Where("key_hash = ?", encryption.HashKey(keyValue))
+	}
+	return query.Where("key_value LIKE ?", "%"+keyValue+"%")
+}
+
 // GetLogsQuery returns a GORM query for fetching logs with filters.
 func (s *LogService) GetLogsQuery(c *gin.Context) *gorm.DB {
-	return s.DB.Model(&models.RequestLog{}).Scopes(logFiltersScope(c))
+	return s.DB.Model(&models.RequestLog{}).Scopes(s.logFiltersScope(c))
 }
 
 // StreamLogKeysToCSV fetches unique keys from logs based on filters and streams them as a CSV.
+// Logs only hold a masked preview, so the full key values are loaded from api_keys by key_id;
+// keys that have since been deleted are skipped.
 func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error {
 	// Create a CSV writer
 	csvWriter := csv.NewWriter(writer)
@@ -93,30 +118,41 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 
 	var results []ExportableLogKey
 
-	baseQuery := s.DB.Model(&models.RequestLog{}).Scopes(logFiltersScope(c))
+	baseQuery := s.DB.Model(&models.RequestLog{}).Scopes(s.logFiltersScope(c)).Where("key_id <> 0")
 
-	// 使用窗口函数获取每个key_value的最新记录
+	// 使用窗口函数获取每个密钥的最新记录
 	err := s.DB.Raw(`
 		SELECT
-			key_value,
-			group_name,
-			status_code
+			api_keys.key_value,
+			ranked.group_name,
+			ranked.status_code
 		FROM (
 			SELECT
-				key_value,
+				key_id,
 				group_name,
 				status_code,
-				ROW_NUMBER() OVER (PARTITION BY key_value ORDER BY timestamp DESC) as rn
+				ROW_NUMBER() OVER (PARTITION BY key_id ORDER BY timestamp DESC) as rn
 			FROM (?) as filtered_logs
 		) ranked
-		WHERE rn = 1
-		ORDER BY key_value
+		JOIN api_keys ON api_keys.id = ranked.key_id
+		WHERE ranked.rn = 1
 	`, baseQuery).Scan(&results).Error
 
 	if err != nil {
 		return fmt.Errorf("failed to fetch log keys: %w", err)
 	}
 
+	for i := range results {
+		keyValue, err := s.Encryption.Decrypt(results[i].KeyValue)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt log key: %w", err)
+		}
+		results[i].KeyValue = keyValue
+	}
+	slices.SortFunc(results, func(a, b ExportableLogKey) int {
+		return cmp.Compare(a.KeyValue, b.KeyValue)
+	})
+
 	// 写入CSV数据
 	for _, record := range results {
 		csvRecord := []string{
@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
+	"gpt-load/internal/redaction"
 	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +25,12 @@ const (
 	RequestLogCachePrefix    = "request_log:"
 	PendingLogKeysSet        = "pending_log_keys"
 	DefaultLogFlushBatchSize = 200
+
+	// maxCachedRedactors 限制缓存的脱敏器数量，超出时清空缓存，避免配置反复修改后旧的脱敏器一直留在内存中
+	maxCachedRedactors = 64
+	// maxLogBodyColumnSize 是请求体和响应体在数据库中保存的最大字节数
+	maxLogBodyColumnSize = 65000
+	logTruncatedMarker   = "\n[TRUNCATED: Response exceeded maximum log size]"
 )
 
 // RequestLogService is responsible for managing request logs.
@@ -28,17 +38,22 @@ type RequestLogService struct {
 	db              *gorm.DB
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
+	groupManager    *GroupManager
+	redactorsMu     sync.Mutex
+	redactors       map[string]*redaction.Redactor // 脱敏配置 -> 脱敏器
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 	ticker          *time.Ticker
 }
 
 // NewRequestLogService creates a new RequestLogService instance
-func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSettingsManager) *RequestLogService {
+func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSettingsManager, gm *GroupManager) *RequestLogService {
 	return &RequestLogService{
 		db:              db,
 		store:           store,
 		settingsManager: sm,
+		groupManager:    gm,
+		redactors:       make(map[string]*redaction.Redactor),
 		stopChan:        make(chan struct{}),
 	}
 }
@@ -100,18 +115,45 @@ func (s *RequestLogService) Stop(ctx context.Context) {
 	}
 }
 
+// truncateResponseBody 在 UTF-8 字符边界截断超过 limit 的响应体并追加截断标记，结果不超过日志列的长度
+func truncateResponseBody(body string, limit int) string {
+	limit = min(limit, maxLogBodyColumnSize-len(logTruncatedMarker))
+	if len(body) <= limit {
+		return body
+	}
+	return utils.TruncateString(body, limit) + logTruncatedMarker
+}
+
+// cachedRequestLog 是写入缓存的日志，RequestLog 的 key_hash 不参与 JSON 序列化，需要单独保存
+type cachedRequestLog struct {
+	*models.RequestLog
+	KeyHash string `json:"key_hash"`
+}
+
 // Record logs a request to the database and cache
 func (s *RequestLogService) Record(log *models.RequestLog) error {
 	log.ID = uuid.NewString()
 	log.Timestamp = time.Now()
 
+	// 日志只按 key_id 关联密钥并保存脱敏预览，完整密钥不落入日志
+	if log.KeyValue != "" && log.KeyHash == "" {
+		log.KeyHash = encryption.HashKey(log.KeyValue)
+		log.KeyValue = utils.MaskAPIKey(log.KeyValue)
+	}
+
+	// 先对完整内容脱敏再截断，避免截断处残留半截的敏感信息无法被识别
+	settings := s.settingsFor(log.GroupName)
+	s.redactContent(log, settings)
+	log.RequestBody = utils.TruncateString(log.RequestBody, min(settings.MaxRequestBodyLogSize, maxLogBodyColumnSize))
+	log.ResponseBody = truncateResponseBody(log.ResponseBody, settings.MaxResponseBodyLogSize)
+
 	if s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes == 0 {
 		return s.writeLogsToDB([]*models.RequestLog{log})
 	}
 
 	cacheKey := RequestLogCachePrefix + log.ID
 
-	logBytes, err := json.Marshal(log)
+	logBytes, err := json.Marshal(cachedRequestLog{RequestLog: log, KeyHash: log.KeyHash})
 	if err != nil {
 		return fmt.Errorf("failed to marshal request log: %w", err)
 	}
@@ -124,6 +166,58 @@ func (s *RequestLogService) Record(log *models.RequestLog) error {
 	return s.store.SAdd(PendingLogKeysSet, cacheKey)
 }
 
+// settingsFor 返回分组生效的配置，分组不存在时使用系统配置
+func (s *RequestLogService) settingsFor(groupName string) types.SystemSettings {
+	if group, err := s.groupManager.GetGroupByName(groupName); err == nil {
+		return group.EffectiveConfig
+	}
+	return s.settingsManager.GetSettings()
+}
+
+// redactContent 按分组的脱敏配置替换请求体、响应体和流式内容中的敏感信息
+func (s *RequestLogService) redactContent(log *models.RequestLog, settings types.SystemSettings) {
+	if log.RequestBody == "" && log.ResponseBody == "" && log.StreamContent == nil {
+		return
+	}
+
+	redactor := s.redactorFor(log.GroupName, settings)
+	if !redactor.Enabled() {
+		return
+	}
+
+	log.RequestBody = redactor.Redact(log.RequestBody)
+	log.ResponseBody = redactor.Redact(log.ResponseBody)
+	if sc := log.StreamContent; sc != nil {
+		sc.ThinkingChain = redactor.Redact(sc.ThinkingChain)
+		sc.TextMessages = redactor.Redact(sc.TextMessages)
+		sc.ToolCalls = redactor.Redact(sc.ToolCalls)
+		sc.RawContent = redactor.Redact(sc.RawContent)
+	}
+}
+
+// redactorFor 返回 settings 对应的脱敏器。编译后的脱敏器按配置缓存，缓存数量超过 maxCachedRedactors 时清空
+func (s *RequestLogService) redactorFor(groupName string, settings types.SystemSettings) *redaction.Redactor {
+	cacheKey := settings.LogRedactionDetectors + "\x00" + settings.LogRedactionPatterns
+
+	s.redactorsMu.Lock()
+	defer s.redactorsMu.Unlock()
+	if cached, ok := s.redactors[cacheKey]; ok {
+		return cached
+	}
+
+	redactor, err := redaction.New(settings.LogRedactionDetectors, settings.LogRedactionPatterns)
+	if err != nil {
+		// 配置在保存时已校验，这里只可能是旧数据，退回到内置检测器，避免敏感内容原样入库
+		logrus.Warnf("Invalid log redaction config for group %s, using all built-in detectors: %v", groupName, err)
+		redactor, _ = redaction.New(strings.Join(redaction.DetectorNames(), ","), "")
+	}
+	if len(s.redactors) >= maxCachedRedactors {
+		clear(s.redactors)
+	}
+	s.redactors[cacheKey] = redactor
+	return redactor
+}
+
 // flush data from cache to database
 func (s *RequestLogService) flush() {
 	if s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes == 0 {
@@ -158,12 +252,13 @@ func (s *RequestLogService) flush() {
 				}
 				continue
 			}
-			var log models.RequestLog
-			if err := json.Unmarshal(logBytes, &log); err != nil {
+			cached := cachedRequestLog{RequestLog: &models.RequestLog{}}
+			if err := json.Unmarshal(logBytes, &cached); err != nil {
 				logrus.Warnf("Failed to unmarshal log for key %s: %v", key, err)
 				continue
 			}
-			logs = append(logs, &log)
+			cached.RequestLog.KeyHash = cached.KeyHash
+			logs = append(logs, cached.RequestLog)
 			processedKeys = append(processedKeys, key)
 		}
 
@@ -207,24 +302,24 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 			return fmt.Errorf("failed to batch insert request logs: %w", err)
 		}
 
-		keyStats := make(map[string]int64)
+		keyStats := make(map[uint]int64)
 		for _, log := range logs {
-			if log.IsSuccess && log.KeyValue != "" {
-				keyStats[log.KeyValue]++
+			if log.IsSuccess && log.KeyID != 0 {
+				keyStats[log.KeyID]++
 			}
 		}
 
 		if len(keyStats) > 0 {
 			var caseStmt strings.Builder
-			var keyValues []string
-			caseStmt.WriteString("CASE key_value ")
-			for keyValue, count := range keyStats {
-				caseStmt.WriteString(fmt.Sprintf("WHEN '%s' THEN request_count + %d ", keyValue, count))
-				keyValues = append(keyValues, keyValue)
+			var keyIDs []uint
+			caseStmt.WriteString("CASE id ")
+			for keyID, count := range keyStats {
+				caseStmt.WriteString(fmt.Sprintf("WHEN %d THEN request_count + %d ", keyID, count))
+				keyIDs = append(keyIDs, keyID)
 			}
 			caseStmt.WriteString("END")
 
-			if err := tx.Model(&models.APIKey{}).Where("key_value IN ?", keyValues).
+			if err := tx.Model(&models.APIKey{}).Where("id IN ?", keyIDs).
 				Updates(map[string]any{
 					"request_count": gorm.Expr(caseStmt.String()),
 					"last_used_at":  time.Now(),
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateResponseBody(t *testing.T) {
+	columnLimit := maxLogBodyColumnSize - len(logTruncatedMarker)
+
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		want  string
+	}{
+		{name: "within limit", body: "ok", limit: 10, want: "ok"},
+		{name: "ascii over limit", body: "abcdef", limit: 4, want: "abcd" + logTruncatedMarker},
+		{name: "cut inside a multi-byte rune", body: "ab你好", limit: 3, want: "ab" + logTruncatedMarker},
+		{name: "limit above column size", body: strings.Repeat("a", maxLogBodyColumnSize+10), limit: 1 << 20, want: strings.Repeat("a", columnLimit) + logTruncatedMarker},
+		{name: "fits the column exactly", body: strings.Repeat("a", columnLimit), limit: 1 << 20, want: strings.Repeat("a", columnLimit)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateResponseBody(tt.body, tt.limit)
+			if got != tt.want {
+				t.Errorf("truncateResponseBody() = %d bytes %q..., want %d bytes", len(got), got[:min(len(got), 20)], len(tt.want))
+			}
+			if !utf8.ValidString(got) || len(got) > maxLogBodyColumnSize {
+				t.Errorf("truncateResponseBody() returned %d bytes, valid UTF-8 %t", len(got), utf8.ValidString(got))
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ const (
 	TaskTypeKeyValidation = "KEY_VALIDATION"
 	TaskTypeKeyImport     = "KEY_IMPORT"
 	TaskTypeKeyDelete     = "KEY_DELETE"
+	TaskTypeKeyMove       = "KEY_MOVE"
+	TaskTypeKeyCopy       = "KEY_COPY"
 )
 
 // TaskStatus represents the full lifecycle of a long-running task.
@@ -17,6 +17,7 @@ type memoryStoreItem struct {
 type MemoryStore struct {
 	mu            sync.RWMutex
 	data          map[string]any
+	hashExpiries  map[string]int64 // HASH 键的过期时间（Unix 纳秒），访问时惰性清理
 	muSubscribers sync.RWMutex
 	subscribers   map[string]map[chan *Message]struct{}
 }
@@ -24,7 +25,8 @@ type MemoryStore struct {
 // NewMemoryStore creates and returns a new MemoryStore instance.
 func NewMemoryStore() *MemoryStore {
 	s := &MemoryStore{
-		data:        make(map[string]any),
+		data:         make(map[string]any),
+		hashExpiries: make(map[string]int64),
 		subscribers: make(map[string]map[chan *Message]struct{}),
 	}
 	return s
@@ -82,6 +84,7 @@ func (s *MemoryStore) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.data, key)
+	delete(s.hashExpiries, key)
 	return nil
 }
 
@@ -91,6 +94,7 @@ func (s *MemoryStore) Del(keys ...string) error {
 	defer s.mu.Unlock()
 	for _, key := range keys {
 		delete(s.data, key)
+		delete(s.hashExpiries, key)
 	}
 	return nil
 }
@@ -146,11 +150,41 @@ func (s *MemoryStore) SetNX(key string, value []byte, ttl time.Duration) (bool,
 	return true, nil
 }
 
+// Expire sets a TTL on an existing key. Lists and sets do not support expiry in the memory store.
+func (s *MemoryStore) Expire(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := time.Now().UnixNano() + ttl.Nanoseconds()
+	switch item := s.data[key].(type) {
+	case memoryStoreItem:
+		item.expiresAt = expiresAt
+		s.data[key] = item
+	case map[string]string:
+		s.hashExpiries[key] = expiresAt
+	}
+	return nil
+}
+
+// dropExpiredHash removes a hash whose TTL has passed. The caller must hold the write lock.
+func (s *MemoryStore) dropExpiredHash(key string) {
+	if expiresAt, ok := s.hashExpiries[key]; ok && time.Now().UnixNano() > expiresAt {
+		delete(s.data, key)
+		delete(s.hashExpiries, key)
+	}
+}
+
 // --- HASH operations ---
 
 func (s *MemoryStore) HSet(key string, values map[string]any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.hset(key, values)
+}
+
+// hset sets hash fields. The caller must hold the write lock.
+func (s *MemoryStore) hset(key string, values map[string]any) error {
+	s.dropExpiredHash(key)
 
 	var hash map[string]string
 	rawHash, exists := s.data[key]
@@ -172,8 +206,9 @@ func (s *MemoryStore) HSet(key string, values map[string]any) error {
 }
 
 func (s *MemoryStore) HGetAll(key string) (map[string]string, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredHash(key)
 
 	rawHash, exists := s.data[key]
 	if !exists {
@@ -196,6 +231,7 @@ func (s *MemoryStore) HGetAll(key string) (map[string]string, error) {
 func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredHash(key)
 
 	var hash map[string]string
 	rawHash, exists := s.data[key]
@@ -217,12 +253,41 @@ func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	return newVal, nil
 }
 
+func (s *MemoryStore) HDel(key string, fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredHash(key)
+
+	rawHash, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+
+	hash, ok := rawHash.(map[string]string)
+	if !ok {
+		return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	for _, field := range fields {
+		delete(hash, field)
+	}
+	if len(hash) == 0 {
+		delete(s.data, key)
+		delete(s.hashExpiries, key)
+	}
+	return nil
+}
+
 // --- LIST operations ---
 
 func (s *MemoryStore) LPush(key string, values ...any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.lpush(key, values...)
+}
 
+// lpush prepends values to a list. The caller must hold the write lock.
+func (s *MemoryStore) lpush(key string, values ...any) error {
 	var list []string
 	rawList, exists := s.data[key]
 	if !exists {
@@ -247,7 +312,11 @@ func (s *MemoryStore) LPush(key string, values ...any) error {
 func (s *MemoryStore) LRem(key string, count int64, value any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.lrem(key, count, value)
+}
 
+// lrem removes all occurrences of value from a list. The caller must hold the write lock.
+func (s *MemoryStore) lrem(key string, count int64, value any) error {
 	rawList, exists := s.data[key]
 	if !exists {
 		return nil
@@ -274,6 +343,47 @@ func (s *MemoryStore) LRem(key string, count int64, value any) error {
 	return nil
 }
 
+// --- TxPipeliner implementation ---
+
+// memoryTxPipeliner queues commands and applies them under a single write lock.
+type memoryTxPipeliner struct {
+	store *MemoryStore
+	cmds  []func() error
+}
+
+// HSet adds an HSET command to the pipeline.
+func (p *memoryTxPipeliner) HSet(key string, values map[string]any) {
+	p.cmds = append(p.cmds, func() error { return p.store.hset(key, values) })
+}
+
+// LPush adds an LPUSH command to the pipeline.
+func (p *memoryTxPipeliner) LPush(key string, values ...any) {
+	p.cmds = append(p.cmds, func() error { return p.store.lpush(key, values...) })
+}
+
+// LRem adds an LREM command to the pipeline.
+func (p *memoryTxPipeliner) LRem(key string, count int64, value any) {
+	p.cmds = append(p.cmds, func() error { return p.store.lrem(key, count, value) })
+}
+
+// Exec applies all queued commands while holding the write lock, so no reader observes a partial batch.
+func (p *memoryTxPipeliner) Exec() error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+
+	for _, cmd := range p.cmds {
+		if err := cmd(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TxPipeline creates a pipeline whose commands are applied atomically.
+func (s *MemoryStore) TxPipeline() Pipeliner {
+	return &memoryTxPipeliner{store: s}
+}
+
 func (s *MemoryStore) Rotate(key string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -302,6 +412,41 @@ func (s *MemoryStore) Rotate(key string) (string, error) {
 	return item, nil
 }
 
+// LRange returns the elements of the list between start and stop (inclusive).
+// Negative indices count from the end of the list, as in Redis.
+func (s *MemoryStore) LRange(key string, start, stop int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rawList, exists := s.data[key]
+	if !exists {
+		return []string{}, nil
+	}
+
+	list, ok := rawList.([]string)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	length := int64(len(list))
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+
+	result := make([]string, stop-start+1)
+	copy(result, list[start:stop+1])
+	return result, nil
+}
+
 // --- SET operations ---
 
 // SAdd adds members to a set.
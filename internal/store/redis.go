@@ -71,6 +71,11 @@ func (s *RedisStore) Close() error {
 
 // --- HASH operations ---
 
+// Expire sets a TTL on an existing key.
+func (s *RedisStore) Expire(key string, ttl time.Duration) error {
+	return s.client.Expire(context.Background(), key, ttl).Err()
+}
+
 func (s *RedisStore) HSet(key string, values map[string]any) error {
 	return s.client.HSet(context.Background(), key, values).Err()
 }
@@ -83,6 +88,10 @@ func (s *RedisStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	return s.client.HIncrBy(context.Background(), key, field, incr).Result()
 }
 
+func (s *RedisStore) HDel(key string, fields ...string) error {
+	return s.client.HDel(context.Background(), key, fields...).Err()
+}
+
 // --- LIST operations ---
 
 func (s *RedisStore) LPush(key string, values ...any) error {
@@ -104,6 +113,10 @@ func (s *RedisStore) Rotate(key string) (string, error) {
 	return val, nil
 }
 
+func (s *RedisStore) LRange(key string, start, stop int64) ([]string, error) {
+	return s.client.LRange(context.Background(), key, start, stop).Result()
+}
+
 // --- SET operations ---
 
 func (s *RedisStore) SAdd(key string, members ...any) error {
@@ -125,6 +138,16 @@ func (p *redisPipeliner) HSet(key string, values map[string]any) {
 	p.pipe.HSet(context.Background(), key, values)
 }
 
+// LPush adds an LPUSH command to the pipeline.
+func (p *redisPipeliner) LPush(key string, values ...any) {
+	p.pipe.LPush(context.Background(), key, values...)
+}
+
+// LRem adds an LREM command to the pipeline.
+func (p *redisPipeliner) LRem(key string, count int64, value any) {
+	p.pipe.LRem(context.Background(), key, count, value)
+}
+
 // Exec executes all commands in the pipeline.
 func (p *redisPipeliner) Exec() error {
 	_, err := p.pipe.Exec(context.Background())
@@ -138,6 +161,13 @@ func (s *RedisStore) Pipeline() Pipeliner {
 	}
 }
 
+// TxPipeline creates a pipeline wrapped in MULTI/EXEC, so its commands are applied atomically.
+func (s *RedisStore) TxPipeline() Pipeliner {
+	return &redisPipeliner{
+		pipe: s.client.TxPipeline(),
+	}
+}
+
 // --- Pub/Sub operations ---
 
 // redisSubscription wraps the redis.PubSub to implement the Subscription interface.
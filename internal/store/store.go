@@ -40,15 +40,20 @@ type Store interface {
 	// SetNX sets a key-value pair if the key does not already exist.
 	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
 
+	// Expire sets a TTL on an existing key.
+	Expire(key string, ttl time.Duration) error
+
 	// HASH operations
 	HSet(key string, values map[string]any) error
 	HGetAll(key string) (map[string]string, error)
 	HIncrBy(key, field string, incr int64) (int64, error)
+	HDel(key string, fields ...string) error
 
 	// LIST operations
 	LPush(key string, values ...any) error
 	LRem(key string, count int64, value any) error
 	Rotate(key string) (string, error)
+	LRange(key string, start, stop int64) ([]string, error)
 
 	// SET operations
 	SAdd(key string, members ...any) error
@@ -62,11 +67,16 @@ type Store interface {
 
 	// Subscribe listens for messages on a given channel.
 	Subscribe(channel string) (Subscription, error)
+
+	// TxPipeline creates a pipeline whose commands are applied atomically on Exec.
+	TxPipeline() Pipeliner
 }
 
 // Pipeliner defines an interface for executing a batch of commands.
 type Pipeliner interface {
 	HSet(key string, values map[string]any)
+	LPush(key string, values ...any)
+	LRem(key string, count int64, value any)
 	Exec() error
 }
 
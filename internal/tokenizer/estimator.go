@@ -0,0 +1,101 @@
+// Package tokenizer provides lightweight prompt token estimation used for pre-flight checks.
+package tokenizer
+
+import (
+	"encoding/json"
+	"math"
+	"unicode"
+)
+
+// family 描述某一类模型分词器的近似参数
+type family struct {
+	charsPerToken    float64 // 非 CJK 文本平均每个 Token 的字符数
+	tokensPerCJK     float64 // 每个 CJK 字符对应的 Token 数
+	tokensPerMessage int     // 每条消息的格式开销
+}
+
+var families = map[string]family{
+	"openai":    {charsPerToken: 4.0, tokensPerCJK: 1.0, tokensPerMessage: 4},
+	"anthropic": {charsPerToken: 3.5, tokensPerCJK: 1.2, tokensPerMessage: 5},
+	"gemini":    {charsPerToken: 4.0, tokensPerCJK: 1.0, tokensPerMessage: 4},
+}
+
+// promptFields 是请求体中包含提示词内容的顶层字段
+var promptFields = []string{"messages", "system", "prompt", "input", "contents", "systemInstruction", "system_instruction", "tools"}
+
+// skippedFields 是不计入提示词的字段，通常为图片等二进制内容
+var skippedFields = map[string]struct{}{
+	"image_url":   {},
+	"inline_data": {},
+	"inlineData":  {},
+	"file_data":   {},
+	"fileData":    {},
+	"source":      {},
+}
+
+// EstimatePromptTokens 按渠道类型对应的分词器近似规则估算请求体中的提示词 Token 数。
+// 无法解析的请求体返回 0。
+func EstimatePromptTokens(channelType string, body []byte) int {
+	f, ok := families[channelType]
+	if !ok {
+		f = families["openai"]
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return 0
+	}
+
+	var tokens float64
+	messages := 0
+	for _, field := range promptFields {
+		value, exists := payload[field]
+		if !exists {
+			continue
+		}
+		if list, ok := value.([]any); ok && field != "tools" {
+			messages += len(list)
+		}
+		tokens += countValue(value, f)
+	}
+
+	return int(math.Ceil(tokens)) + messages*f.tokensPerMessage
+}
+
+// countValue 递归统计 JSON 值中所有文本的 Token 数
+func countValue(value any, f family) float64 {
+	switch v := value.(type) {
+	case string:
+		return countText(v, f)
+	case []any:
+		var total float64
+		for _, item := range v {
+			total += countValue(item, f)
+		}
+		return total
+	case map[string]any:
+		var total float64
+		for key, item := range v {
+			if _, skip := skippedFields[key]; skip {
+				continue
+			}
+			total += countValue(item, f)
+		}
+		return total
+	default:
+		return 0
+	}
+}
+
+// countText 估算单段文本的 Token 数，CJK 字符与其他字符分别计算
+func countText(text string, f family) float64 {
+	var cjk, other int
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return float64(cjk)*f.tokensPerCJK + float64(other)/f.charsPerToken
+}
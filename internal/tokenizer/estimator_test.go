@@ -0,0 +1,75 @@
+package tokenizer
+
+import "testing"
+
+func TestEstimatePromptTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType string
+		body        string
+		want        int
+	}{
+		{
+			name:        "openai chat message",
+			channelType: "openai",
+			body:        `{"model":"gpt-4o","messages":[{"role":"user","content":"hello world!"}]}`,
+			want:        8, // user 1 + hello world! 3 + 每条消息 4
+		},
+		{
+			name:        "cjk characters count one token each",
+			channelType: "openai",
+			body:        `{"messages":[{"role":"user","content":"你好"}]}`,
+			want:        7, // user 1 + 你好 2 + 每条消息 4
+		},
+		{
+			name:        "anthropic ratios and system prompt",
+			channelType: "anthropic",
+			body:        `{"system":"abcdefg","messages":[{"role":"user","content":"你好"}]}`,
+			want:        11, // ceil(7/3.5 + 4/3.5 + 2*1.2) + 每条消息 5
+		},
+		{
+			name:        "gemini contents",
+			channelType: "gemini",
+			body:        `{"contents":[{"role":"user","parts":[{"text":"abcdefgh"}]}]}`,
+			want:        7, // user 1 + abcdefgh 2 + 每条消息 4
+		},
+		{
+			name:        "image data is skipped",
+			channelType: "openai",
+			body:        `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk" + `"}}]}]}`,
+			want:        8, // ceil(user 1 + image_url 2.25) + 每条消息 4，图片数据不计入
+		},
+		{
+			name:        "tools do not add message overhead",
+			channelType: "openai",
+			body:        `{"tools":[{"name":"abcd"}]}`,
+			want:        1,
+		},
+		{
+			name:        "unknown channel uses openai ratios",
+			channelType: "custom",
+			body:        `{"prompt":"abcdefgh"}`,
+			want:        2,
+		},
+		{
+			name:        "fields outside the prompt are ignored",
+			channelType: "openai",
+			body:        `{"model":"a-very-long-model-name-that-is-not-prompt","temperature":0.5}`,
+			want:        0,
+		},
+		{
+			name:        "invalid json",
+			channelType: "openai",
+			body:        `not json`,
+			want:        0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimatePromptTokens(tt.channelType, []byte(tt.body)); got != tt.want {
+				t.Errorf("EstimatePromptTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// usagePayload 覆盖 OpenAI、Anthropic 与 Gemini 响应中的用量字段
+type usagePayload struct {
+	Usage *struct {
+		TotalTokens  int64 `json:"total_tokens"`
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+	Message *struct {
+		Usage *struct {
+			InputTokens  int64 `json:"input_tokens"`
+			OutputTokens int64 `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	UsageMetadata *struct {
+		TotalTokenCount int64 `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// ExtractUsageTokens 从上游响应中提取 Token 总用量，支持普通 JSON 响应和 SSE 流。
+// 流式响应中各事件的用量取最大值，Anthropic 的输入与输出 Token 分别取最大值后相加。
+// 响应中没有用量信息时返回 0。
+func ExtractUsageTokens(body string) int64 {
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" {
+		return 0
+	}
+
+	var total, input, output int64
+	collect := func(data string) {
+		var payload usagePayload
+		if err := json.Unmarshal([]byte(data), &payload); err != nil {
+			return
+		}
+		if payload.Usage != nil {
+			total = max(total, payload.Usage.TotalTokens)
+			input = max(input, payload.Usage.InputTokens)
+			output = max(output, payload.Usage.OutputTokens)
+		}
+		if payload.Message != nil && payload.Message.Usage != nil {
+			input = max(input, payload.Message.Usage.InputTokens)
+			output = max(output, payload.Message.Usage.OutputTokens)
+		}
+		if payload.UsageMetadata != nil {
+			total = max(total, payload.UsageMetadata.TotalTokenCount)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		collect(trimmed)
+	case strings.HasPrefix(trimmed, "["):
+		// Gemini 非 SSE 流式响应为 JSON 数组
+		var items []json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &items); err == nil {
+			for _, item := range items {
+				collect(string(item))
+			}
+		}
+	default:
+		for _, line := range strings.Split(trimmed, "\n") {
+			data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+			if !ok {
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == "" || data == "[DONE]" {
+				continue
+			}
+			collect(data)
+		}
+	}
+
+	return max(total, input+output)
+}
@@ -0,0 +1,43 @@
+package tokenizer
+
+import "testing"
+
+func TestExtractUsageTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int64
+	}{
+		{name: "empty", body: "", want: 0},
+		{name: "openai response", body: `{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":30,"total_tokens":42}}`, want: 42},
+		{name: "openai responses api", body: `{"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`, want: 15},
+		{name: "anthropic response", body: `{"type":"message","usage":{"input_tokens":20,"output_tokens":7}}`, want: 27},
+		{name: "gemini response", body: `{"candidates":[],"usageMetadata":{"promptTokenCount":3,"totalTokenCount":9}}`, want: 9},
+		{name: "gemini json array stream", body: `[{"usageMetadata":{"totalTokenCount":4}},{"usageMetadata":{"totalTokenCount":11}}]`, want: 11},
+		{
+			name: "openai stream",
+			body: "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"total_tokens\":33}}\n\n" +
+				"data: [DONE]\n",
+			want: 33,
+		},
+		{
+			name: "anthropic stream",
+			body: "event: message_start\n" +
+				"data: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n\n" +
+				"event: message_delta\n" +
+				"data: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":15}}\n",
+			want: 40,
+		},
+		{name: "no usage", body: `{"choices":[{"message":{"content":"hi"}}]}`, want: 0},
+		{name: "not json", body: "upstream error", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractUsageTokens(tt.body); got != tt.want {
+				t.Errorf("ExtractUsageTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,23 +3,33 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 // MaskAPIKey masks an API key for safe logging.
+// Keys of 8 characters or fewer are too short to reveal any part of, so only their length is kept.
 func MaskAPIKey(key string) string {
 	length := len(key)
+	if length == 0 {
+		return ""
+	}
 	if length <= 8 {
-		return key
+		return fmt.Sprintf("****(%d)", length)
 	}
 	return fmt.Sprintf("%s****%s", key[:4], key[length-4:])
 }
 
-// TruncateString shortens a string to a maximum length.
+// TruncateString shortens a string to at most maxLength bytes.
+// 截断位置回退到 UTF-8 字符边界，不会留下半个多字节字符。
 func TruncateString(s string, maxLength int) string {
-	if len(s) > maxLength {
-		return s[:maxLength]
+	if len(s) <= maxLength {
+		return s
+	}
+	end := max(maxLength, 0)
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
 	}
-	return s
+	return s[:end]
 }
 
 // SplitAndTrim splits a string by a separator
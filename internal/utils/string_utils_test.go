@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		maxLength int
+		want      string
+	}{
+		{name: "shorter than limit", input: "hello", maxLength: 10, want: "hello"},
+		{name: "exact limit", input: "hello", maxLength: 5, want: "hello"},
+		{name: "ascii", input: "hello world", maxLength: 5, want: "hello"},
+		{name: "cut inside a multi-byte rune", input: "ab你好", maxLength: 4, want: "ab"},
+		{name: "cut after a multi-byte rune", input: "ab你好", maxLength: 5, want: "ab你"},
+		{name: "cut inside an emoji", input: "a😀b", maxLength: 3, want: "a"},
+		{name: "zero limit", input: "abc", maxLength: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateString(tt.input, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("TruncateString(%q, %d) = %q, want %q", tt.input, tt.maxLength, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("TruncateString(%q, %d) returned invalid UTF-8", tt.input, tt.maxLength)
+			}
+		})
+	}
+}
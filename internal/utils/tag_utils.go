@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxKeyTags 是单个 Key 最多可设置的标签数量
+	MaxKeyTags = 20
+	// maxTagLength 是单个标签的最大长度
+	maxTagLength = 64
+)
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]+$`)
+
+// NormalizeTags trims, validates, deduplicates and sorts tags such as "tier:paid" or "region:us".
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q exceeds %d characters", tag, maxTagLength)
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: only letters, digits and _ . : / - are allowed", tag)
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > MaxKeyTags {
+		return nil, fmt.Errorf("at most %d tags are allowed, got %d", MaxKeyTags, len(normalized))
+	}
+	return normalized, nil
+}
+
+// HasAllTags reports whether the comma-separated tag list contains every required tag.
+func HasAllTags(tags string, required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	set := StringToSet(tags, ",")
+	for _, tag := range required {
+		if _, ok := set[tag]; !ok {
+			return false
+		}
+	}
+	return true
+}
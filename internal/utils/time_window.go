@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxTimeWindows 是单个 Key 最多可设置的可用时段数量
+const maxTimeWindows = 10
+
+// TimeWindow 表示一天中的时段，以分钟计。End 小于 Start 时表示跨越午夜，例如 22:00-06:00
+type TimeWindow struct {
+	Start int
+	End   int
+}
+
+// Contains 判断 t 的本地时刻是否落在时段内，时段包含起点不包含终点
+func (w TimeWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// String 以 HH:MM-HH:MM 形式返回时段
+func (w TimeWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// ParseTimeWindows parses comma separated windows such as "22:00-06:00,12:00-13:30".
+// An empty string yields no windows, meaning the key is usable all day.
+func ParseTimeWindows(s string) ([]TimeWindow, error) {
+	var windows []TimeWindow
+	for _, part := range SplitAndTrim(s, ",") {
+		startStr, endStr, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", part)
+		}
+		start, err := parseClock(startStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window %q: %w", part, err)
+		}
+		end, err := parseClock(endStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window %q: %w", part, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("invalid time window %q: start and end must differ", part)
+		}
+		windows = append(windows, TimeWindow{Start: start, End: end})
+	}
+	if len(windows) > maxTimeWindows {
+		return nil, fmt.Errorf("at most %d time windows are allowed, got %d", maxTimeWindows, len(windows))
+	}
+	return windows, nil
+}
+
+// NormalizeTimeWindows 校验时段并返回规范化的逗号分隔形式
+func NormalizeTimeWindows(s string) (string, error) {
+	windows, err := ParseTimeWindows(s)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// InTimeWindows 判断 t 是否落在任一时段内，未设置时段视为全天可用
+func InTimeWindows(windows []TimeWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 将 HH:MM 解析为当天的分钟数，24:00 表示当天结束
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeTimeWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty means all day", input: "", want: ""},
+		{name: "single window", input: "9:00-17:30", want: "09:00-17:30"},
+		{name: "multiple windows with spaces", input: " 22:00-06:00 , 12:00-13:30 ", want: "22:00-06:00,12:00-13:30"},
+		{name: "end of day", input: "18:00-24:00", want: "18:00-24:00"},
+		{name: "missing separator", input: "09:00", wantErr: true},
+		{name: "invalid clock", input: "25:00-26:00", wantErr: true},
+		{name: "invalid minutes", input: "09:60-10:00", wantErr: true},
+		{name: "start equals end", input: "08:00-08:00", wantErr: true},
+		{
+			name:    "too many windows",
+			input:   "00:00-01:00,01:00-02:00,02:00-03:00,03:00-04:00,04:00-05:00,05:00-06:00,06:00-07:00,07:00-08:00,08:00-09:00,09:00-10:00,10:00-11:00",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTimeWindows(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeTimeWindows(%q) error = %v, wantErr %t", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeTimeWindows(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInTimeWindows(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		windows string
+		now     time.Time
+		want    bool
+	}{
+		{name: "no windows", windows: "", now: at(3, 0), want: true},
+		{name: "inside daytime window", windows: "09:00-17:00", now: at(12, 0), want: true},
+		{name: "start is inclusive", windows: "09:00-17:00", now: at(9, 0), want: true},
+		{name: "end is exclusive", windows: "09:00-17:00", now: at(17, 0), want: false},
+		{name: "before daytime window", windows: "09:00-17:00", now: at(8, 59), want: false},
+		{name: "overnight window before midnight", windows: "22:00-06:00", now: at(23, 30), want: true},
+		{name: "overnight window after midnight", windows: "22:00-06:00", now: at(5, 59), want: true},
+		{name: "outside overnight window", windows: "22:00-06:00", now: at(12, 0), want: false},
+		{name: "window ending at midnight", windows: "18:00-24:00", now: at(23, 59), want: true},
+		{name: "second window matches", windows: "01:00-02:00,12:00-13:30", now: at(13, 15), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := ParseTimeWindows(tt.windows)
+			if err != nil {
+				t.Fatalf("ParseTimeWindows(%q) error = %v", tt.windows, err)
+			}
+			if got := InTimeWindows(windows, tt.now); got != tt.want {
+				t.Errorf("InTimeWindows(%q, %s) = %t, want %t", tt.windows, tt.now.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
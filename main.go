@@ -24,6 +24,12 @@ var buildFS embed.FS
 var indexPage []byte
 
 func main() {
+	// 离线重新加密密钥值
+	if len(os.Args) > 1 && os.Args[1] == "migrate-keys" {
+		runMigrateKeys(os.Args[2:])
+		return
+	}
+
 	// Build the dependency injection container
 	container, err := container.BuildContainer()
 	if err != nil {
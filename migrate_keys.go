@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/container"
+	migrations "gpt-load/internal/db/migrations"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// runMigrateKeys 离线重新加密数据库中的密钥值，用于首次启用加密、轮换主密钥或关闭加密，执行前需要停止服务。
+// 当前主密钥取自 ENCRYPTION_KEY 或 ENCRYPTION_KEY_FILE，新主密钥取自 NEW_ENCRYPTION_KEY 或 NEW_ENCRYPTION_KEY_FILE。
+func runMigrateKeys(args []string) {
+	flags := flag.NewFlagSet("migrate-keys", flag.ExitOnError)
+	decrypt := flags.Bool("decrypt", false, "store key values in plaintext instead of encrypting them with a new key")
+	_ = flags.Parse(args)
+
+	newKey, err := config.LoadSecret("NEW_ENCRYPTION_KEY")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if newKey == "" && !*decrypt {
+		logrus.Fatal("NEW_ENCRYPTION_KEY or NEW_ENCRYPTION_KEY_FILE is required, use --decrypt to store key values in plaintext")
+	}
+	if newKey != "" && *decrypt {
+		logrus.Fatal("--decrypt cannot be used together with NEW_ENCRYPTION_KEY")
+	}
+	to, err := encryption.NewService(newKey)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	container, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+	if err := container.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+
+	err = container.Invoke(func(db *gorm.DB, from encryption.Service, keyProvider *keypool.KeyProvider) error {
+		// 旧版本升级后尚未启动过时，先补齐 key_hash 列
+		if err := migrations.MigrateSchema(db); err != nil {
+			return fmt.Errorf("database schema migration failed: %w", err)
+		}
+		if err := db.AutoMigrate(&models.APIKey{}, &models.RequestLog{}); err != nil {
+			return fmt.Errorf("database auto-migration failed: %w", err)
+		}
+
+		// 旧日志中的密钥可能是用当前主密钥加密的，换主密钥之前先脱敏
+		if err := migrations.V1_2_1_MaskRequestLogKeys(db, from); err != nil {
+			return fmt.Errorf("failed to mask request log keys: %w", err)
+		}
+
+		result, err := encryption.Reencrypt(db, from, to)
+		if err != nil {
+			return err
+		}
+
+		// store 中缓存的仍是旧密文，下次启动时重新从数据库加载
+		if err := keyProvider.InvalidateLoadedKeys(); err != nil {
+			return fmt.Errorf("failed to reset cached keys: %w", err)
+		}
+
+		logrus.Infof("Re-encrypted %d API keys.", result.Keys)
+		if *decrypt {
+			logrus.Info("Key values are now stored in plaintext, remove ENCRYPTION_KEY before restarting.")
+		} else {
+			logrus.Info("Set ENCRYPTION_KEY to the new key before restarting.")
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Fatalf("Failed to migrate keys: %v", err)
+	}
+}
@@ -1,9 +1,12 @@
 import type {
   APIKey,
+  DuplicateKeyReport,
   Group,
   GroupConfigOption,
   GroupStatsResponse,
+  KeyImportFormat,
   KeyStatus,
+  LibraryKey,
   TaskInfo,
 } from "@/types/models";
 import http from "@/utils/http";
@@ -95,6 +98,7 @@ export const keysApi = {
     page_size: number;
     key_value?: string;
     status?: KeyStatus;
+    tag?: string;
   }): Promise<{
     items: APIKey[];
     pagination: {
@@ -122,24 +126,31 @@ export const keysApi = {
     return res.data;
   },
 
-  // 异步批量添加密钥
-  async addKeysAsync(group_id: number, keys_text: string): Promise<TaskInfo> {
+  // 异步批量添加密钥，支持纯文本、CSV 和 JSONL
+  async addKeysAsync(
+    group_id: number,
+    keys_text: string,
+    format: KeyImportFormat = ""
+  ): Promise<TaskInfo> {
     const res = await http.post("/keys/add-async", {
       group_id,
       keys_text,
+      format,
     });
     return res.data;
   },
 
-  // 异步批量添加密钥（带备注）
+  // 异步批量添加密钥（带备注），CSV 和 JSONL 行内的备注优先
   async addKeysAsyncWithRemarks(
     group_id: number,
     keys_text: string,
-    remarks: { remarks: string; useForAll: boolean }
+    remarks: { remarks: string; useForAll: boolean },
+    format: KeyImportFormat = ""
   ): Promise<TaskInfo> {
     const res = await http.post("/keys/add-async-with-remarks", {
       group_id,
       keys_text,
+      format,
       remarks: remarks.remarks,
       use_remarks_for_all: remarks.useForAll,
     });
@@ -192,6 +203,19 @@ export const keysApi = {
     return res.data;
   },
 
+  // 异步移动或复制密钥到其他分组，keys_text 为空时按状态和标签筛选
+  async transferKeysAsync(params: {
+    group_id: number;
+    target_group_id: number;
+    mode: "move" | "copy";
+    keys_text?: string;
+    status?: KeyStatus;
+    tag?: string;
+  }): Promise<TaskInfo> {
+    const res = await http.post("/keys/transfer-async", params);
+    return res.data;
+  },
+
   // 测试密钥
   restoreKeys(group_id: number, keys_text: string): Promise<null> {
     return http.post("/keys/restore-multiple", {
@@ -233,7 +257,11 @@ export const keysApi = {
   },
 
   // 导出密钥
-  exportKeys(groupId: number, status: "all" | "active" | "invalid" = "all") {
+  exportKeys(
+    groupId: number,
+    status: "all" | "active" | "invalid" | "retired" | "exhausted" = "all",
+    tag?: string
+  ) {
     const authKey = localStorage.getItem("authKey");
     if (!authKey) {
       window.$message.error("未找到认证信息，无法导出", {
@@ -250,6 +278,9 @@ export const keysApi = {
     if (status !== "all") {
       params.append("status", status);
     }
+    if (tag) {
+      params.append("tag", tag);
+    }
 
     const url = `${http.defaults.baseURL}/keys/export?${params.toString()}`;
 
@@ -313,4 +344,96 @@ export const keysApi = {
     });
     return res.data;
   },
+
+  // 批量更新密钥标签
+  async updateKeyTags(
+    groupId: number,
+    keysText: string,
+    tags: string[],
+    action: "set" | "add" | "remove" = "set"
+  ): Promise<{ updated_count: number; message: string }> {
+    const res = await http.post("/keys/update-tags", {
+      group_id: groupId,
+      keys_text: keysText,
+      tags,
+      action,
+    });
+    return res.data;
+  },
+
+  // 批量设置密钥用量配额，quotaPeriod 为空表示不限制
+  async updateKeyQuota(
+    groupId: number,
+    keysText: string,
+    quotaPeriod: "" | "day" | "month",
+    requestQuota = 0,
+    tokenQuota = 0
+  ): Promise<{ updated_count: number; message: string }> {
+    const res = await http.post("/keys/update-quota", {
+      group_id: groupId,
+      keys_text: keysText,
+      quota_period: quotaPeriod,
+      request_quota: requestQuota,
+      token_quota: tokenQuota,
+    });
+    return res.data;
+  },
+
+  // 批量设置密钥生效时间、过期时间和每天可用时段，留空表示不限制
+  async updateKeySchedule(
+    groupId: number,
+    keysText: string,
+    schedule: { valid_from?: string | null; expires_at?: string | null; active_windows?: string }
+  ): Promise<{ updated_count: number; message: string }> {
+    const res = await http.post("/keys/update-schedule", {
+      group_id: groupId,
+      keys_text: keysText,
+      valid_from: schedule.valid_from || null,
+      expires_at: schedule.expires_at || null,
+      active_windows: schedule.active_windows ?? "",
+    });
+    return res.data;
+  },
+  // 获取密钥库，相同密钥值只列出一次并附带所属分组
+  async getLibraryKeys(params: {
+    page: number;
+    page_size: number;
+    key_value?: string;
+  }): Promise<{
+    items: LibraryKey[];
+    pagination: {
+      total_items: number;
+      total_pages: number;
+    };
+  }> {
+    const res = await http.get("/keys/library", { params });
+    return res.data;
+  },
+
+  // 将密钥库中的密钥加入其他分组，新分组沿用已有的健康状态
+  async assignLibraryKey(
+    keyValue: string,
+    groupIds: number[]
+  ): Promise<{ assigned_count: number; message: string }> {
+    const res = await http.post("/keys/library/assign", {
+      key_value: keyValue,
+      group_ids: groupIds,
+    });
+    return res.data;
+  },
+  // 查找跨分组重复和规范化后相同的密钥
+  async getDuplicateKeys(type: "" | "exact" | "normalized" = ""): Promise<DuplicateKeyReport> {
+    const res = await http.get("/keys/duplicates", { params: type ? { type } : {} });
+    return res.data;
+  },
+
+  // 批量去重，每组完全相同的重复密钥只保留一个
+  async dedupeKeys(params: {
+    type: "exact"; // 只自动去重完全相同的密钥
+    keep: "oldest" | "most_used";
+    keep_group_id?: number;
+  }): Promise<{ removed_count: number; message: string }> {
+    const res = await http.post("/keys/duplicates/dedupe", params);
+    return res.data;
+  },
 };
@@ -6,7 +6,7 @@ export interface ApiResponse<T> {
 }
 
 // 密钥状态
-export type KeyStatus = "active" | "invalid" | "disabled" | undefined;
+export type KeyStatus = "active" | "invalid" | "disabled" | "retired" | "exhausted" | undefined;
 
 // 数据模型定义
 export interface APIKey {
@@ -16,9 +16,22 @@ export interface APIKey {
   status: KeyStatus;
   is_disabled: boolean; // 手动停用标志
   remarks: string; // 备注信息
+  tags?: string; // 标签，逗号分隔，例如 tier:paid,region:us
   request_count: number;
   failure_count: number;
   last_used_at?: string;
+  cooldown_until?: string; // 错误规则触发的冷却截止时间
+  supported_models?: string; // 模型发现得到的可用模型，逗号分隔，为空表示未知
+  models_discovered_at?: string; // 最近一次模型发现的时间
+  quota_period?: "" | "day" | "month"; // 配额周期，为空表示不限制
+  request_quota?: number; // 每周期请求数上限，0 表示不限制
+  token_quota?: number; // 每周期 Token 上限，0 表示不限制
+  exhausted_until?: string; // 配额耗尽后的自动恢复时间
+  valid_from?: string; // 生效时间，之前不参与轮询
+  expires_at?: string; // 过期时间，之后不再参与轮询
+  active_windows?: string; // 每天可用时段，例如 22:00-06:00，为空表示全天可用
+  balance?: number | null; // 最近一次查询到的剩余余额，为空表示未知
+  balance_checked_at?: string; // 最近一次查询余额的时间
   created_at: string;
   updated_at: string;
 }
@@ -37,6 +50,23 @@ export interface HeaderRule {
   action: "set" | "remove";
 }
 
+// Key 路由规则：匹配模型或代理密钥的请求只使用带有全部指定标签的 Key
+export interface KeyRoutingRule {
+  model?: string; // 模型名称通配符，例如 o3*
+  proxy_key?: string;
+  tags: string[];
+}
+
+// 自定义验证请求模板，body 与 headers 支持 ${TEST_MODEL} 占位符
+export interface ValidationTemplate {
+  method: string;
+  path: string;
+  headers?: Record<string, string>;
+  body?: string;
+  success_status_codes?: number[];
+  success_json_path?: string;
+}
+
 export interface Group {
   id?: number;
   name: string;
@@ -53,6 +83,8 @@ export interface Group {
   endpoint?: string;
   param_overrides: Record<string, unknown>;
   header_rules?: HeaderRule[];
+  validation_template?: ValidationTemplate | null;
+  key_routing_rules?: KeyRoutingRule[];
   proxy_keys: string;
   created_at?: string;
   updated_at?: string;
@@ -81,6 +113,7 @@ export interface GroupConfigOption {
 // GroupStatsResponse defines the complete statistics for a group.
 export interface GroupStatsResponse {
   key_stats: KeyStats;
+  balance_stats: BalanceStats;
   hourly_stats: RequestStats;
   daily_stats: RequestStats;
   weekly_stats: RequestStats;
@@ -91,6 +124,15 @@ export interface KeyStats {
   total_keys: number;
   active_keys: number;
   invalid_keys: number;
+  retired_keys: number;
+  exhausted_keys: number;
+}
+
+// BalanceStats defines the remaining credit of the active keys in a group.
+export interface BalanceStats {
+  total_balance: number;
+  checked_keys: number;
+  low_balance_keys: number;
 }
 
 // RequestStats defines the statistics for requests over a period.
@@ -100,7 +142,7 @@ export interface RequestStats {
   failure_rate: number;
 }
 
-export type TaskType = "KEY_VALIDATION" | "KEY_IMPORT" | "KEY_DELETE";
+export type TaskType = "KEY_VALIDATION" | "KEY_IMPORT" | "KEY_DELETE" | "KEY_MOVE" | "KEY_COPY";
 
 export interface KeyValidationResult {
   invalid_keys: number;
@@ -111,13 +153,35 @@ export interface KeyValidationResult {
 export interface KeyImportResult {
   added_count: number;
   ignored_count: number;
+  shared_count?: number; // 已添加且其他分组中已有相同密钥
+  duplicate_count?: number;
+  invalid_count?: number;
+  rows?: KeyImportRowResult[];
+  rows_truncated?: boolean;
+  unsupported_columns?: string[]; // 未被导入的列
+}
+
+// 导入报告中每行的结果
+export interface KeyImportRowResult {
+  row: number;
+  key: string; // 已脱敏
+  result: "added" | "shared" | "duplicate" | "invalid";
+  message?: string;
 }
 
+// 密钥导入格式，为空时自动识别
+export type KeyImportFormat = "" | "text" | "csv" | "jsonl";
+
 export interface KeyDeleteResult {
   deleted_count: number;
   ignored_count: number;
 }
 
+export interface KeyTransferResult {
+  transferred_count: number;
+  skipped_count: number;
+}
+
 export interface TaskInfo {
   task_type: TaskType;
   is_running: boolean;
@@ -126,7 +190,7 @@ export interface TaskInfo {
   total?: number;
   started_at?: string;
   finished_at?: string;
-  result?: KeyValidationResult | KeyImportResult | KeyDeleteResult;
+  result?: KeyValidationResult | KeyImportResult | KeyDeleteResult | KeyTransferResult;
   error?: string;
 }
 
@@ -150,6 +214,7 @@ export interface RequestLog {
   request_path: string;
   duration_ms: number;
   error_message: string;
+  error_category?: string;
   user_agent: string;
   request_type: "retry" | "final";
   group_name?: string;
@@ -184,6 +249,7 @@ export interface LogFilter {
   status_code?: number | null;
   source_ip?: string;
   error_contains?: string;
+  error_category?: string;
   start_time?: string | null;
   end_time?: string | null;
   request_type?: "retry" | "final";
@@ -216,6 +282,63 @@ export interface DashboardStatsResponse {
   rpm: StatCard;
   request_count: StatCard;
   error_rate: StatCard;
+  expiring_keys: ExpiringKey[];
+}
+
+// 即将过期的密钥
+export interface ExpiringKey {
+  id: number;
+  group_id: number;
+  group_name: string;
+  key_value: string;
+  expires_at: string;
+}
+
+// 密钥库中的密钥，相同密钥值在多个分组中共享状态
+export interface LibraryKey {
+  id: number;
+  key_value: string;
+  status: KeyStatus;
+  is_disabled: boolean;
+  failure_count: number;
+  remarks: string;
+  tags?: string;
+  group_count: number;
+  groups: LibraryKeyGroup[];
+}
+
+// 密钥所属分组
+export interface LibraryKeyGroup {
+  key_id: number;
+  group_id: number;
+  group_name: string;
+}
+
+// 重复密钥中的一个密钥
+export interface DuplicateKeyMember {
+  key_id: number;
+  group_id: number;
+  group_name: string;
+  key_value: string; // 已脱敏
+  status: KeyStatus;
+  is_disabled: boolean;
+  request_count: number;
+  created_at: string;
+}
+
+// 一组重复密钥，exact 为多个分组中的相同密钥，normalized 为去掉空白或前缀后相同
+export interface DuplicateKeySet {
+  type: "exact" | "normalized";
+  members: DuplicateKeyMember[];
+}
+
+// 重复密钥报告
+export interface DuplicateKeyReport {
+  exact_sets: number;
+  normalized_sets: number;
+  redundant_keys: number;
+  sets: DuplicateKeySet[];
+  truncated: boolean;
 }
 
 // 图表数据集